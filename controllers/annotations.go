@@ -0,0 +1,17 @@
+package controllers
+
+import (
+	"github.com/go-logr/logr"
+)
+
+// ignoreAnnotationErrorOnDelete drops the error of an invalid annotation while the cluster is being deleted. The
+// deletion doesn't depend on these annotations, and failing on them would leave the finalizer in place forever.
+// The caller falls back to the default value of the annotation.
+func ignoreAnnotationErrorOnDelete(logger logr.Logger, deleting bool, err error) error {
+	if err != nil && deleting {
+		logger.Info("Ignoring invalid annotation of deleted cluster", "reason", err.Error())
+		return nil
+	}
+
+	return err
+}
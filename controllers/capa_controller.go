@@ -33,13 +33,17 @@ import (
 	"k8s.io/apimachinery/pkg/util/yaml"
 	"k8s.io/client-go/tools/record"
 	capa "sigs.k8s.io/cluster-api-provider-aws/v2/api/v1beta2"
+	capi "sigs.k8s.io/cluster-api/api/v1beta1"
 	"sigs.k8s.io/cluster-api/util"
 	"sigs.k8s.io/cluster-api/util/annotations"
 	"sigs.k8s.io/cluster-api/util/patch"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	"github.com/giantswarm/irsa-operator/pkg/aws/scope"
@@ -58,12 +62,23 @@ type CAPAClusterReconciler struct {
 	Installation string
 	recorder     record.EventRecorder
 	Cache        *gocache.Cache
+
+	CloudFrontCaching    bool
+	DiscoveryCacheMaxAge time.Duration
+	DNSProvider          string
+	DNSRoleARN           string
+	JWKSCacheMaxAge      time.Duration
+	// RequeueInterval is how often clusters are reconciled without any change, e.g. to refresh thumbprints.
+	RequeueInterval time.Duration
 }
 
 // +kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=awscluster,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=awscluster/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=awscluster/finalizers,verbs=update
-// +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;patch
+// +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=events,verbs=create;update;patch
+// +kubebuilder:rbac:groups=externaldns.k8s.io,resources=dnsendpoints,verbs=get;list;watch;create;update;patch;delete
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -100,7 +115,7 @@ func (r *CAPAClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 
 			// Fetch config map created by cluster-apps-operator
 			clusterValues := &v1.ConfigMap{}
-			err = r.Get(ctx, types.NamespacedName{Namespace: awsCluster.Namespace, Name: fmt.Sprintf("%s-cluster-values", awsCluster.Name)}, clusterValues)
+			err = r.Get(ctx, types.NamespacedName{Namespace: awsCluster.Namespace, Name: key.ClusterValuesConfigMapName(awsCluster.Name)}, clusterValues)
 			if err != nil && !k8serrors.IsNotFound(err) {
 				return reconcile.Result{}, microerror.Mask(err)
 			}
@@ -173,7 +188,7 @@ func (r *CAPAClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 
 	// Fetch config map created by cluster-apps-operator
 	clusterValues := &v1.ConfigMap{}
-	err = r.Get(ctx, types.NamespacedName{Namespace: awsCluster.Namespace, Name: fmt.Sprintf("%s-cluster-values", awsCluster.Name)}, clusterValues)
+	err = r.Get(ctx, types.NamespacedName{Namespace: awsCluster.Namespace, Name: key.ClusterValuesConfigMapName(awsCluster.Name)}, clusterValues)
 	if err != nil {
 		return reconcile.Result{}, microerror.Mask(err)
 	}
@@ -183,25 +198,71 @@ func (r *CAPAClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		return reconcile.Result{}, microerror.Mask(err)
 	}
 
+	// Annotations are validated before the deletion is handled, invalid values must not block it.
+	deleting := awsCluster.DeletionTimestamp != nil || cluster.DeletionTimestamp != nil
+
+	certificateKeyAlgorithm, err := key.CertificateKeyAlgorithm(awsCluster.Annotations[key.IRSACertificateKeyAlgorithmAnnotation])
+	if err = ignoreAnnotationErrorOnDelete(logger, deleting, err); err != nil {
+		return ctrl.Result{}, microerror.Mask(err)
+	}
+
+	dnsRoleARN, err := key.DNSRoleARN(awsCluster.Annotations[key.IRSADNSRoleARNAnnotation], r.DNSRoleARN)
+	if err = ignoreAnnotationErrorOnDelete(logger, deleting, err); err != nil {
+		return ctrl.Result{}, microerror.Mask(err)
+	}
+
+	additionalAccountRoleARNs, err := key.AdditionalAccountRoleARNs(awsCluster.Annotations[key.IRSAAdditionalAccountRolesAnnotation], accountID)
+	if err = ignoreAnnotationErrorOnDelete(logger, deleting, err); err != nil {
+		return ctrl.Result{}, microerror.Mask(err)
+	}
+
+	unknownAudiencesPolicy, err := key.UnknownAudiencesPolicy(awsCluster.Annotations[key.IRSAUnknownAudiencesAnnotation])
+	if err = ignoreAnnotationErrorOnDelete(logger, deleting, err); err != nil {
+		return ctrl.Result{}, microerror.Mask(err)
+	}
+
+	thumbprintMode, err := key.ThumbprintMode(awsCluster.Annotations[key.IRSAThumbprintModeAnnotation])
+	if err = ignoreAnnotationErrorOnDelete(logger, deleting, err); err != nil {
+		return ctrl.Result{}, microerror.Mask(err)
+	}
+
+	extraAliases := key.ExtraAliases(awsCluster.Annotations[key.IRSAExtraAliasesAnnotation], baseDomain)
+
 	// create the cluster scope.
 	clusterScope, err := scope.NewClusterScope(scope.ClusterScopeParams{
 		AccountID:                  accountID,
+		AdditionalAccountRoleARNs:  additionalAccountRoleARNs,
+		AdditionalAudiences:        key.AdditionalAudiences(awsCluster.Annotations[key.IRSAAdditionalAudiencesAnnotation]),
 		ARN:                        arn,
 		BaseDomain:                 baseDomain,
 		BucketName:                 key.BucketName(accountID, awsCluster.Name),
 		Cache:                      r.Cache,
+		CertificateCustomerTagKeys: key.CertificateCustomerTagKeys(awsCluster.Annotations[key.IRSACertificateCustomerTagsAnnotation]),
+		CertificateKeyAlgorithm:    certificateKeyAlgorithm,
+		CertificateSecretName:      awsCluster.Annotations[key.IRSACertificateSecretAnnotation],
+		CloudFrontCaching:          r.CloudFrontCaching,
 		ClusterName:                awsCluster.Name,
 		ClusterNamespace:           awsCluster.Namespace,
 		ConfigName:                 key.ConfigName(awsCluster.Name),
+		DiscoveryCacheMaxAge:       r.DiscoveryCacheMaxAge,
+		DNSProvider:                r.DNSProvider,
+		DNSRoleARN:                 dnsRoleARN,
+		ExtraAliases:               extraAliases,
 		Installation:               r.Installation,
+		JWKSCacheMaxAge:            r.JWKSCacheMaxAge,
 		ManagementClusterAccountID: managementClusterAccountID,
 		ManagementClusterRegion:    mcAWSCluster.Spec.Region,
 		Region:                     awsCluster.Spec.Region,
 		// Change to this once we have all clusters in 25.0.0
 		// ReleaseVersion:   key.Release(cluster),
-		ReleaseVersion: "25.0.0",
-		SecretName:     key.SecretName(awsCluster.Name),
-		VPCMode:        awsCluster.Annotations["aws.giantswarm.io/vpc-mode"],
+		ReleaseVersion:         "25.0.0",
+		RemovedAccountRoleARNs: key.RemovedAccountRoleARNs(awsCluster.Annotations[key.IRSARegisteredAccountRolesAnnotation], accountID, additionalAccountRoleARNs),
+		RemovedAliases:         key.RemovedAliases(awsCluster.Annotations[key.IRSAManagedAliasesAnnotation], baseDomain, extraAliases),
+		ReplicaRegion:          awsCluster.Annotations[key.IRSAReplicaRegionAnnotation],
+		SecretName:             key.SecretName(awsCluster.Name),
+		ThumbprintMode:         thumbprintMode,
+		UnknownAudiencesPolicy: unknownAudiencesPolicy,
+		VPCMode:                awsCluster.Annotations["aws.giantswarm.io/vpc-mode"],
 
 		Logger:  logger,
 		Cluster: awsCluster,
@@ -279,14 +340,28 @@ func (r *CAPAClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 			logger.Info("successfully added finalizer to AWSCluster")
 		}
 
-		// Re-run regularly to ensure OIDC certificate thumbprints are up to date (see `EnsureOIDCProviders`)
-		requeueAfter := time.Minute * 5
+		// Re-run regularly to ensure OIDC certificate thumbprints are up to date (see `EnsureOIDCProviders`).
+		// Changes to the objects the cluster depends on trigger a reconciliation through the watches.
+		requeueAfter := r.RequeueInterval
+
+		// Conditions are set during the reconciliation, so patch them even if it fails.
+		patchHelper, err := patch.NewHelper(awsCluster, r.Client)
+		if err != nil {
+			return ctrl.Result{}, microerror.Mask(err)
+		}
+
+		reconcileErr := irsaService.Reconcile(ctx, &requeueAfter)
 
-		err := irsaService.Reconcile(ctx, &requeueAfter)
+		err = patchHelper.Patch(ctx, awsCluster, patch.WithOwnedConditions{Conditions: []capi.ConditionType{key.IRSAPermissionsCondition, key.IRSAReconciledCondition}})
 		if err != nil {
+			logger.Error(err, "failed to patch AWSCluster conditions")
 			return ctrl.Result{}, microerror.Mask(err)
 		}
 
+		if reconcileErr != nil {
+			return ctrl.Result{}, microerror.Mask(reconcileErr)
+		}
+
 		if created {
 			r.sendEvent(awsCluster, v1.EventTypeNormal, "IRSA", "IRSA bootstrap created")
 		}
@@ -350,9 +425,33 @@ func getBaseDomain(clusterValuesConfigMap *v1.ConfigMap) (string, error) {
 }
 
 // SetupWithManager sets up the controller with the Manager.
-func (r *CAPAClusterReconciler) SetupWithManager(mgr ctrl.Manager, controllerOpts controller.Options) error {
+func (r *CAPAClusterReconciler) SetupWithManager(ctx context.Context, mgr ctrl.Manager, controllerOpts controller.Options) error {
 	err := ctrl.NewControllerManagedBy(mgr).
-		For(&capa.AWSCluster{}).
+		For(&capa.AWSCluster{}, builder.WithPredicates(specOrMetadataChanged)).
+		Watches(
+			&capi.Cluster{},
+			handler.EnqueueRequestsFromMapFunc(util.ClusterToInfrastructureMapFunc(ctx, capa.GroupVersion.WithKind("AWSCluster"), r.Client, &capa.AWSCluster{})),
+			builder.WithPredicates(specOrMetadataChanged),
+		).
+		Watches(
+			&capa.AWSClusterRoleIdentity{},
+			handler.EnqueueRequestsFromMapFunc(r.identityToAWSClusters),
+			builder.WithPredicates(predicate.GenerationChangedPredicate{}),
+		).
+		// The client doesn't cache ConfigMaps and Secrets, so only their metadata is watched. Updates change the
+		// resource version, which is enough to trigger a reconciliation.
+		Watches(
+			&v1.ConfigMap{},
+			handler.EnqueueRequestsFromMapFunc(suffixToAWSCluster(key.ClusterValuesConfigMapSuffix)),
+			builder.OnlyMetadata,
+			builder.WithPredicates(hasNameSuffix(key.ClusterValuesConfigMapSuffix)),
+		).
+		Watches(
+			&v1.Secret{},
+			handler.EnqueueRequestsFromMapFunc(suffixToAWSCluster(key.ServiceAccountKeySecretSuffix)),
+			builder.OnlyMetadata,
+			builder.WithPredicates(hasNameSuffix(key.ServiceAccountKeySecretSuffix)),
+		).
 		WithOptions(controllerOpts).
 		Complete(r)
 	if err != nil {
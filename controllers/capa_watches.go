@@ -0,0 +1,58 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+
+	capa "sigs.k8s.io/cluster-api-provider-aws/v2/api/v1beta2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// specOrMetadataChanged ignores status-only updates, including the ones of our own condition patches.
+var specOrMetadataChanged = predicate.Or(
+	predicate.GenerationChangedPredicate{},
+	predicate.AnnotationChangedPredicate{},
+	predicate.LabelChangedPredicate{},
+)
+
+// hasNameSuffix filters objects named after a cluster, like `<cluster>-cluster-values`.
+func hasNameSuffix(suffix string) predicate.Predicate {
+	return predicate.NewPredicateFuncs(func(obj client.Object) bool {
+		return strings.HasSuffix(obj.GetName(), suffix) && obj.GetName() != suffix
+	})
+}
+
+// suffixToAWSCluster maps an object named after a cluster to the AWSCluster of the same name in its namespace.
+func suffixToAWSCluster(suffix string) handler.MapFunc {
+	return func(ctx context.Context, obj client.Object) []reconcile.Request {
+		clusterName, ok := strings.CutSuffix(obj.GetName(), suffix)
+		if !ok || clusterName == "" {
+			return nil
+		}
+
+		return []reconcile.Request{{NamespacedName: client.ObjectKey{Namespace: obj.GetNamespace(), Name: clusterName}}}
+	}
+}
+
+// identityToAWSClusters maps an AWSClusterRoleIdentity to all AWSClusters using it.
+func (r *CAPAClusterReconciler) identityToAWSClusters(ctx context.Context, obj client.Object) []reconcile.Request {
+	awsClusters := &capa.AWSClusterList{}
+	err := r.List(ctx, awsClusters)
+	if err != nil {
+		r.Log.Error(err, "failed to list AWSClusters for AWSClusterRoleIdentity", "identity", obj.GetName())
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, awsCluster := range awsClusters.Items {
+		identityRef := awsCluster.Spec.IdentityRef
+		if identityRef != nil && identityRef.Kind == capa.ClusterRoleIdentityKind && identityRef.Name == obj.GetName() {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&awsCluster)})
+		}
+	}
+
+	return requests
+}
@@ -0,0 +1,150 @@
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	capa "sigs.k8s.io/cluster-api-provider-aws/v2/api/v1beta2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func Test_specOrMetadataChanged(t *testing.T) {
+	awsCluster := func(generation int64, annotations, labels map[string]string, ready bool) *capa.AWSCluster {
+		return &capa.AWSCluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "test",
+				Namespace:   "org-test",
+				Generation:  generation,
+				Annotations: annotations,
+				Labels:      labels,
+			},
+			Status: capa.AWSClusterStatus{Ready: ready},
+		}
+	}
+	old := awsCluster(1, map[string]string{"a": "1"}, map[string]string{"l": "1"}, false)
+
+	tests := []struct {
+		name string
+		new  *capa.AWSCluster
+		want bool
+	}{
+		{
+			name: "status only",
+			new:  awsCluster(1, map[string]string{"a": "1"}, map[string]string{"l": "1"}, true),
+			want: false,
+		},
+		{
+			name: "spec",
+			new:  awsCluster(2, map[string]string{"a": "1"}, map[string]string{"l": "1"}, false),
+			want: true,
+		},
+		{
+			name: "annotation",
+			new:  awsCluster(1, map[string]string{"a": "2"}, map[string]string{"l": "1"}, false),
+			want: true,
+		},
+		{
+			name: "label",
+			new:  awsCluster(1, map[string]string{"a": "1"}, map[string]string{"l": "2"}, false),
+			want: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := specOrMetadataChanged.Update(event.UpdateEvent{ObjectOld: old, ObjectNew: tt.new}); got != tt.want {
+				t.Errorf("specOrMetadataChanged.Update() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_hasNameSuffix(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{name: "test-cluster-values", want: true},
+		{name: "-cluster-values", want: false},
+		{name: "cluster-values", want: false},
+		{name: "test-cluster-values-old", want: false},
+		{name: "test", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obj := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: tt.name, Namespace: "org-test"}}
+			if got := hasNameSuffix("-cluster-values").Generic(event.GenericEvent{Object: obj}); got != tt.want {
+				t.Errorf("hasNameSuffix() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_suffixToAWSCluster(t *testing.T) {
+	tests := []struct {
+		name string
+		want []reconcile.Request
+	}{
+		{
+			name: "test-sa",
+			want: []reconcile.Request{{NamespacedName: client.ObjectKey{Namespace: "org-test", Name: "test"}}},
+		},
+		{
+			name: "test-sa-sa",
+			want: []reconcile.Request{{NamespacedName: client.ObjectKey{Namespace: "org-test", Name: "test-sa"}}},
+		},
+		{name: "-sa", want: nil},
+		{name: "test", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obj := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: tt.name, Namespace: "org-test"}}
+			if got := suffixToAWSCluster("-sa")(context.Background(), obj); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("suffixToAWSCluster() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_identityToAWSClusters(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := capa.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	awsCluster := func(namespace, name string, identityRef *capa.AWSIdentityReference) *capa.AWSCluster {
+		return &capa.AWSCluster{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Spec:       capa.AWSClusterSpec{IdentityRef: identityRef},
+		}
+	}
+
+	r := &CAPAClusterReconciler{
+		Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+			awsCluster("org-a", "a", &capa.AWSIdentityReference{Kind: capa.ClusterRoleIdentityKind, Name: "default"}),
+			awsCluster("org-b", "b", &capa.AWSIdentityReference{Kind: capa.ClusterRoleIdentityKind, Name: "default"}),
+			awsCluster("org-c", "c", &capa.AWSIdentityReference{Kind: capa.ClusterRoleIdentityKind, Name: "other"}),
+			awsCluster("org-d", "d", &capa.AWSIdentityReference{Kind: capa.ControllerIdentityKind, Name: "default"}),
+			awsCluster("org-e", "e", nil),
+		).Build(),
+		Log: logr.Discard(),
+	}
+
+	identity := &capa.AWSClusterRoleIdentity{ObjectMeta: metav1.ObjectMeta{Name: "default"}}
+	got := r.identityToAWSClusters(context.Background(), identity)
+
+	want := []reconcile.Request{
+		{NamespacedName: client.ObjectKey{Namespace: "org-a", Name: "a"}},
+		{NamespacedName: client.ObjectKey{Namespace: "org-b", Name: "b"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("identityToAWSClusters() = %v, want %v", got, want)
+	}
+}
@@ -32,6 +32,10 @@ import (
 	"k8s.io/client-go/tools/record"
 	capa "sigs.k8s.io/cluster-api-provider-aws/v2/api/v1beta2"
 	eks "sigs.k8s.io/cluster-api-provider-aws/v2/controlplane/eks/api/v1beta2"
+	capi "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util"
+	"sigs.k8s.io/cluster-api/util/annotations"
+	"sigs.k8s.io/cluster-api/util/conditions"
 	"sigs.k8s.io/cluster-api/util/patch"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -66,6 +70,50 @@ func (r *EKSClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		return ctrl.Result{}, microerror.Mask(client.IgnoreNotFound(err))
 	}
 
+	// The owner reference is set by CAPI after creation, until then only the control plane itself can be paused.
+	cluster, err := util.GetOwnerCluster(ctx, r.Client, eksCluster.ObjectMeta)
+	if err != nil {
+		return ctrl.Result{}, microerror.Mask(err)
+	}
+	if (cluster != nil && annotations.IsPaused(cluster, eksCluster)) || annotations.HasPaused(eksCluster) {
+		logger.Info("AWSManagedControlPlane or its Cluster is marked as paused, skipping")
+		return ctrl.Result{}, nil
+	}
+
+	if eksCluster.Annotations[key.PauseIRSAOperatorAnnotation] == "true" {
+		if eksCluster.DeletionTimestamp != nil {
+			err = r.removeFinalizer(ctx, logger, eksCluster)
+			if err != nil {
+				return ctrl.Result{}, microerror.Mask(err)
+			}
+			logger.Info("AWSManagedControlPlane is marked as paused and deleted, finalizer removed")
+			return ctrl.Result{}, nil
+		}
+		logger.Info("AWSManagedControlPlane is marked as paused, skipping")
+		return ctrl.Result{}, nil
+	}
+
+	if eksCluster.DeletionTimestamp != nil && !controllerutil.ContainsFinalizer(eksCluster, key.FinalizerName) {
+		return ctrl.Result{}, nil
+	}
+
+	if eksCluster.DeletionTimestamp == nil && !eksCluster.Status.Ready {
+		logger.Info("EKS control plane is not ready yet")
+
+		patchHelper, err := patch.NewHelper(eksCluster, r.Client)
+		if err != nil {
+			return ctrl.Result{}, microerror.Mask(err)
+		}
+		conditions.MarkFalse(eksCluster, key.IRSAOIDCProviderCondition, "ControlPlaneNotReady", capi.ConditionSeverityInfo, "EKS control plane is not ready yet")
+		err = patchHelper.Patch(ctx, eksCluster, patch.WithOwnedConditions{Conditions: []capi.ConditionType{key.IRSAOIDCProviderCondition}})
+		if err != nil {
+			logger.Error(err, "failed to patch AWSManagedControlPlane conditions")
+			return ctrl.Result{}, microerror.Mask(err)
+		}
+
+		return ctrl.Result{RequeueAfter: time.Minute}, nil
+	}
+
 	awsClusterRoleIdentity := &capa.AWSClusterRoleIdentity{}
 	err = r.Get(ctx, types.NamespacedName{Name: eksCluster.Spec.IdentityRef.Name}, awsClusterRoleIdentity)
 	if err != nil {
@@ -83,20 +131,50 @@ func (r *EKSClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		return ctrl.Result{}, microerror.Mask(fmt.Errorf("unable to extract Account ID from ARN %s", string(arn)))
 	}
 
+	// Annotations are validated before the deletion is handled, invalid values must not block it.
+	deleting := eksCluster.DeletionTimestamp != nil
+
+	additionalAccountRoleARNs, err := key.AdditionalAccountRoleARNs(eksCluster.Annotations[key.IRSAAdditionalAccountRolesAnnotation], accountID)
+	if err = ignoreAnnotationErrorOnDelete(logger, deleting, err); err != nil {
+		return ctrl.Result{}, microerror.Mask(err)
+	}
+
+	podIdentityAssociations, err := key.PodIdentityAssociations(eksCluster.Annotations[key.IRSAPodIdentityAssociationsAnnotation])
+	if err = ignoreAnnotationErrorOnDelete(logger, deleting, err); err != nil {
+		return ctrl.Result{}, microerror.Mask(err)
+	}
+
+	unknownAudiencesPolicy, err := key.UnknownAudiencesPolicy(eksCluster.Annotations[key.IRSAUnknownAudiencesAnnotation])
+	if err = ignoreAnnotationErrorOnDelete(logger, deleting, err); err != nil {
+		return ctrl.Result{}, microerror.Mask(err)
+	}
+
+	thumbprintMode, err := key.ThumbprintMode(eksCluster.Annotations[key.IRSAThumbprintModeAnnotation])
+	if err = ignoreAnnotationErrorOnDelete(logger, deleting, err); err != nil {
+		return ctrl.Result{}, microerror.Mask(err)
+	}
+
 	// create the cluster scope.
 	clusterScope, err := scope.NewClusterScope(scope.ClusterScopeParams{
-		AccountID:        accountID,
-		ARN:              arn,
-		BucketName:       key.BucketName(accountID, eksCluster.Name),
-		Cache:            r.Cache,
-		ClusterName:      eksCluster.Name,
-		ClusterNamespace: eksCluster.Namespace,
-		ConfigName:       key.ConfigName(eksCluster.Name),
-		Installation:     r.Installation,
-		Region:           eksCluster.Spec.Region,
+		AccountID:                 accountID,
+		AdditionalAccountRoleARNs: additionalAccountRoleARNs,
+		AdditionalAudiences:       key.AdditionalAudiences(eksCluster.Annotations[key.IRSAAdditionalAudiencesAnnotation]),
+		ARN:                       arn,
+		BucketName:                key.BucketName(accountID, eksCluster.Name),
+		Cache:                     r.Cache,
+		ClusterName:               eksCluster.Name,
+		ClusterNamespace:          eksCluster.Namespace,
+		ConfigName:                key.ConfigName(eksCluster.Name),
+		Installation:              r.Installation,
+		PodIdentityAssociations:   podIdentityAssociations,
+		PodIdentityManaged:        eksCluster.Annotations[key.IRSAManagedPodIdentityAssociationsAnnotation] == "true",
+		Region:                    eksCluster.Spec.Region,
 		// This is a hack to allow CAPI clusters to drop the 'release.giantswarm.io/version' label.
-		ReleaseVersion: "20.0.0-alpha1",
-		SecretName:     key.SecretName(eksCluster.Name),
+		ReleaseVersion:         "20.0.0-alpha1",
+		RemovedAccountRoleARNs: key.RemovedAccountRoleARNs(eksCluster.Annotations[key.IRSARegisteredAccountRolesAnnotation], accountID, additionalAccountRoleARNs),
+		SecretName:             key.SecretName(eksCluster.Name),
+		ThumbprintMode:         thumbprintMode,
+		UnknownAudiencesPolicy: unknownAudiencesPolicy,
 
 		Logger:  logger,
 		Cluster: eksCluster,
@@ -109,10 +187,6 @@ func (r *EKSClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	irsaService := irsaEks.New(clusterScope, r.Client)
 
 	if eksCluster.DeletionTimestamp != nil {
-		finalizers := eksCluster.GetFinalizers()
-		if !key.ContainsFinalizer(finalizers, key.FinalizerName) {
-			return ctrl.Result{}, nil
-		}
 		logger.Info("Deleting IRSA resources for cluster")
 
 		err := irsaService.Delete(ctx)
@@ -120,29 +194,14 @@ func (r *EKSClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 			return ctrl.Result{}, microerror.Mask(err)
 		}
 
-		patchHelper, err := patch.NewHelper(eksCluster, r.Client)
+		err = r.removeFinalizer(ctx, logger, eksCluster)
 		if err != nil {
 			return ctrl.Result{}, microerror.Mask(err)
 		}
-		controllerutil.RemoveFinalizer(eksCluster, key.FinalizerName)
-		err = patchHelper.Patch(ctx, eksCluster)
-		if err != nil {
-			logger.Error(err, "failed to remove finalizer from AWSManagedControlPlane")
-			return ctrl.Result{}, microerror.Mask(err)
-		}
-		logger.Info("successfully removed finalizer from AWSManagedControlPlane")
 
 		r.sendEvent(eksCluster, v1.EventTypeNormal, "IRSA", "IRSA bootstrap deleted")
 		return ctrl.Result{}, nil
 	} else {
-		if !eksCluster.Status.Ready {
-			logger.Info("EKS control plane is not ready yet")
-			return ctrl.Result{
-				RequeueAfter: time.Minute,
-				Requeue:      true,
-			}, nil
-		}
-
 		created := false
 		if !controllerutil.ContainsFinalizer(eksCluster, key.FinalizerName) {
 			created = true
@@ -160,21 +219,51 @@ func (r *EKSClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 			logger.Info("successfully added finalizer to AWSManagedControlPlane")
 		}
 
-		err := irsaService.Reconcile(ctx)
+		// Re-run regularly to ensure OIDC certificate thumbprints are up to date (see `EnsureOIDCProviders`)
+		requeueAfter := time.Minute * 5
+
+		// Conditions are set during the reconciliation, so patch them even if it fails.
+		patchHelper, err := patch.NewHelper(eksCluster, r.Client)
+		if err != nil {
+			return ctrl.Result{}, microerror.Mask(err)
+		}
+
+		reconcileErr := irsaService.Reconcile(ctx, &requeueAfter)
+
+		err = patchHelper.Patch(ctx, eksCluster, patch.WithOwnedConditions{Conditions: []capi.ConditionType{key.IRSAOIDCProviderCondition, key.IRSAPermissionsCondition}})
 		if err != nil {
+			logger.Error(err, "failed to patch AWSManagedControlPlane conditions")
 			return ctrl.Result{}, microerror.Mask(err)
 		}
 
+		if reconcileErr != nil {
+			return ctrl.Result{}, microerror.Mask(reconcileErr)
+		}
+
 		if created {
 			r.sendEvent(eksCluster, v1.EventTypeNormal, "IRSA", "IRSA bootstrap created")
 		}
 
-		return ctrl.Result{
-			Requeue: true,
-		}, nil
+		return ctrl.Result{RequeueAfter: requeueAfter}, nil
 	}
 }
 
+func (r *EKSClusterReconciler) removeFinalizer(ctx context.Context, logger logr.Logger, eksCluster *eks.AWSManagedControlPlane) error {
+	patchHelper, err := patch.NewHelper(eksCluster, r.Client)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	controllerutil.RemoveFinalizer(eksCluster, key.FinalizerName)
+	err = patchHelper.Patch(ctx, eksCluster)
+	if err != nil {
+		logger.Error(err, "failed to remove finalizer from AWSManagedControlPlane")
+		return microerror.Mask(err)
+	}
+	logger.Info("successfully removed finalizer from AWSManagedControlPlane")
+
+	return nil
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *EKSClusterReconciler) SetupWithManager(mgr ctrl.Manager, controllerOpts controller.Options) error {
 	err := ctrl.NewControllerManagedBy(mgr).
@@ -52,11 +52,18 @@ type LegacyClusterReconciler struct {
 	Installation string
 	recorder     record.EventRecorder
 	Cache        *gocache.Cache
+
+	CloudFrontCaching    bool
+	DiscoveryCacheMaxAge time.Duration
+	DNSProvider          string
+	DNSRoleARN           string
+	JWKSCacheMaxAge      time.Duration
 }
 
 // +kubebuilder:rbac:groups=infrastructure.giantswarm.io,resources=awscluster,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=infrastructure.giantswarm.io,resources=awscluster/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=infrastructure.giantswarm.io,resources=awscluster/finalizers,verbs=update
+// +kubebuilder:rbac:groups=externaldns.k8s.io,resources=dnsendpoints,verbs=get;list;watch;create;update;patch;delete
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -146,22 +153,62 @@ func (r *LegacyClusterReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 		return ctrl.Result{}, microerror.Mask(fmt.Errorf("invalid value %q in annotation %q, only `\"true\"` and `\"false\"` are allowed", keepCloudFrontOIDCProvider, key.KeepCloudFrontOIDCProviderAnnotation))
 	}
 
+	// Annotations are validated before the deletion is handled, invalid values must not block it.
+	deleting := !awsCluster.DeletionTimestamp.IsZero()
+
+	certificateKeyAlgorithm, err := key.CertificateKeyAlgorithm(awsCluster.Annotations[key.IRSACertificateKeyAlgorithmAnnotation])
+	if err = ignoreAnnotationErrorOnDelete(logger, deleting, err); err != nil {
+		return ctrl.Result{}, microerror.Mask(err)
+	}
+
+	dnsRoleARN, err := key.DNSRoleARN(awsCluster.Annotations[key.IRSADNSRoleARNAnnotation], r.DNSRoleARN)
+	if err = ignoreAnnotationErrorOnDelete(logger, deleting, err); err != nil {
+		return ctrl.Result{}, microerror.Mask(err)
+	}
+
+	additionalAccountRoleARNs, err := key.AdditionalAccountRoleARNs(awsCluster.Annotations[key.IRSAAdditionalAccountRolesAnnotation], accountID)
+	if err = ignoreAnnotationErrorOnDelete(logger, deleting, err); err != nil {
+		return ctrl.Result{}, microerror.Mask(err)
+	}
+
+	unknownAudiencesPolicy, err := key.UnknownAudiencesPolicy(awsCluster.Annotations[key.IRSAUnknownAudiencesAnnotation])
+	if err = ignoreAnnotationErrorOnDelete(logger, deleting, err); err != nil {
+		return ctrl.Result{}, microerror.Mask(err)
+	}
+
+	thumbprintMode, err := key.ThumbprintMode(awsCluster.Annotations[key.IRSAThumbprintModeAnnotation])
+	if err = ignoreAnnotationErrorOnDelete(logger, deleting, err); err != nil {
+		return ctrl.Result{}, microerror.Mask(err)
+	}
+
 	// create the cluster scope.
 	clusterScope, err := scope.NewClusterScope(scope.ClusterScopeParams{
 		AccountID:                  accountID,
+		AdditionalAccountRoleARNs:  additionalAccountRoleARNs,
+		AdditionalAudiences:        key.AdditionalAudiences(awsCluster.Annotations[key.IRSAAdditionalAudiencesAnnotation]),
 		ARN:                        arn,
 		BucketName:                 key.BucketName(accountID, awsCluster.Name),
 		Cache:                      r.Cache,
+		CertificateCustomerTagKeys: key.CertificateCustomerTagKeys(awsCluster.Annotations[key.IRSACertificateCustomerTagsAnnotation]),
+		CertificateKeyAlgorithm:    certificateKeyAlgorithm,
+		CloudFrontCaching:          r.CloudFrontCaching,
 		ClusterName:                awsCluster.Name,
 		ClusterNamespace:           awsCluster.Namespace,
 		ConfigName:                 key.ConfigName(awsCluster.Name),
+		DiscoveryCacheMaxAge:       r.DiscoveryCacheMaxAge,
+		DNSProvider:                r.DNSProvider,
+		DNSRoleARN:                 dnsRoleARN,
 		Installation:               r.Installation,
+		JWKSCacheMaxAge:            r.JWKSCacheMaxAge,
 		KeepCloudFrontOIDCProvider: keepCloudFrontOIDCProvider != "false",
 		Migration:                  migration,
 		PreCloudfrontAlias:         preCloudfrontAlias,
 		Region:                     awsCluster.Spec.Provider.Region,
 		ReleaseVersion:             key.Release(awsCluster),
+		RemovedAccountRoleARNs:     key.RemovedAccountRoleARNs(awsCluster.Annotations[key.IRSARegisteredAccountRolesAnnotation], accountID, additionalAccountRoleARNs),
 		SecretName:                 key.SecretName(awsCluster.Name),
+		ThumbprintMode:             thumbprintMode,
+		UnknownAudiencesPolicy:     unknownAudiencesPolicy,
 
 		Logger:  logger,
 		Cluster: awsCluster,
@@ -219,19 +266,35 @@ func (r *LegacyClusterReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 			logger.Info("successfully added finalizer to AWSCluster")
 		}
 
-		err := irsaService.Reconcile(ctx)
+		// Re-run regularly to ensure OIDC certificate thumbprints are up to date (see `EnsureOIDCProviders`)
+		requeueAfter := time.Minute * 5
+
+		// The accounts the OIDC providers are registered in are recorded during the reconciliation, so patch them
+		// even if it fails.
+		patchHelper, err := patch.NewHelper(awsCluster, r.Client)
+		if err != nil {
+			return ctrl.Result{}, microerror.Mask(err)
+		}
+
+		reconcileErr := irsaService.Reconcile(ctx, &requeueAfter)
+
+		err = patchHelper.Patch(ctx, awsCluster)
 		if err != nil {
+			logger.Error(err, "failed to patch AWSCluster")
 			return ctrl.Result{}, microerror.Mask(err)
 		}
 
+		if reconcileErr != nil {
+			return ctrl.Result{}, microerror.Mask(reconcileErr)
+		}
+
 		if created {
 			r.sendEvent(awsCluster, v1.EventTypeNormal, "IRSA", "IRSA bootstrap created")
 		}
 
-		// Re-run regularly to ensure OIDC certificate thumbprints are up to date (see `EnsureOIDCProviders`)
 		return ctrl.Result{
 			Requeue:      true,
-			RequeueAfter: time.Minute * 5,
+			RequeueAfter: requeueAfter,
 		}, nil
 	}
 }
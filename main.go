@@ -15,6 +15,7 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"os"
 	"time"
 
@@ -37,6 +38,9 @@ import (
 
 	"github.com/giantswarm/irsa-operator/controllers"
 	"github.com/giantswarm/irsa-operator/pkg/aws/scope"
+	"github.com/giantswarm/irsa-operator/pkg/dns"
+	"github.com/giantswarm/irsa-operator/pkg/gc"
+	"github.com/giantswarm/irsa-operator/pkg/util/record"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -62,6 +66,15 @@ func main() {
 	var probeAddr string
 	var installation string
 	var maxConcurrentReconciles int
+	var cloudFrontCaching bool
+	var discoveryCacheMaxAge time.Duration
+	var dnsProvider string
+	var dnsRoleARN string
+	var gcEnabled bool
+	var gcGracePeriod time.Duration
+	var gcInterval time.Duration
+	var jwksCacheMaxAge time.Duration
+	var requeueInterval time.Duration
 
 	flag.BoolVar(&capa, "capa", false, "Reconciles on CAPA resources.")
 	flag.BoolVar(&legacy, "legacy", false, "Reconciles on GiantSwarm AWS resources.")
@@ -69,6 +82,15 @@ func main() {
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.IntVar(&maxConcurrentReconciles, "max-concurrent-reconciles", 4, "The maximum number of concurrent reconciles for the controller.")
+	flag.BoolVar(&cloudFrontCaching, "cloudfront-caching", false, "Let CloudFront cache the OIDC documents according to their Cache-Control headers.")
+	flag.DurationVar(&discoveryCacheMaxAge, "discovery-cache-max-age", time.Hour, "The max age in the Cache-Control header of the OIDC discovery document.")
+	flag.StringVar(&dnsProvider, "dns-provider", dns.ProviderRoute53, fmt.Sprintf("The provider managing the DNS records, either %q or %q to create external-dns DNSEndpoint resources.", dns.ProviderRoute53, dns.ProviderDNSEndpoint))
+	flag.StringVar(&dnsRoleARN, "dns-role-arn", "", "The ARN of the IAM role used for Route53, e.g. in a central DNS account. Defaults to the cluster's role.")
+	flag.BoolVar(&gcEnabled, "gc-enabled", false, "Delete orphaned AWS resources of the installation after the grace period. They are reported as metrics either way.")
+	flag.DurationVar(&gcGracePeriod, "gc-grace-period", 24*time.Hour, "How long AWS resources have to be orphaned before they are deleted. The time is kept in memory, so the grace period starts over when the operator restarts or the leader changes.")
+	flag.DurationVar(&gcInterval, "gc-interval", time.Hour, "The interval in which orphaned AWS resources are collected.")
+	flag.DurationVar(&jwksCacheMaxAge, "jwks-cache-max-age", 5*time.Minute, "The max age in the Cache-Control header of the JWKS document.")
+	flag.DurationVar(&requeueInterval, "requeue-interval", 30*time.Minute, "The interval in which CAPA clusters are reconciled without changes, e.g. to refresh thumbprints.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
@@ -80,6 +102,11 @@ func main() {
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
+	if dnsProvider != dns.ProviderRoute53 && dnsProvider != dns.ProviderDNSEndpoint {
+		setupLog.Error(fmt.Errorf("invalid DNS provider %q", dnsProvider), "unable to start manager")
+		os.Exit(1)
+	}
+
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme: scheme,
 		Metrics: metricsserver.Options{
@@ -107,6 +134,12 @@ func main() {
 		os.Exit(1)
 	}
 
+	ctx := ctrl.SetupSignalHandler()
+
+	// Events emitted from the AWS services (e.g. permission issues, ownership conflicts) go through the
+	// package-level recorder.
+	record.InitFromRecorder(mgr.GetEventRecorderFor("irsa-operator"))
+
 	cache := gocache.New(
 		// The cache is shared and can be used for various things.
 		// A reasonable expiration duration should be specified at usage, not here.
@@ -121,6 +154,12 @@ func main() {
 			Scheme:       mgr.GetScheme(),
 			Installation: installation,
 			Cache:        cache,
+
+			CloudFrontCaching:    cloudFrontCaching,
+			DiscoveryCacheMaxAge: discoveryCacheMaxAge,
+			DNSProvider:          dnsProvider,
+			DNSRoleARN:           dnsRoleARN,
+			JWKSCacheMaxAge:      jwksCacheMaxAge,
 		}).SetupWithManager(mgr); err != nil {
 			setupLog.Error(err, "unable to create controller", "controller", "Cluster")
 			os.Exit(1)
@@ -137,7 +176,14 @@ func main() {
 			Scheme:       mgr.GetScheme(),
 			Installation: installation,
 			Cache:        cache,
-		}).SetupWithManager(mgr, opts); err != nil {
+
+			CloudFrontCaching:    cloudFrontCaching,
+			DiscoveryCacheMaxAge: discoveryCacheMaxAge,
+			DNSProvider:          dnsProvider,
+			DNSRoleARN:           dnsRoleARN,
+			JWKSCacheMaxAge:      jwksCacheMaxAge,
+			RequeueInterval:      requeueInterval,
+		}).SetupWithManager(ctx, mgr, opts); err != nil {
 			setupLog.Error(err, "unable to create controller", "controller", "Cluster")
 			os.Exit(1)
 		}
@@ -151,6 +197,19 @@ func main() {
 			setupLog.Error(err, "unable to create controller", "controller", "AWSManagedControlPlane")
 			os.Exit(1)
 		}
+		if err = mgr.Add(&gc.Collector{
+			Client:       mgr.GetClient(),
+			Log:          ctrl.Log.WithName("gc"),
+			Installation: installation,
+			Cache:        cache,
+
+			DeleteOrphans: gcEnabled,
+			GracePeriod:   gcGracePeriod,
+			Interval:      gcInterval,
+		}); err != nil {
+			setupLog.Error(err, "unable to add runnable", "runnable", "gc")
+			os.Exit(1)
+		}
 
 	}
 	// +kubebuilder:scaffold:builder
@@ -165,7 +224,7 @@ func main() {
 	}
 
 	setupLog.Info("starting manager", "currentCommit", scope.CurrentCommit)
-	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+	if err := mgr.Start(ctx); err != nil {
 		setupLog.Error(err, "problem running manager")
 		os.Exit(1)
 	}
@@ -0,0 +1,169 @@
+package scope
+
+import (
+	"time"
+
+	awsclient "github.com/aws/aws-sdk-go/aws/client"
+	"github.com/go-logr/logr"
+	gocache "github.com/patrickmn/go-cache"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// AccountScopeParams defines the input parameters used to create a new AccountScope.
+type AccountScopeParams struct {
+	AccountID    string
+	ARN          string
+	Cache        *gocache.Cache
+	Installation string
+	Region       string
+	// Target is the object AWS permission issues are reported on as events.
+	Target runtime.Object
+
+	Logger logr.Logger
+}
+
+// NewAccountScope creates a new AccountScope from the supplied parameters.
+func NewAccountScope(params AccountScopeParams) (*AccountScope, error) {
+	if params.AccountID == "" {
+		return nil, errors.New("failed to generate new scope from emtpy string AccountID")
+	}
+	if params.ARN == "" {
+		return nil, errors.New("failed to generate new scope from emtpy string ARN")
+	}
+	if params.Cache == nil {
+		return nil, errors.New("failed to generate new scope from nil Cache")
+	}
+	if params.Installation == "" {
+		return nil, errors.New("failed to generate new scope from emtpy string Installation")
+	}
+	if params.Region == "" {
+		return nil, errors.New("failed to generate new scope from emtpy string Region")
+	}
+	if params.Target == nil {
+		return nil, errors.New("failed to generate new scope from nil Target")
+	}
+
+	session, err := sessionForRegion(params.Region)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create aws session")
+	}
+
+	return &AccountScope{
+		accountID:    params.AccountID,
+		assumeRole:   params.ARN,
+		cache:        params.Cache,
+		installation: params.Installation,
+		region:       params.Region,
+		target:       params.Target,
+
+		Logr:    params.Logger,
+		session: session,
+	}, nil
+}
+
+// AccountScope defines the context for operating on all resources of the installation in an AWS account, as
+// opposed to the resources of a single cluster.
+type AccountScope struct {
+	accountID    string
+	assumeRole   string
+	cache        *gocache.Cache
+	installation string
+	region       string
+	target       runtime.Object
+
+	Logr    logr.Logger
+	session awsclient.ConfigProvider
+}
+
+func (s *AccountScope) Logger() logr.Logger {
+	return s.Logr
+}
+
+// AccountID returns the account ID of the assumed role.
+func (s *AccountScope) AccountID() string {
+	return s.accountID
+}
+
+// ARN returns the AWS SDK assumed role.
+func (s *AccountScope) ARN() string {
+	return s.assumeRole
+}
+
+// Cache returns the reconciler cache.
+func (s *AccountScope) Cache() *gocache.Cache {
+	return s.cache
+}
+
+// Installation returns the installation name.
+func (s *AccountScope) Installation() string {
+	return s.installation
+}
+
+// Region returns the region of the AWS session.
+func (s *AccountScope) Region() string {
+	return s.region
+}
+
+// Session returns the AWS SDK session.
+func (s *AccountScope) Session() awsclient.ConfigProvider {
+	return s.session
+}
+
+// Target returns the object AWS permission issues are reported on.
+func (s *AccountScope) Target() runtime.Object {
+	return s.target
+}
+
+// The account scope belongs to no cluster. The following methods only make it usable with the services of the
+// cluster scope, for operations on all resources of the account, like listing and deleting them.
+
+// BucketName returns an empty string, the account scope has no bucket.
+func (s *AccountScope) BucketName() string {
+	return ""
+}
+
+// CallerReference returns an empty string, the account scope has no distribution.
+func (s *AccountScope) CallerReference() string {
+	return ""
+}
+
+// Cluster returns the object AWS permission issues are reported on.
+func (s *AccountScope) Cluster() runtime.Object {
+	return s.target
+}
+
+// ClusterName returns an empty string, the account scope has no cluster.
+func (s *AccountScope) ClusterName() string {
+	return ""
+}
+
+// ClusterNamespace returns an empty string, the account scope has no cluster.
+func (s *AccountScope) ClusterNamespace() string {
+	return ""
+}
+
+// DiscoveryCacheMaxAge returns zero, the account scope uploads no OIDC documents.
+func (s *AccountScope) DiscoveryCacheMaxAge() time.Duration {
+	return 0
+}
+
+// JWKSCacheMaxAge returns zero, the account scope uploads no OIDC documents.
+func (s *AccountScope) JWKSCacheMaxAge() time.Duration {
+	return 0
+}
+
+// MigrationNeeded returns false, the account scope has no cluster to migrate.
+func (s *AccountScope) MigrationNeeded() bool {
+	return false
+}
+
+// ThumbprintMode returns an empty string, the account scope creates no OIDC providers.
+func (s *AccountScope) ThumbprintMode() string {
+	return ""
+}
+
+// UnknownAudiencesPolicy returns an empty string, the account scope creates no OIDC providers.
+func (s *AccountScope) UnknownAudiencesPolicy() string {
+	return ""
+}
@@ -77,6 +77,19 @@ func NewS3Client(session aws.Session, arn string, target runtime.Object) *s3.S3
 	return S3Client
 }
 
+// NewS3ClientForRegion creates a new S3 API client for a given session which operates in the given region
+// instead of the session's one.
+func NewS3ClientForRegion(session aws.Session, arn string, region string, target runtime.Object) *s3.S3 {
+	S3Client := s3.New(session.Session(), &awsclient.Config{
+		Credentials: stscreds.NewCredentials(session.Session(), arn),
+		Region:      awsclient.String(region),
+	})
+	S3Client.Handlers.Build.PushFrontNamed(getUserAgentHandler())
+	S3Client.Handlers.Complete.PushBack(recordAWSPermissionsIssue(target))
+
+	return S3Client
+}
+
 // NewIAMClient creates a new IAM API client for a given session
 func NewIAMClient(session aws.Session, arn string, target runtime.Object) *iam.IAM {
 	IAMClient := iam.New(session.Session(), &awsclient.Config{Credentials: stscreds.NewCredentials(session.Session(), arn)})
@@ -2,6 +2,8 @@ package scope
 
 import (
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	awsclient "github.com/aws/aws-sdk-go/aws/client"
@@ -11,6 +13,7 @@ import (
 	"github.com/go-logr/logr"
 	gocache "github.com/patrickmn/go-cache"
 	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/runtime"
 
 	"github.com/giantswarm/irsa-operator/pkg/key"
@@ -19,23 +22,41 @@ import (
 // ClusterScopeParams defines the input parameters used to create a new Scope.
 type ClusterScopeParams struct {
 	AccountID                  string
+	AdditionalAccountRoleARNs  []string
+	AdditionalAudiences        []string
 	ARN                        string
 	BaseDomain                 string
 	BucketName                 string
 	Cache                      *gocache.Cache
+	CertificateCustomerTagKeys []string
+	CertificateKeyAlgorithm    string
+	CertificateSecretName      string
+	CloudFrontCaching          bool
 	Cluster                    runtime.Object
 	ClusterName                string
 	ClusterNamespace           string
 	ConfigName                 string
+	DiscoveryCacheMaxAge       time.Duration
+	DNSProvider                string
+	DNSRoleARN                 string
+	ExtraAliases               []string
 	Installation               string
+	JWKSCacheMaxAge            time.Duration
 	KeepCloudFrontOIDCProvider bool
 	ManagementClusterAccountID string
 	ManagementClusterRegion    string
 	Migration                  bool
+	PodIdentityAssociations    []key.PodIdentityAssociation
+	PodIdentityManaged         bool
 	PreCloudfrontAlias         bool
 	Region                     string
 	ReleaseVersion             string
+	RemovedAccountRoleARNs     []string
+	RemovedAliases             []string
+	ReplicaRegion              string
 	SecretName                 string
+	ThumbprintMode             string
+	UnknownAudiencesPolicy     string
 	VPCMode                    string
 
 	Logger  logr.Logger
@@ -81,6 +102,9 @@ func NewClusterScope(params ClusterScopeParams) (*ClusterScope, error) {
 	if params.SecretName == "" {
 		return nil, errors.New("failed to generate new scope from emtpy string SecretName")
 	}
+	if params.ReplicaRegion != "" && params.ReplicaRegion == params.Region {
+		return nil, errors.Errorf("failed to generate new scope, ReplicaRegion %q must differ from Region", params.ReplicaRegion)
+	}
 
 	// `ParseTolerant` instead of `Parse` in case we ever mistakenly use the `v` version prefix or other non-strict format
 	releaseSemver, err := semver.ParseTolerant(params.ReleaseVersion)
@@ -105,24 +129,42 @@ func NewClusterScope(params ClusterScopeParams) (*ClusterScope, error) {
 
 	return &ClusterScope{
 		accountID:                  params.AccountID,
+		additionalAccountRoleARNs:  params.AdditionalAccountRoleARNs,
+		additionalAudiences:        params.AdditionalAudiences,
 		managementClusterAccountID: params.ManagementClusterAccountID,
 		managementClusterRegion:    params.ManagementClusterRegion,
 		assumeRole:                 params.ARN,
 		baseDomain:                 params.BaseDomain,
 		bucketName:                 params.BucketName,
 		cache:                      params.Cache,
+		certificateCustomerTagKeys: params.CertificateCustomerTagKeys,
+		certificateKeyAlgorithm:    params.CertificateKeyAlgorithm,
+		certificateSecretName:      params.CertificateSecretName,
+		cloudFrontCaching:          params.CloudFrontCaching,
 		cluster:                    params.Cluster,
 		clusterName:                params.ClusterName,
 		clusterNamespace:           params.ClusterNamespace,
 		configName:                 params.ConfigName,
+		discoveryCacheMaxAge:       params.DiscoveryCacheMaxAge,
+		dnsProvider:                params.DNSProvider,
+		dnsRoleARN:                 params.DNSRoleARN,
+		extraAliases:               params.ExtraAliases,
 		installation:               params.Installation,
+		jwksCacheMaxAge:            params.JWKSCacheMaxAge,
 		keepCloudFrontOIDCProvider: params.KeepCloudFrontOIDCProvider,
 		migration:                  params.Migration,
+		podIdentityAssociations:    params.PodIdentityAssociations,
+		podIdentityManaged:         params.PodIdentityManaged,
 		preCloudfrontAlias:         params.PreCloudfrontAlias,
 		region:                     params.Region,
 		releaseVersion:             params.ReleaseVersion,
 		releaseSemver:              releaseSemver,
+		removedAccountRoleARNs:     params.RemovedAccountRoleARNs,
+		removedAliases:             params.RemovedAliases,
+		replicaRegion:              params.ReplicaRegion,
 		secretName:                 params.SecretName,
+		thumbprintMode:             params.ThumbprintMode,
+		unknownAudiencesPolicy:     params.UnknownAudiencesPolicy,
 		vpcMode:                    params.VPCMode,
 
 		Logr:    params.Logger,
@@ -133,24 +175,42 @@ func NewClusterScope(params ClusterScopeParams) (*ClusterScope, error) {
 // ClusterScope defines the basic context for an actuator to operate upon.
 type ClusterScope struct {
 	accountID                  string
+	additionalAccountRoleARNs  []string
+	additionalAudiences        []string
 	baseDomain                 string
 	bucketName                 string
 	assumeRole                 string
 	cache                      *gocache.Cache
+	certificateCustomerTagKeys []string
+	certificateKeyAlgorithm    string
+	certificateSecretName      string
+	cloudFrontCaching          bool
 	cluster                    runtime.Object
 	clusterName                string
 	clusterNamespace           string
 	configName                 string
+	discoveryCacheMaxAge       time.Duration
+	dnsProvider                string
+	dnsRoleARN                 string
+	extraAliases               []string
 	installation               string
+	jwksCacheMaxAge            time.Duration
 	keepCloudFrontOIDCProvider bool
 	managementClusterAccountID string
 	managementClusterRegion    string
 	migration                  bool
+	podIdentityAssociations    []key.PodIdentityAssociation
+	podIdentityManaged         bool
 	preCloudfrontAlias         bool
 	region                     string
 	releaseVersion             string
 	releaseSemver              semver.Version
+	removedAccountRoleARNs     []string
+	removedAliases             []string
+	replicaRegion              string
 	secretName                 string
+	thumbprintMode             string
+	unknownAudiencesPolicy     string
 	vpcMode                    string
 
 	Logr    logr.Logger
@@ -166,6 +226,16 @@ func (s *ClusterScope) AccountID() string {
 	return s.accountID
 }
 
+// AdditionalAccountRoleARNs returns the roles of the other accounts the cluster's OIDC providers are registered in.
+func (s *ClusterScope) AdditionalAccountRoleARNs() []string {
+	return s.additionalAccountRoleARNs
+}
+
+// AdditionalAudiences returns the audiences of the OIDC providers in addition to the STS one.
+func (s *ClusterScope) AdditionalAudiences() []string {
+	return s.additionalAudiences
+}
+
 // ManagementClusterAccountID returns the account ID used by the Management Cluster.
 func (s *ClusterScope) ManagementClusterAccountID() string {
 	return s.managementClusterAccountID
@@ -224,16 +294,77 @@ func (s *ClusterScope) ClusterNamespace() string {
 	return s.clusterNamespace
 }
 
+// CertificateCustomerTagKeys returns the customer tag keys the operator set on the ACM certificate.
+func (s *ClusterScope) CertificateCustomerTagKeys() []string {
+	return s.certificateCustomerTagKeys
+}
+
+// SetCertificateCustomerTagKeys records the customer tag keys the operator set on the ACM certificate on the cluster
+// object, which the controller persists.
+func (s *ClusterScope) SetCertificateCustomerTagKeys(keys []string) {
+	s.certificateCustomerTagKeys = keys
+	s.setAnnotation(key.IRSACertificateCustomerTagsAnnotation, strings.Join(keys, ","))
+}
+
+// CertificateKeyAlgorithm returns the key algorithm of the ACM certificate for the CloudFront aliases.
+func (s *ClusterScope) CertificateKeyAlgorithm() string {
+	if s.certificateKeyAlgorithm == "" {
+		return key.DefaultCertificateKeyAlgorithm
+	}
+	return s.certificateKeyAlgorithm
+}
+
+// CertificateSecretName returns the name of the TLS secret to import into ACM, or an empty string if the
+// certificate is requested from ACM.
+func (s *ClusterScope) CertificateSecretName() string {
+	return s.certificateSecretName
+}
+
+// CloudFrontCaching returns whether the CloudFront distribution caches the OIDC documents according to their
+// Cache-Control headers.
+func (s *ClusterScope) CloudFrontCaching() bool {
+	return s.cloudFrontCaching
+}
+
 // ConfigName returns the name of Cloudfront config from the cluster.
 func (s *ClusterScope) ConfigName() string {
 	return s.configName
 }
 
+// DiscoveryCacheMaxAge returns the max age set in the Cache-Control header of the OIDC discovery document.
+func (s *ClusterScope) DiscoveryCacheMaxAge() time.Duration {
+	return s.discoveryCacheMaxAge
+}
+
+// DNSProvider returns the name of the provider managing the DNS records.
+func (s *ClusterScope) DNSProvider() string {
+	return s.dnsProvider
+}
+
+// DNSRoleARN returns the role assumed for Route53, which falls back to the cluster's role if no separate DNS role
+// is configured.
+func (s *ClusterScope) DNSRoleARN() string {
+	if s.dnsRoleARN == "" {
+		return s.assumeRole
+	}
+	return s.dnsRoleARN
+}
+
+// ExtraAliases returns the domains served by the CloudFront distribution in addition to `irsa.<basedomain>`.
+func (s *ClusterScope) ExtraAliases() []string {
+	return s.extraAliases
+}
+
 // Installation returns the name of the installation where the cluster object is located.
 func (s *ClusterScope) Installation() string {
 	return s.installation
 }
 
+// JWKSCacheMaxAge returns the max age set in the Cache-Control header of the JWKS document.
+func (s *ClusterScope) JWKSCacheMaxAge() time.Duration {
+	return s.jwksCacheMaxAge
+}
+
 // KeepCloudFrontOIDCProvider returns whether the `<random>.cloudfront.net` OIDC provider
 // domain should be created/kept (true) or deleted (false)
 func (s *ClusterScope) KeepCloudFrontOIDCProvider() bool {
@@ -245,6 +376,29 @@ func (s *ClusterScope) MigrationNeeded() bool {
 	return s.migration
 }
 
+// PodIdentityAssociations returns the desired EKS Pod Identity associations, nil if they are not managed.
+func (s *ClusterScope) PodIdentityAssociations() []key.PodIdentityAssociation {
+	return s.podIdentityAssociations
+}
+
+// PodIdentityManaged returns whether the operator created pod identity associations of the cluster, which have to be
+// deleted even if they are not managed anymore.
+func (s *ClusterScope) PodIdentityManaged() bool {
+	return s.podIdentityManaged
+}
+
+// SetPodIdentityManaged records on the cluster object whether the operator created pod identity associations of the
+// cluster, which the controllers persist after the reconciliation.
+func (s *ClusterScope) SetPodIdentityManaged(managed bool) {
+	s.podIdentityManaged = managed
+
+	value := ""
+	if managed {
+		value = "true"
+	}
+	s.setAnnotation(key.IRSAManagedPodIdentityAssociationsAnnotation, value)
+}
+
 // PreCloudfrontAlias returns if the cloudfront alias should be used before v19.0.0.
 func (s *ClusterScope) PreCloudfrontAlias() bool {
 	return s.preCloudfrontAlias
@@ -265,6 +419,62 @@ func (s *ClusterScope) Release() *semver.Version {
 	return &s.releaseSemver
 }
 
+// RemovedAccountRoleARNs returns the roles of the accounts the cluster's OIDC providers are still registered in,
+// although they were removed from the additional account roles.
+func (s *ClusterScope) RemovedAccountRoleARNs() []string {
+	return s.removedAccountRoleARNs
+}
+
+// SetRegisteredAccountRoleARNs records the additional account roles the cluster's OIDC providers are registered with
+// on the cluster object, which the controllers persist after the reconciliation.
+func (s *ClusterScope) SetRegisteredAccountRoleARNs(roleARNs []string) {
+	s.setAnnotation(key.IRSARegisteredAccountRolesAnnotation, strings.Join(roleARNs, ","))
+}
+
+// RemovedAliases returns the aliases the operator created DNS records for, although they were removed from the
+// extra aliases.
+func (s *ClusterScope) RemovedAliases() []string {
+	return s.removedAliases
+}
+
+// SetManagedAliases records the extra aliases the operator created DNS records for on the cluster object, which the
+// controllers persist after the reconciliation.
+func (s *ClusterScope) SetManagedAliases(aliases []string) {
+	s.setAnnotation(key.IRSAManagedAliasesAnnotation, strings.Join(aliases, ","))
+}
+
+// setAnnotation sets the annotation on the cluster object, or removes it if the value is empty.
+func (s *ClusterScope) setAnnotation(annotation, value string) {
+	accessor, err := meta.Accessor(s.cluster)
+	if err != nil {
+		return
+	}
+
+	annotations := accessor.GetAnnotations()
+	if value == "" {
+		delete(annotations, annotation)
+	} else {
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[annotation] = value
+	}
+	accessor.SetAnnotations(annotations)
+}
+
+// ReplicaRegion returns the region of the OIDC S3 bucket replica, or an empty string if there is none.
+func (s *ClusterScope) ReplicaRegion() string {
+	return s.replicaRegion
+}
+
+// ReplicaBucketName returns the name of the OIDC S3 bucket replica, or an empty string if there is none.
+func (s *ClusterScope) ReplicaBucketName() string {
+	if s.replicaRegion == "" {
+		return ""
+	}
+	return key.ReplicaBucketName(s.BucketName())
+}
+
 // SecretName returns the name of the OIDC secret from the cluster.
 func (s *ClusterScope) SecretName() string {
 	return s.secretName
@@ -275,6 +485,23 @@ func (s *ClusterScope) Session() awsclient.ConfigProvider {
 	return s.session
 }
 
+// ThumbprintMode returns which certificates of the issuer's TLS chain are set as OIDC provider thumbprints, the root
+// certificate by default.
+func (s *ClusterScope) ThumbprintMode() string {
+	if s.thumbprintMode == "" {
+		return key.ThumbprintModeRoot
+	}
+	return s.thumbprintMode
+}
+
+// UnknownAudiencesPolicy returns what to do with unknown audiences of the OIDC providers, removing them by default.
+func (s *ClusterScope) UnknownAudiencesPolicy() string {
+	if s.unknownAudiencesPolicy == "" {
+		return key.UnknownAudiencesRemove
+	}
+	return s.unknownAudiencesPolicy
+}
+
 // VPCMode returns the VPC mode used on this cluster.
 func (s *ClusterScope) VPCMode() string {
 	return s.vpcMode
@@ -1,17 +1,36 @@
 package scope
 
 import (
+	"time"
+
 	"github.com/giantswarm/irsa-operator/pkg/aws"
+	"github.com/giantswarm/irsa-operator/pkg/key"
 )
 
 // ACMScope is a scope for use with the ACM reconciling service in cluster
 type ACMScope interface {
 	aws.ClusterScoper
+
+	// AccountID returns the ID of the cluster's account, used as label of the certificate metrics.
+	AccountID() string
+	// CertificateCustomerTagKeys returns the customer tag keys the operator set on the ACM certificate.
+	CertificateCustomerTagKeys() []string
+	CertificateKeyAlgorithm() string
+	// SetCertificateCustomerTagKeys records the customer tag keys the operator set on the ACM certificate.
+	SetCertificateCustomerTagKeys([]string)
+}
+
+// DNSEndpointScope is a scope for use with the external-dns DNSEndpoint reconciling service in cluster
+type DNSEndpointScope interface {
+	aws.ClusterScoper
 }
 
 // EKSScope is a scope for use with the EKS reconciling service in cluster
 type EKSScope interface {
 	aws.ClusterScoper
+
+	// PodIdentityAssociations returns the desired EKS Pod Identity associations, nil if they are not managed.
+	PodIdentityAssociations() []key.PodIdentityAssociation
 }
 
 // CloudfrontScope is a scope for use with the Cloudfront reconciling service in cluster
@@ -22,14 +41,27 @@ type CloudfrontScope interface {
 // IAMScope is a scope for use with the IAM reconciling service in cluster
 type IAMScope interface {
 	aws.ClusterScoper
+
+	// ThumbprintMode returns which certificates of the issuer's TLS chain are set as OIDC provider thumbprints.
+	ThumbprintMode() string
+	// UnknownAudiencesPolicy returns what to do with audiences of the OIDC providers that are not desired.
+	UnknownAudiencesPolicy() string
 }
 
 // Route53Scope is a scope for use with the route53 reconciling service in cluster
 type Route53Scope interface {
 	aws.ClusterScoper
+
+	// DNSRoleARN returns the role assumed for Route53, which may live in a central DNS account.
+	DNSRoleARN() string
 }
 
 // S3Scope is a scope for use with the S3 reconciling service in cluster
 type S3Scope interface {
 	aws.ClusterScoper
+
+	// DiscoveryCacheMaxAge returns the max age set in the Cache-Control header of the OIDC discovery document.
+	DiscoveryCacheMaxAge() time.Duration
+	// JWKSCacheMaxAge returns the max age set in the Cache-Control header of the JWKS document.
+	JWKSCacheMaxAge() time.Duration
 }
@@ -2,23 +2,36 @@ package acm
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/acm"
 	"github.com/aws/aws-sdk-go/service/acm/acmiface"
 	"github.com/giantswarm/microerror"
 
 	"github.com/giantswarm/irsa-operator/pkg/aws/services/route53"
 	"github.com/giantswarm/irsa-operator/pkg/key"
-	"github.com/giantswarm/irsa-operator/pkg/util"
+	"github.com/giantswarm/irsa-operator/pkg/util/record"
+	"github.com/giantswarm/irsa-operator/pkg/util/slicediff"
 )
 
-func (s *Service) EnsureCertificate(domain string, customerTags map[string]string) (*string, error) {
-	s.scope.Logger().Info(fmt.Sprintf("Ensuring ACM certificate for domain %q", domain))
+// DomainValidationRecord is the DNS record proving ownership of one of the domains of a certificate.
+type DomainValidationRecord struct {
+	Domain string
+	CNAME  route53.CNAME
+}
+
+// EnsureCertificate makes sure a certificate for exactly the given domains exists. The first domain is used as
+// the certificate's domain name, the others are added as subject alternative names. When the set of domains
+// changes, a new certificate is requested and older ones are left in place until DeleteUnusedCertificates is
+// called after the distribution switched to the new certificate.
+func (s *Service) EnsureCertificate(domains []string, customerTags map[string]string) (*string, error) {
+	s.scope.Logger().Info(fmt.Sprintf("Ensuring ACM certificate for domains %q", domains))
 
 	// Check if certificate exists
-	certificateArn, err := s.findCertificateForDomain(domain)
+	certificateArn, err := s.findCertificateForDomains(domains)
 	if err != nil {
 		return nil, microerror.Mask(err)
 	}
@@ -26,46 +39,27 @@ func (s *Service) EnsureCertificate(domain string, customerTags map[string]strin
 	if certificateArn != nil {
 		s.scope.Logger().Info("ACM certificate already exists")
 
+		err = s.ensureTags(*certificateArn, customerTags)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
 		return certificateArn, nil
 	}
 
 	input := &acm.RequestCertificateInput{
-		DomainName: aws.String(domain),
-		Options:    &acm.CertificateOptions{},
-		Tags: []*acm.Tag{
-			{
-				Key:   aws.String(key.S3TagOrganization),
-				Value: aws.String(util.RemoveOrg(s.scope.ClusterNamespace())),
-			},
-			{
-				Key:   aws.String(fmt.Sprintf(key.S3TagCloudProvider, s.scope.ClusterName())),
-				Value: aws.String("owned"),
-			},
-			{
-				Key:   aws.String(key.S3TagInstallation),
-				Value: aws.String(s.scope.Installation()),
-			},
-		},
+		DomainName:       aws.String(domains[0]),
+		KeyAlgorithm:     aws.String(s.scope.CertificateKeyAlgorithm()),
+		Options:          &acm.CertificateOptions{},
 		ValidationMethod: aws.String(acm.ValidationMethodDns),
 	}
-	// add cluster tag if missing (this is case for vintage clusters)
-	if _, ok := customerTags[key.S3TagCluster]; !ok {
-		if customerTags == nil {
-			customerTags = make(map[string]string)
-		}
-		customerTags[key.S3TagCluster] = s.scope.ClusterName()
+	if len(domains) > 1 {
+		input.SubjectAlternativeNames = aws.StringSlice(domains[1:])
 	}
-
-	for k, v := range customerTags {
-		tag := &acm.Tag{
-			Key:   aws.String(k),
-			Value: aws.String(v),
-		}
-		input.Tags = append(input.Tags, tag)
+	for k, v := range s.desiredTags(customerTags) {
+		input.Tags = append(input.Tags, &acm.Tag{Key: aws.String(k), Value: aws.String(v)})
 	}
 
-	input.Tags = util.FilterUniqueTags(input.Tags)
-
 	s.scope.Logger().Info("Creating ACM certificate")
 
 	output, err := s.Client.RequestCertificate(input)
@@ -73,6 +67,8 @@ func (s *Service) EnsureCertificate(domain string, customerTags map[string]strin
 		return nil, microerror.Mask(err)
 	}
 
+	s.recordCustomerTagKeys(customerTags)
+
 	s.scope.Logger().Info("ACM certificate created successfully")
 	return output.CertificateArn, nil
 }
@@ -100,7 +96,7 @@ func (s *Service) GetCertificateExpirationTS(arn string) (*time.Time, error) {
 	return cert.NotAfter, nil
 }
 
-// IsValidated checks wheter an ACM certificate's ownership is already validated or not.
+// IsValidated checks wheter an ACM certificate's ownership is already validated for all of its domains.
 func (s *Service) IsValidated(arn string) (bool, error) {
 	s.scope.Logger().Info("Checking ACM certificate's validation status")
 
@@ -118,13 +114,19 @@ func (s *Service) IsValidated(arn string) (bool, error) {
 		renewalValidationPending = *cert.RenewalSummary.RenewalStatus == acm.RenewalStatusPendingValidation
 	}
 
-	validated := *cert.DomainValidationOptions[0].ValidationStatus == acm.DomainStatusSuccess
-	return validated && !renewalValidationPending, nil
+	for _, option := range cert.DomainValidationOptions {
+		if aws.StringValue(option.ValidationStatus) != acm.DomainStatusSuccess {
+			return false, nil
+		}
+	}
+
+	return !renewalValidationPending, nil
 }
 
-// GetValidationCNAME returns a CNAME record that needs to be created in order for automated domain ownership validation to work.
-func (s *Service) GetValidationCNAME(arn string) (*route53.CNAME, error) {
-	s.scope.Logger().Info("Generating CNAME record for ACM certificate")
+// GetValidationCNAMEs returns the CNAME records that need to be created in order for automated domain ownership
+// validation to work, one per domain of the certificate. Domains sharing the same record are only returned once.
+func (s *Service) GetValidationCNAMEs(arn string) ([]DomainValidationRecord, error) {
+	s.scope.Logger().Info("Generating CNAME records for ACM certificate")
 
 	cert, err := s.getACMCertificate(arn)
 	if err != nil {
@@ -132,50 +134,203 @@ func (s *Service) GetValidationCNAME(arn string) (*route53.CNAME, error) {
 	}
 
 	// If certificate is just created, validation data might be missing.
-	if len(cert.DomainValidationOptions) == 0 ||
-		cert.DomainValidationOptions[0].ResourceRecord == nil ||
-		cert.DomainValidationOptions[0].ResourceRecord.Name == nil ||
-		cert.DomainValidationOptions[0].ResourceRecord.Value == nil {
+	if len(cert.DomainValidationOptions) == 0 {
 		return nil, microerror.Mask(domainValidationDnsRecordNotFound)
 	}
 
-	return &route53.CNAME{
-		Name:  *cert.DomainValidationOptions[0].ResourceRecord.Name,
-		Value: *cert.DomainValidationOptions[0].ResourceRecord.Value,
-	}, nil
+	records := make([]DomainValidationRecord, 0, len(cert.DomainValidationOptions))
+	seen := map[string]bool{}
+	for _, option := range cert.DomainValidationOptions {
+		if option.DomainName == nil ||
+			option.ResourceRecord == nil ||
+			option.ResourceRecord.Name == nil ||
+			option.ResourceRecord.Value == nil {
+			return nil, microerror.Mask(domainValidationDnsRecordNotFound)
+		}
+
+		if seen[*option.ResourceRecord.Name] {
+			continue
+		}
+		seen[*option.ResourceRecord.Name] = true
+
+		records = append(records, DomainValidationRecord{
+			Domain: *option.DomainName,
+			CNAME: route53.CNAME{
+				Name:  *option.ResourceRecord.Name,
+				Value: *option.ResourceRecord.Value,
+			},
+		})
+	}
+
+	return records, nil
 }
 
+// GetValidationRecords returns the DNS validation records of all certificates for the given domain owned by the
+// cluster, so that they can be removed along with the certificates.
+func (s *Service) GetValidationRecords(domain string) ([]DomainValidationRecord, error) {
+	certs, err := s.findCertificatesForDomain(domain)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	records := make([]DomainValidationRecord, 0)
+	seen := map[string]bool{}
+	for _, cert := range certs {
+		owned, err := s.isOwned(*cert.CertificateArn)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		if !owned {
+			continue
+		}
+
+		certRecords, err := s.GetValidationCNAMEs(*cert.CertificateArn)
+		if microerror.Cause(err) == domainValidationDnsRecordNotFound {
+			// Imported certificates or ones that never got far enough to have validation records.
+			continue
+		} else if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		for _, record := range certRecords {
+			if seen[record.CNAME.Name] {
+				continue
+			}
+			seen[record.CNAME.Name] = true
+			records = append(records, record)
+		}
+	}
+
+	return records, nil
+}
+
+// DeleteCertificate deletes all certificates for the given domain, including ones left over from earlier sets of
+// subject alternative names.
 func (s *Service) DeleteCertificate(domain string) error {
-	s.scope.Logger().Info("Ensuring ACM certificate is deleted")
+	s.scope.Logger().Info("Ensuring ACM certificates are deleted")
 
-	arn, err := s.findCertificateForDomain(domain)
+	certs, err := s.findCertificatesForDomain(domain)
 	if err != nil {
 		return microerror.Mask(err)
 	}
 
-	if arn != nil {
-		s.scope.Logger().Info("Deleting ACM certificate")
-		_, err = s.Client.DeleteCertificate(&acm.DeleteCertificateInput{CertificateArn: arn})
+	if len(certs) == 0 {
+		s.scope.Logger().Info("ACM certificate was not found")
+		return nil
+	}
+
+	for _, cert := range certs {
+		owned, err := s.isOwned(*cert.CertificateArn)
 		if err != nil {
 			return microerror.Mask(err)
 		}
+		if !owned {
+			s.scope.Logger().Info("ACM certificate is not owned by this cluster, refusing to delete it", "arn", *cert.CertificateArn)
+			record.Warnf(s.scope.Cluster(), "CertificateNotOwned", "Refusing to delete ACM certificate %s for domain %s since it is not owned by this cluster", *cert.CertificateArn, domain)
+			continue
+		}
 
-		s.scope.Logger().Info("Deleted ACM certificate")
-		return nil
+		s.scope.Logger().Info("Deleting ACM certificate", "arn", *cert.CertificateArn)
+		_, err = s.Client.DeleteCertificate(&acm.DeleteCertificateInput{CertificateArn: cert.CertificateArn})
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	s.scope.Logger().Info("Deleted ACM certificates")
+	return nil
+}
+
+// DeleteUnusedCertificates deletes certificates for the given domain that were replaced by the certificate with
+// the given ARN. Certificates still attached to a distribution are skipped, since CloudFront takes a while to
+// deploy the switch to the new certificate. They are deleted in a later reconciliation.
+func (s *Service) DeleteUnusedCertificates(domain, currentArn string) error {
+	certs, err := s.findCertificatesForDomain(domain)
+	if err != nil {
+		return microerror.Mask(err)
 	}
 
-	s.scope.Logger().Info("ACM certificate was not found")
+	for _, cert := range certs {
+		if *cert.CertificateArn == currentArn {
+			continue
+		}
+
+		// Certificates of other owners for the same domain are not replaced by ours, just leave them alone.
+		owned, err := s.isOwned(*cert.CertificateArn)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+		if !owned {
+			continue
+		}
+
+		logger := s.scope.Logger().WithValues("arn", *cert.CertificateArn)
+		if aws.BoolValue(cert.InUse) {
+			logger.Info("Replaced ACM certificate is still in use, skipping deletion")
+			continue
+		}
+
+		logger.Info("Deleting replaced ACM certificate")
+		_, err = s.Client.DeleteCertificate(&acm.DeleteCertificateInput{CertificateArn: cert.CertificateArn})
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == acm.ErrCodeResourceInUseException {
+			logger.Info("Replaced ACM certificate is still in use, skipping deletion")
+			continue
+		} else if err != nil {
+			return microerror.Mask(err)
+		}
+		logger.Info("Deleted replaced ACM certificate")
+	}
 
 	return nil
 }
 
-func (s *Service) findCertificateForDomain(domain string) (*string, error) {
-	certs, err := getACMCertificates(s.Client)
+// findCertificateForDomains returns the ARN of the certificate covering exactly the given domains, or nil if
+// there is none.
+func (s *Service) findCertificateForDomains(domains []string) (*string, error) {
+	certs, err := s.findCertificatesForDomain(domains[0])
 	if err != nil {
 		return nil, microerror.Mask(err)
 	}
+
 	for _, certificate := range certs {
-		if *certificate.DomainName == domain {
+		// Imported certificates are not renewed by ACM, they are only used when configured explicitly.
+		if aws.StringValue(certificate.Type) == acm.CertificateTypeImported {
+			continue
+		}
+
+		if !isUsable(certificate) {
+			s.scope.Logger().Info("Ignoring ACM certificate in unusable state", "arn", *certificate.CertificateArn, "status", aws.StringValue(certificate.Status))
+			continue
+		}
+
+		owned, err := s.isOwned(*certificate.CertificateArn)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		if !owned {
+			s.scope.Logger().Info("Ignoring ACM certificate not owned by this cluster", "arn", *certificate.CertificateArn)
+			continue
+		}
+
+		sans := certificate.SubjectAlternativeNameSummaries
+		// The summary only contains the first 100 names, fall back to the full certificate details otherwise.
+		if aws.BoolValue(certificate.HasAdditionalSubjectAlternativeNames) {
+			detail, err := s.getACMCertificate(*certificate.CertificateArn)
+			if err != nil {
+				return nil, microerror.Mask(err)
+			}
+			sans = detail.SubjectAlternativeNames
+		}
+
+		// A different key algorithm is handled like a changed set of domains, a new certificate is requested.
+		if normalizeKeyAlgorithm(aws.StringValue(certificate.KeyAlgorithm)) != s.scope.CertificateKeyAlgorithm() {
+			s.scope.Logger().Info("Ignoring ACM certificate with different key algorithm", "arn", *certificate.CertificateArn, "keyAlgorithm", aws.StringValue(certificate.KeyAlgorithm))
+			continue
+		}
+
+		// Subject alternative names always include the certificate's domain name.
+		diff := slicediff.DiffIgnoreCase(sans, aws.StringSlice(domains))
+		if !diff.Changed() {
 			return certificate.CertificateArn, nil
 		}
 	}
@@ -183,9 +338,90 @@ func (s *Service) findCertificateForDomain(domain string) (*string, error) {
 	return nil, nil
 }
 
+// findCertificatesForDomain returns all certificates with the given domain name, regardless of their subject
+// alternative names. Imported certificates are listed under the name of their leaf certificate, e.g. a wildcard,
+// so they are returned as well when one of their subject alternative names covers the domain.
+func (s *Service) findCertificatesForDomain(domain string) ([]*acm.CertificateSummary, error) {
+	certs, err := getACMCertificates(s.Client)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	found := make([]*acm.CertificateSummary, 0)
+	for _, certificate := range certs {
+		if *certificate.DomainName == domain {
+			found = append(found, certificate)
+		} else if aws.StringValue(certificate.Type) == acm.CertificateTypeImported && coversDomain(aws.StringValueSlice(certificate.SubjectAlternativeNameSummaries), domain) {
+			found = append(found, certificate)
+		}
+	}
+
+	return found, nil
+}
+
+// isOwned checks whether the certificate carries the ownership tags of this cluster and installation.
+func (s *Service) isOwned(arn string) (bool, error) {
+	tags, err := s.getACMCertificateTags(arn)
+	if err != nil {
+		return false, microerror.Mask(err)
+	}
+
+	if tags[key.S3TagInstallation] != s.scope.Installation() {
+		return false, nil
+	}
+
+	// Vintage certificates carry the `giantswarm.io/cluster` tag, the cloud provider tag is set on all certificates.
+	return tags[key.S3TagCluster] == s.scope.ClusterName() ||
+		tags[fmt.Sprintf(key.S3TagCloudProvider, s.scope.ClusterName())] == "owned", nil
+}
+
+// isUsable returns false for certificates that can never be issued or served again.
+func isUsable(certificate *acm.CertificateSummary) bool {
+	switch aws.StringValue(certificate.Status) {
+	case acm.CertificateStatusFailed, acm.CertificateStatusExpired, acm.CertificateStatusRevoked, acm.CertificateStatusValidationTimedOut:
+		return false
+	}
+
+	return true
+}
+
+func (s *Service) getACMCertificateTags(arn string) (map[string]string, error) {
+	cacheKey := tagsCacheKey(arn)
+
+	if cachedValue, ok := s.scope.Cache().Get(cacheKey); ok {
+		return cachedValue.(map[string]string), nil
+	}
+
+	output, err := s.Client.ListTagsForCertificate(&acm.ListTagsForCertificateInput{
+		CertificateArn: aws.String(arn),
+	})
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	tags := make(map[string]string, len(output.Tags))
+	for _, tag := range output.Tags {
+		tags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+	}
+
+	// Other clusters' certificates are checked on every reconciliation, so cache for a while to avoid ACM
+	// throttling. The entry is invalidated when we change the tags ourselves.
+	s.scope.Cache().Set(cacheKey, tags, 10*time.Minute)
+
+	return tags, nil
+}
+
+func describeCacheKey(arn string) string {
+	return fmt.Sprintf("acm/arn=%q/describe-certificate", arn)
+}
+
+func tagsCacheKey(arn string) string {
+	return fmt.Sprintf("acm/arn=%q/tags", arn)
+}
+
 func (s *Service) getACMCertificate(arn string) (*acm.CertificateDetail, error) {
 
-	cacheKey := fmt.Sprintf("acm/arn=%q/describe-certificate", arn)
+	cacheKey := describeCacheKey(arn)
 
 	if cachedValue, ok := s.scope.Cache().Get(cacheKey); ok {
 		s.scope.Logger().WithValues("arn", arn).Info("Found acm certificate in the cache")
@@ -208,9 +444,24 @@ func (s *Service) getACMCertificate(arn string) (*acm.CertificateDetail, error)
 	return output.Certificate, nil
 }
 
+// normalizeKeyAlgorithm maps the key algorithm as returned by the API (e.g. `RSA-2048`) to the values used when
+// requesting a certificate (e.g. `RSA_2048`). Summaries without a key algorithm are treated as having the default
+// one.
+func normalizeKeyAlgorithm(keyAlgorithm string) string {
+	if keyAlgorithm == "" {
+		return key.DefaultCertificateKeyAlgorithm
+	}
+
+	return strings.ReplaceAll(keyAlgorithm, "-", "_")
+}
+
 func getACMCertificates(acmClient acmiface.ACMAPI) ([]*acm.CertificateSummary, error) {
+	// Without filter, only RSA_2048 certificates are returned.
+	includes := &acm.Filters{KeyTypes: aws.StringSlice(acm.KeyAlgorithm_Values())}
+
 	certs := []*acm.CertificateSummary{}
 	listCertificatesOutput, err := acmClient.ListCertificates(&acm.ListCertificatesInput{
+		Includes: includes,
 		MaxItems: aws.Int64(100),
 	})
 	if err != nil {
@@ -222,6 +473,7 @@ func getACMCertificates(acmClient acmiface.ACMAPI) ([]*acm.CertificateSummary, e
 	// If the response contains `NexToken` we need to keep sending requests including the token to get all results.
 	for listCertificatesOutput.NextToken != nil && *listCertificatesOutput.NextToken != "" {
 		listCertificatesOutput, err = acmClient.ListCertificates(&acm.ListCertificatesInput{
+			Includes:  includes,
 			MaxItems:  aws.Int64(100),
 			NextToken: listCertificatesOutput.NextToken,
 		})
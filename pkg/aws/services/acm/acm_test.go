@@ -0,0 +1,337 @@
+package acm
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/acm"
+	"github.com/aws/aws-sdk-go/service/acm/acmiface"
+	"github.com/go-logr/logr"
+	gocache "github.com/patrickmn/go-cache"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/giantswarm/irsa-operator/pkg/aws/scope"
+	"github.com/giantswarm/irsa-operator/pkg/key"
+)
+
+type fakeScope struct {
+	scope.ACMScope
+	cache           *gocache.Cache
+	customerTagKeys []string
+}
+
+func newFakeScope() *fakeScope {
+	return &fakeScope{cache: gocache.New(gocache.NoExpiration, 0)}
+}
+
+func (s *fakeScope) AccountID() string                        { return "123456789012" }
+func (s *fakeScope) Cache() *gocache.Cache                    { return s.cache }
+func (s *fakeScope) CertificateCustomerTagKeys() []string     { return s.customerTagKeys }
+func (s *fakeScope) CertificateKeyAlgorithm() string          { return key.DefaultCertificateKeyAlgorithm }
+func (s *fakeScope) Cluster() runtime.Object                  { return nil }
+func (s *fakeScope) ClusterName() string                      { return "lbj23" }
+func (s *fakeScope) ClusterNamespace() string                 { return "org-wonderland" }
+func (s *fakeScope) Installation() string                     { return "wonderland" }
+func (s *fakeScope) Logger() logr.Logger                      { return logr.Discard() }
+func (s *fakeScope) SetCertificateCustomerTagKeys(k []string) { s.customerTagKeys = k }
+
+type fakeACMClient struct {
+	acmiface.ACMAPI
+	certificates []*acm.CertificateSummary
+	detail       *acm.CertificateDetail
+	tags         map[string]map[string]string
+	deleted      []string
+}
+
+func (c *fakeACMClient) DescribeCertificate(*acm.DescribeCertificateInput) (*acm.DescribeCertificateOutput, error) {
+	return &acm.DescribeCertificateOutput{Certificate: c.detail}, nil
+}
+
+func (c *fakeACMClient) ListCertificates(*acm.ListCertificatesInput) (*acm.ListCertificatesOutput, error) {
+	return &acm.ListCertificatesOutput{CertificateSummaryList: c.certificates}, nil
+}
+
+func (c *fakeACMClient) ListTagsForCertificate(input *acm.ListTagsForCertificateInput) (*acm.ListTagsForCertificateOutput, error) {
+	output := &acm.ListTagsForCertificateOutput{}
+	for k, v := range c.tags[aws.StringValue(input.CertificateArn)] {
+		output.Tags = append(output.Tags, &acm.Tag{Key: aws.String(k), Value: aws.String(v)})
+	}
+	return output, nil
+}
+
+func (c *fakeACMClient) AddTagsToCertificate(input *acm.AddTagsToCertificateInput) (*acm.AddTagsToCertificateOutput, error) {
+	for _, tag := range input.Tags {
+		c.tags[aws.StringValue(input.CertificateArn)][aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+	}
+	return &acm.AddTagsToCertificateOutput{}, nil
+}
+
+func (c *fakeACMClient) RemoveTagsFromCertificate(input *acm.RemoveTagsFromCertificateInput) (*acm.RemoveTagsFromCertificateOutput, error) {
+	for _, tag := range input.Tags {
+		delete(c.tags[aws.StringValue(input.CertificateArn)], aws.StringValue(tag.Key))
+	}
+	return &acm.RemoveTagsFromCertificateOutput{}, nil
+}
+
+func (c *fakeACMClient) DeleteCertificate(input *acm.DeleteCertificateInput) (*acm.DeleteCertificateOutput, error) {
+	c.deleted = append(c.deleted, aws.StringValue(input.CertificateArn))
+	return &acm.DeleteCertificateOutput{}, nil
+}
+
+var (
+	ownedTags = map[string]string{
+		"giantswarm.io/installation":  "wonderland",
+		"kubernetes.io/cluster/lbj23": "owned",
+	}
+	vintageTags = map[string]string{
+		"giantswarm.io/installation": "wonderland",
+		"giantswarm.io/cluster":      "lbj23",
+	}
+	foreignClusterTags = map[string]string{
+		"giantswarm.io/installation":  "wonderland",
+		"kubernetes.io/cluster/other": "owned",
+	}
+	foreignInstallationTags = map[string]string{
+		"giantswarm.io/installation":  "otherland",
+		"kubernetes.io/cluster/lbj23": "owned",
+	}
+)
+
+func Test_isOwned(t *testing.T) {
+	tests := []struct {
+		name string
+		tags map[string]string
+		want bool
+	}{
+		{
+			name: "cloud provider tag",
+			tags: ownedTags,
+			want: true,
+		},
+		{
+			name: "vintage cluster tag",
+			tags: vintageTags,
+			want: true,
+		},
+		{
+			name: "other cluster",
+			tags: foreignClusterTags,
+			want: false,
+		},
+		{
+			name: "other installation",
+			tags: foreignInstallationTags,
+			want: false,
+		},
+		{
+			name: "cluster tag without installation tag",
+			tags: map[string]string{
+				"kubernetes.io/cluster/lbj23": "owned",
+			},
+			want: false,
+		},
+		{
+			name: "cloud provider tag not owned",
+			tags: map[string]string{
+				"giantswarm.io/installation":  "wonderland",
+				"kubernetes.io/cluster/lbj23": "shared",
+			},
+			want: false,
+		},
+		{
+			name: "no tags",
+			tags: nil,
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Service{
+				scope:  newFakeScope(),
+				Client: &fakeACMClient{tags: map[string]map[string]string{"arn": tt.tags}},
+			}
+
+			got, err := s.isOwned("arn")
+			if err != nil {
+				t.Fatalf("isOwned() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("isOwned() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_isUsable(t *testing.T) {
+	tests := []struct {
+		status string
+		want   bool
+	}{
+		{status: acm.CertificateStatusIssued, want: true},
+		{status: acm.CertificateStatusPendingValidation, want: true},
+		{status: acm.CertificateStatusInactive, want: true},
+		{status: acm.CertificateStatusExpired, want: false},
+		{status: acm.CertificateStatusFailed, want: false},
+		{status: acm.CertificateStatusRevoked, want: false},
+		{status: acm.CertificateStatusValidationTimedOut, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.status, func(t *testing.T) {
+			if got := isUsable(&acm.CertificateSummary{Status: aws.String(tt.status)}); got != tt.want {
+				t.Errorf("isUsable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func certificateSummary(arn, status string, inUse bool, domains ...string) *acm.CertificateSummary {
+	return &acm.CertificateSummary{
+		CertificateArn:                       aws.String(arn),
+		DomainName:                           aws.String(domains[0]),
+		InUse:                                aws.Bool(inUse),
+		KeyAlgorithm:                         aws.String("RSA-2048"),
+		Status:                               aws.String(status),
+		SubjectAlternativeNameSummaries:      aws.StringSlice(domains),
+		Type:                                 aws.String(acm.CertificateTypeAmazonIssued),
+		HasAdditionalSubjectAlternativeNames: aws.Bool(false),
+	}
+}
+
+func Test_findCertificateForDomains(t *testing.T) {
+	domains := []string{"irsa.lbj23.example.com", "oidc.example.com"}
+
+	tests := []struct {
+		name         string
+		certificates []*acm.CertificateSummary
+		tags         map[string]map[string]string
+		want         *string
+	}{
+		{
+			name: "owned certificate",
+			certificates: []*acm.CertificateSummary{
+				certificateSummary("owned", acm.CertificateStatusIssued, true, domains...),
+			},
+			tags: map[string]map[string]string{"owned": ownedTags},
+			want: aws.String("owned"),
+		},
+		{
+			name: "foreign certificate is not reused",
+			certificates: []*acm.CertificateSummary{
+				certificateSummary("foreign", acm.CertificateStatusIssued, true, domains...),
+			},
+			tags: map[string]map[string]string{"foreign": foreignClusterTags},
+			want: nil,
+		},
+		{
+			name: "certificate without tags is not reused",
+			certificates: []*acm.CertificateSummary{
+				certificateSummary("untagged", acm.CertificateStatusIssued, false, domains...),
+			},
+			want: nil,
+		},
+		{
+			name: "expired certificate is not reused",
+			certificates: []*acm.CertificateSummary{
+				certificateSummary("expired", acm.CertificateStatusExpired, false, domains...),
+				certificateSummary("issued", acm.CertificateStatusIssued, false, domains...),
+			},
+			tags: map[string]map[string]string{"expired": ownedTags, "issued": ownedTags},
+			want: aws.String("issued"),
+		},
+		{
+			name: "certificate without key algorithm has the default one",
+			certificates: []*acm.CertificateSummary{
+				func() *acm.CertificateSummary {
+					summary := certificateSummary("owned", acm.CertificateStatusIssued, true, domains...)
+					summary.KeyAlgorithm = nil
+					return summary
+				}(),
+			},
+			tags: map[string]map[string]string{"owned": ownedTags},
+			want: aws.String("owned"),
+		},
+		{
+			name: "certificate with other key algorithm is not reused",
+			certificates: []*acm.CertificateSummary{
+				func() *acm.CertificateSummary {
+					summary := certificateSummary("ec", acm.CertificateStatusIssued, true, domains...)
+					summary.KeyAlgorithm = aws.String("EC-prime256v1")
+					return summary
+				}(),
+			},
+			tags: map[string]map[string]string{"ec": ownedTags},
+			want: nil,
+		},
+		{
+			name: "certificate with other domains is not reused",
+			certificates: []*acm.CertificateSummary{
+				certificateSummary("other", acm.CertificateStatusIssued, true, domains[0]),
+			},
+			tags: map[string]map[string]string{"other": ownedTags},
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Service{
+				scope:  newFakeScope(),
+				Client: &fakeACMClient{certificates: tt.certificates, tags: tt.tags},
+			}
+
+			got, err := s.findCertificateForDomains(domains)
+			if err != nil {
+				t.Fatalf("findCertificateForDomains() error = %v", err)
+			}
+			if aws.StringValue(got) != aws.StringValue(tt.want) {
+				t.Errorf("findCertificateForDomains() = %v, want %v", aws.StringValue(got), aws.StringValue(tt.want))
+			}
+		})
+	}
+}
+
+func Test_DeleteUnusedCertificates(t *testing.T) {
+	domain := "irsa.lbj23.example.com"
+	client := &fakeACMClient{
+		certificates: []*acm.CertificateSummary{
+			certificateSummary("current", acm.CertificateStatusIssued, true, domain),
+			certificateSummary("replaced", acm.CertificateStatusIssued, false, domain),
+			certificateSummary("replaced-in-use", acm.CertificateStatusIssued, true, domain),
+			certificateSummary("expired", acm.CertificateStatusExpired, false, domain),
+			certificateSummary("vintage", acm.CertificateStatusIssued, false, domain),
+			certificateSummary("foreign-cluster", acm.CertificateStatusIssued, false, domain),
+			certificateSummary("foreign-installation", acm.CertificateStatusIssued, false, domain),
+			certificateSummary("untagged", acm.CertificateStatusIssued, false, domain),
+			certificateSummary("other-domain", acm.CertificateStatusIssued, false, "oidc.example.com"),
+			func() *acm.CertificateSummary {
+				summary := certificateSummary("imported-wildcard", acm.CertificateStatusIssued, false, "*.example.com", "lbj23.example.com", "*.lbj23.example.com")
+				summary.Type = aws.String(acm.CertificateTypeImported)
+				return summary
+			}(),
+		},
+		tags: map[string]map[string]string{
+			"current":              ownedTags,
+			"replaced":             ownedTags,
+			"replaced-in-use":      ownedTags,
+			"expired":              ownedTags,
+			"vintage":              vintageTags,
+			"foreign-cluster":      foreignClusterTags,
+			"foreign-installation": foreignInstallationTags,
+			"other-domain":         ownedTags,
+			"imported-wildcard":    ownedTags,
+		},
+	}
+	s := &Service{scope: newFakeScope(), Client: client}
+
+	err := s.DeleteUnusedCertificates(domain, "current")
+	if err != nil {
+		t.Fatalf("DeleteUnusedCertificates() error = %v", err)
+	}
+
+	want := []string{"expired", "imported-wildcard", "replaced", "vintage"}
+	sort.Strings(client.deleted)
+	if !reflect.DeepEqual(client.deleted, want) {
+		t.Errorf("DeleteUnusedCertificates() deleted %v, want %v", client.deleted, want)
+	}
+}
@@ -5,3 +5,7 @@ import "github.com/giantswarm/microerror"
 var domainValidationDnsRecordNotFound = &microerror.Error{
 	Kind: "domainValidationDnsRecordNotFound",
 }
+
+var invalidCertificateSecretError = &microerror.Error{
+	Kind: "invalidCertificateSecret",
+}
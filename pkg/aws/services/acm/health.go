@@ -0,0 +1,159 @@
+package acm
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/acm"
+	"github.com/giantswarm/microerror"
+	gocache "github.com/patrickmn/go-cache"
+
+	"github.com/giantswarm/irsa-operator/pkg/key"
+	ctrlmetrics "github.com/giantswarm/irsa-operator/pkg/metrics"
+	"github.com/giantswarm/irsa-operator/pkg/util/record"
+)
+
+// CertificateHealth summarizes the state of a certificate relevant for managed renewal.
+type CertificateHealth struct {
+	// DaysUntilExpiry is negative for expired certificates.
+	DaysUntilExpiry float64
+	InUseBy         int
+	// RenewalStatus is empty as long as ACM did not start renewing the certificate.
+	RenewalStatus       string
+	RenewalStatusReason string
+	ValidationRecords   []DomainValidationRecord
+}
+
+// GetCertificateHealth returns the renewal relevant state of an issued certificate.
+func (s *Service) GetCertificateHealth(arn string) (*CertificateHealth, error) {
+	cert, err := s.getACMCertificate(arn)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	health := &CertificateHealth{
+		InUseBy: len(cert.InUseBy),
+	}
+
+	if cert.NotAfter != nil {
+		health.DaysUntilExpiry = time.Until(*cert.NotAfter).Hours() / 24
+	}
+
+	if cert.RenewalSummary != nil {
+		health.RenewalStatus = aws.StringValue(cert.RenewalSummary.RenewalStatus)
+		health.RenewalStatusReason = aws.StringValue(cert.RenewalSummary.RenewalStatusReason)
+	}
+
+	// ACM keeps using the records of the initial validation for renewals.
+	records, err := s.GetValidationCNAMEs(arn)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	health.ValidationRecords = records
+
+	return health, nil
+}
+
+// certificateProblem is a reason for the managed renewal of a certificate to fail. The key identifies the problem
+// across reconciliations, the message is sent as warning event.
+type certificateProblem struct {
+	key     string
+	reason  string
+	message string
+}
+
+// ReportCertificateHealth exposes the renewal relevant state of the certificate as metrics and emits events for
+// problems that make the managed renewal fail, so that they are noticed long before the certificate expires. The
+// events are only emitted when a problem shows up, not on every reconciliation. recordExists checks whether a DNS
+// validation record is still in place.
+func (s *Service) ReportCertificateHealth(arn, domain string, recordExists func(DomainValidationRecord) (bool, error)) error {
+	labels := []string{s.scope.Installation(), s.scope.AccountID(), s.scope.ClusterName(), s.scope.ClusterNamespace(), domain}
+
+	health, err := s.GetCertificateHealth(arn)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	ctrlmetrics.CertDaysUntilExpiry.WithLabelValues(labels...).Set(health.DaysUntilExpiry)
+	ctrlmetrics.CertInUseBy.WithLabelValues(labels...).Set(float64(health.InUseBy))
+
+	renewalStatus := health.RenewalStatus
+	if renewalStatus == "" {
+		renewalStatus = "NOT_STARTED"
+	}
+	ctrlmetrics.SetCertificateRenewalStatus(s.scope.Installation(), s.scope.AccountID(), s.scope.ClusterName(), s.scope.ClusterNamespace(), domain, renewalStatus, health.RenewalStatusReason)
+
+	problems := make([]certificateProblem, 0)
+
+	switch health.RenewalStatus {
+	case acm.RenewalStatusFailed:
+		problems = append(problems, certificateProblem{
+			key:     fmt.Sprintf("renewal-failed/%s", health.RenewalStatusReason),
+			reason:  "CertificateRenewalFailed",
+			message: fmt.Sprintf("Managed renewal of ACM certificate %s failed: %s", arn, health.RenewalStatusReason),
+		})
+	case acm.RenewalStatusPendingValidation:
+		problems = append(problems, certificateProblem{
+			key:     "renewal-pending-validation",
+			reason:  "CertificateRenewalPendingValidation",
+			message: fmt.Sprintf("Managed renewal of ACM certificate %s is waiting for domain validation", arn),
+		})
+	}
+
+	if health.DaysUntilExpiry < key.CertificateExpiryWarningDays {
+		problems = append(problems, certificateProblem{
+			key:     "expiring-soon",
+			reason:  "CertificateExpiringSoon",
+			message: fmt.Sprintf("ACM certificate %s expires in %d days", arn, int(health.DaysUntilExpiry)),
+		})
+	}
+
+	present := true
+	for _, r := range health.ValidationRecords {
+		exists, err := recordExists(r)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+		if !exists {
+			present = false
+			problems = append(problems, certificateProblem{
+				key:     fmt.Sprintf("validation-record-missing/%s", r.CNAME.Name),
+				reason:  "CertificateValidationRecordMissing",
+				message: fmt.Sprintf("DNS validation record %s for domain %s is missing, managed renewal of ACM certificate %s will fail", r.CNAME.Name, r.Domain, arn),
+			})
+		}
+	}
+
+	if present {
+		ctrlmetrics.CertValidationRecordPresent.WithLabelValues(labels...).Set(1)
+	} else {
+		ctrlmetrics.CertValidationRecordPresent.WithLabelValues(labels...).Set(0)
+	}
+
+	s.reportProblems(arn, problems)
+
+	return nil
+}
+
+// reportProblems emits a warning event for each problem that was not present in the previous report of the
+// certificate. The reported problems are kept in the cache, so they are announced again after a restart.
+func (s *Service) reportProblems(arn string, problems []certificateProblem) {
+	cacheKey := fmt.Sprintf("acm/arn=%q/reported-problems", arn)
+
+	previous := map[string]bool{}
+	if cachedValue, ok := s.scope.Cache().Get(cacheKey); ok {
+		previous = cachedValue.(map[string]bool)
+	}
+
+	current := make(map[string]bool, len(problems))
+	for _, problem := range problems {
+		current[problem.key] = true
+		if previous[problem.key] {
+			continue
+		}
+		record.Warn(s.scope.Cluster(), problem.reason, problem.message)
+	}
+
+	s.scope.Cache().Set(cacheKey, current, gocache.NoExpiration)
+}
@@ -0,0 +1,116 @@
+package acm
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/acm"
+	k8srecord "k8s.io/client-go/tools/record"
+
+	"github.com/giantswarm/irsa-operator/pkg/util/record"
+)
+
+func Test_ReportCertificateHealth(t *testing.T) {
+	recorder := k8srecord.NewFakeRecorder(100)
+	record.InitFromRecorder(recorder)
+
+	detail := func(renewalStatus string, daysUntilExpiry int) *acm.CertificateDetail {
+		d := &acm.CertificateDetail{
+			CertificateArn: aws.String("arn"),
+			NotAfter:       aws.Time(time.Now().Add(time.Duration(daysUntilExpiry) * 24 * time.Hour)),
+			DomainValidationOptions: []*acm.DomainValidation{
+				{
+					DomainName:       aws.String("irsa.lbj23.example.com"),
+					ValidationStatus: aws.String(acm.DomainStatusSuccess),
+					ResourceRecord: &acm.ResourceRecord{
+						Name:  aws.String("_x1.irsa.lbj23.example.com."),
+						Value: aws.String("_x2.acm-validations.aws."),
+					},
+				},
+			},
+		}
+		if renewalStatus != "" {
+			d.RenewalSummary = &acm.RenewalSummary{RenewalStatus: aws.String(renewalStatus), RenewalStatusReason: aws.String("CAA_ERROR")}
+		}
+		return d
+	}
+
+	// The steps run one after the other against the same cache, like consecutive reconciliations.
+	steps := []struct {
+		name          string
+		detail        *acm.CertificateDetail
+		recordExists  bool
+		wantEventsFor []string
+	}{
+		{
+			name:         "healthy",
+			detail:       detail("", 200),
+			recordExists: true,
+		},
+		{
+			name:          "renewal pending validation",
+			detail:        detail(acm.RenewalStatusPendingValidation, 50),
+			recordExists:  true,
+			wantEventsFor: []string{"CertificateRenewalPendingValidation"},
+		},
+		{
+			name:         "renewal still pending validation",
+			detail:       detail(acm.RenewalStatusPendingValidation, 49),
+			recordExists: true,
+		},
+		{
+			name:          "validation record missing",
+			detail:        detail(acm.RenewalStatusPendingValidation, 48),
+			recordExists:  false,
+			wantEventsFor: []string{"CertificateValidationRecordMissing"},
+		},
+		{
+			name:          "renewal failed and expiring",
+			detail:        detail(acm.RenewalStatusFailed, 20),
+			recordExists:  false,
+			wantEventsFor: []string{"CertificateRenewalFailed", "CertificateExpiringSoon"},
+		},
+		{
+			name:         "renewal still failed",
+			detail:       detail(acm.RenewalStatusFailed, 19),
+			recordExists: false,
+		},
+		{
+			name:         "renewed",
+			detail:       detail(acm.RenewalStatusSuccess, 395),
+			recordExists: true,
+		},
+		{
+			name:          "renewal pending validation again",
+			detail:        detail(acm.RenewalStatusPendingValidation, 50),
+			recordExists:  true,
+			wantEventsFor: []string{"CertificateRenewalPendingValidation"},
+		},
+	}
+
+	client := &fakeACMClient{}
+	s := &Service{scope: newFakeScope(), Client: client}
+
+	for _, step := range steps {
+		client.detail = step.detail
+		s.scope.Cache().Delete(describeCacheKey("arn"))
+
+		err := s.ReportCertificateHealth("arn", "irsa.lbj23.example.com", func(DomainValidationRecord) (bool, error) {
+			return step.recordExists, nil
+		})
+		if err != nil {
+			t.Fatalf("%s: ReportCertificateHealth() error = %v", step.name, err)
+		}
+
+		var gotEventsFor []string
+		for len(recorder.Events) > 0 {
+			gotEventsFor = append(gotEventsFor, strings.Fields(<-recorder.Events)[1])
+		}
+		if !reflect.DeepEqual(gotEventsFor, step.wantEventsFor) {
+			t.Errorf("%s: ReportCertificateHealth() emitted events %v, want %v", step.name, gotEventsFor, step.wantEventsFor)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package acm
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/acm"
+	"github.com/giantswarm/microerror"
+)
+
+// ImportCertificate imports a certificate issued outside of ACM (e.g. by cert-manager) for the given domains. An
+// already imported certificate is re-imported in place when the serial number changed, so the distribution keeps
+// using the same ARN across renewals. certPEM holds the leaf certificate followed by its chain.
+func (s *Service) ImportCertificate(domains []string, certPEM, keyPEM []byte, customerTags map[string]string) (*string, error) {
+	s.scope.Logger().Info("Ensuring imported ACM certificate", "domains", domains)
+
+	leaf, chain, err := splitCertificateChain(certPEM)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	// CloudFront rejects aliases not covered by the certificate, fail early with a clear message instead.
+	for _, domain := range domains {
+		if err := leaf.VerifyHostname(domain); err != nil {
+			return nil, microerror.Maskf(invalidCertificateSecretError, "certificate does not cover domain %q", domain)
+		}
+	}
+
+	certificateArn, err := s.findImportedCertificate(leaf)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	desiredTags := s.desiredTags(customerTags)
+
+	input := &acm.ImportCertificateInput{
+		Certificate: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: leaf.Raw}),
+		PrivateKey:  keyPEM,
+	}
+	if len(chain) > 0 {
+		input.CertificateChain = chain
+	}
+
+	if certificateArn != nil {
+		cert, err := s.getACMCertificate(*certificateArn)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		if normalizeSerial(aws.StringValue(cert.Serial)) == normalizeSerial(leaf.SerialNumber.Text(16)) {
+			s.scope.Logger().Info("Imported ACM certificate is up to date")
+
+			err = s.ensureTags(*certificateArn, customerTags)
+			if err != nil {
+				return nil, microerror.Mask(err)
+			}
+
+			return certificateArn, nil
+		}
+
+		// Tags can't be passed when re-importing, they are kept from the initial import.
+		s.scope.Logger().Info("Re-importing renewed ACM certificate", "arn", *certificateArn)
+		input.CertificateArn = certificateArn
+		_, err = s.Client.ImportCertificate(input)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		s.scope.Cache().Delete(describeCacheKey(*certificateArn))
+
+		s.scope.Logger().Info("Re-imported ACM certificate")
+		return certificateArn, nil
+	}
+
+	for k, v := range desiredTags {
+		input.Tags = append(input.Tags, &acm.Tag{Key: aws.String(k), Value: aws.String(v)})
+	}
+
+	s.scope.Logger().Info("Importing ACM certificate")
+	output, err := s.Client.ImportCertificate(input)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	s.recordCustomerTagKeys(customerTags)
+
+	s.scope.Logger().Info("Imported ACM certificate")
+	return output.CertificateArn, nil
+}
+
+// findImportedCertificate returns the ARN of the imported certificate owned by the cluster that was imported for
+// the given leaf certificate, or nil if there is none. ACM lists imported certificates under the common name of the
+// leaf or, without one, its first subject alternative name, which can differ from the cluster's domains.
+func (s *Service) findImportedCertificate(leaf *x509.Certificate) (*string, error) {
+	certs, err := getACMCertificates(s.Client)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	names := leaf.DNSNames
+	if leaf.Subject.CommonName != "" {
+		names = append([]string{leaf.Subject.CommonName}, names...)
+	}
+	for _, certificate := range certs {
+		if aws.StringValue(certificate.Type) != acm.CertificateTypeImported {
+			continue
+		}
+		if !containsName(names, aws.StringValue(certificate.DomainName)) {
+			continue
+		}
+
+		owned, err := s.isOwned(*certificate.CertificateArn)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		if owned {
+			return certificate.CertificateArn, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// coversDomain checks whether one of the names of a certificate, which may be wildcards, covers the domain.
+func coversDomain(names []string, domain string) bool {
+	for _, name := range names {
+		if strings.EqualFold(name, domain) {
+			return true
+		}
+
+		i := strings.Index(domain, ".")
+		if strings.HasPrefix(name, "*.") && i > 0 && strings.EqualFold(name[1:], domain[i:]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsName(names []string, name string) bool {
+	for _, n := range names {
+		if strings.EqualFold(n, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// splitCertificateChain parses the leaf certificate and returns it along with the PEM encoded rest of the chain.
+func splitCertificateChain(certPEM []byte) (*x509.Certificate, []byte, error) {
+	block, rest := pem.Decode(certPEM)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, nil, microerror.Maskf(invalidCertificateSecretError, "no PEM encoded certificate found")
+	}
+
+	leaf, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, nil, microerror.Maskf(invalidCertificateSecretError, "failed to parse certificate: %s", err)
+	}
+
+	return leaf, bytes.TrimSpace(rest), nil
+}
+
+// normalizeSerial makes the colon separated hex serial number returned by ACM comparable to the one parsed from
+// the certificate.
+func normalizeSerial(serial string) string {
+	return strings.TrimLeft(strings.ToLower(strings.ReplaceAll(serial, ":", "")), "0")
+}
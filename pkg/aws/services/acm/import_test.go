@@ -0,0 +1,186 @@
+package acm
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/acm"
+)
+
+func Test_normalizeSerial(t *testing.T) {
+	serial := big.NewInt(0x0a1bff)
+
+	tests := []struct {
+		name string
+		acm  string
+	}{
+		{name: "Colon separated with leading zero", acm: "0a:1b:ff"},
+		{name: "Upper case", acm: "0A:1B:FF"},
+		{name: "Without leading zero", acm: "a:1b:ff"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if normalizeSerial(tt.acm) != normalizeSerial(serial.Text(16)) {
+				t.Errorf("normalizeSerial() %q != %q", normalizeSerial(tt.acm), normalizeSerial(serial.Text(16)))
+			}
+		})
+	}
+}
+
+func Test_splitCertificateChain(t *testing.T) {
+	leafPEM := selfSignedPEM(t, "irsa.example.com")
+	intermediatePEM := selfSignedPEM(t, "intermediate")
+
+	leaf, chain, err := splitCertificateChain(append(leafPEM, intermediatePEM...))
+	if err != nil {
+		t.Fatalf("splitCertificateChain() error = %v", err)
+	}
+	if leaf.Subject.CommonName != "irsa.example.com" {
+		t.Errorf("splitCertificateChain() leaf = %q, want %q", leaf.Subject.CommonName, "irsa.example.com")
+	}
+	if string(chain) != string(intermediatePEM[:len(intermediatePEM)-1]) {
+		t.Errorf("splitCertificateChain() chain = %q, want intermediate", chain)
+	}
+
+	_, chain, err = splitCertificateChain(leafPEM)
+	if err != nil {
+		t.Fatalf("splitCertificateChain() error = %v", err)
+	}
+	if len(chain) != 0 {
+		t.Errorf("splitCertificateChain() chain = %q, want empty", chain)
+	}
+
+	_, _, err = splitCertificateChain([]byte("garbage"))
+	if err == nil {
+		t.Errorf("splitCertificateChain() expected error for invalid input")
+	}
+}
+
+func selfSignedPEM(t *testing.T, commonName string) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		DNSNames:     []string{commonName},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func Test_findImportedCertificate(t *testing.T) {
+	leaf := &x509.Certificate{
+		Subject:  pkix.Name{CommonName: "*.example.com"},
+		DNSNames: []string{"*.example.com", "irsa.lbj23.example.com"},
+	}
+
+	imported := func(arn, domainName string) *acm.CertificateSummary {
+		summary := certificateSummary(arn, acm.CertificateStatusIssued, true, domainName)
+		summary.Type = aws.String(acm.CertificateTypeImported)
+		return summary
+	}
+
+	tests := []struct {
+		name         string
+		certificates []*acm.CertificateSummary
+		tags         map[string]map[string]string
+		want         *string
+	}{
+		{
+			name: "listed under the common name",
+			certificates: []*acm.CertificateSummary{
+				imported("imported", "*.example.com"),
+			},
+			tags: map[string]map[string]string{"imported": ownedTags},
+			want: aws.String("imported"),
+		},
+		{
+			name: "listed under a subject alternative name",
+			certificates: []*acm.CertificateSummary{
+				imported("imported", "irsa.lbj23.example.com"),
+			},
+			tags: map[string]map[string]string{"imported": ownedTags},
+			want: aws.String("imported"),
+		},
+		{
+			name: "foreign certificate",
+			certificates: []*acm.CertificateSummary{
+				imported("imported", "*.example.com"),
+			},
+			tags: map[string]map[string]string{"imported": foreignClusterTags},
+			want: nil,
+		},
+		{
+			name: "requested certificate",
+			certificates: []*acm.CertificateSummary{
+				certificateSummary("requested", acm.CertificateStatusIssued, true, "irsa.lbj23.example.com"),
+			},
+			tags: map[string]map[string]string{"requested": ownedTags},
+			want: nil,
+		},
+		{
+			name: "other name",
+			certificates: []*acm.CertificateSummary{
+				imported("imported", "oidc.example.com"),
+			},
+			tags: map[string]map[string]string{"imported": ownedTags},
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Service{
+				scope:  newFakeScope(),
+				Client: &fakeACMClient{certificates: tt.certificates, tags: tt.tags},
+			}
+
+			got, err := s.findImportedCertificate(leaf)
+			if err != nil {
+				t.Fatalf("findImportedCertificate() error = %v", err)
+			}
+			if aws.StringValue(got) != aws.StringValue(tt.want) {
+				t.Errorf("findImportedCertificate() = %v, want %v", aws.StringValue(got), aws.StringValue(tt.want))
+			}
+		})
+	}
+}
+
+func Test_coversDomain(t *testing.T) {
+	tests := []struct {
+		name   string
+		names  []string
+		domain string
+		want   bool
+	}{
+		{name: "exact", names: []string{"irsa.example.com"}, domain: "irsa.example.com", want: true},
+		{name: "case insensitive", names: []string{"IRSA.example.com"}, domain: "irsa.example.com", want: true},
+		{name: "wildcard", names: []string{"*.example.com"}, domain: "irsa.example.com", want: true},
+		{name: "wildcard only covers one label", names: []string{"*.example.com"}, domain: "irsa.lbj23.example.com", want: false},
+		{name: "wildcard doesn't cover the apex", names: []string{"*.example.com"}, domain: "example.com", want: false},
+		{name: "other domain", names: []string{"oidc.example.com"}, domain: "irsa.example.com", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := coversDomain(tt.names, tt.domain); got != tt.want {
+				t.Errorf("coversDomain() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
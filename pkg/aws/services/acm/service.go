@@ -13,7 +13,7 @@ type Service struct {
 }
 
 // NewService returns a new service given the Cloudfront api client.
-func NewService(clusterScope scope.IAMScope) *Service {
+func NewService(clusterScope scope.ACMScope) *Service {
 	return &Service{
 		scope:  clusterScope,
 		Client: scope.NewACMClient(clusterScope, clusterScope.ARN(), clusterScope.Cluster()),
@@ -0,0 +1,116 @@
+package acm
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/acm"
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/irsa-operator/pkg/key"
+	"github.com/giantswarm/irsa-operator/pkg/util"
+)
+
+// desiredTags returns the tags the certificates of the cluster should carry. Internal tags take precedence over
+// customer tags with the same key.
+func (s *Service) desiredTags(customerTags map[string]string) map[string]string {
+	tags := make(map[string]string, len(customerTags)+4)
+	for k, v := range customerTags {
+		tags[k] = v
+	}
+
+	// add cluster tag if missing (this is case for vintage clusters)
+	if _, ok := tags[key.S3TagCluster]; !ok {
+		tags[key.S3TagCluster] = s.scope.ClusterName()
+	}
+
+	tags[key.S3TagOrganization] = util.RemoveOrg(s.scope.ClusterNamespace())
+	tags[fmt.Sprintf(key.S3TagCloudProvider, s.scope.ClusterName())] = "owned"
+	tags[key.S3TagInstallation] = s.scope.Installation()
+
+	return tags
+}
+
+// ensureTags adds the desired tags missing on an existing certificate, updates the ones with a different value and
+// removes the customer tags the operator set before which are not desired anymore. Other tags are left alone, they
+// might have been set by someone else.
+func (s *Service) ensureTags(arn string, customerTags map[string]string) error {
+	logger := s.scope.Logger().WithValues("arn", arn)
+
+	currentTags, err := s.getACMCertificateTags(arn)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	tagsToBeAdded, tagsToBeRemoved := tagsNeedUpdating(currentTags, s.desiredTags(customerTags), s.scope.CertificateCustomerTagKeys())
+	if len(tagsToBeAdded) == 0 && len(tagsToBeRemoved) == 0 {
+		logger.Info("ACM certificate tags are up to date")
+		s.recordCustomerTagKeys(customerTags)
+		return nil
+	}
+
+	if len(tagsToBeAdded) > 0 {
+		i := &acm.AddTagsToCertificateInput{CertificateArn: aws.String(arn)}
+		for k, v := range tagsToBeAdded {
+			i.Tags = append(i.Tags, &acm.Tag{Key: aws.String(k), Value: aws.String(v)})
+		}
+		_, err = s.Client.AddTagsToCertificate(i)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	if len(tagsToBeRemoved) > 0 {
+		i := &acm.RemoveTagsFromCertificateInput{CertificateArn: aws.String(arn)}
+		for _, k := range tagsToBeRemoved {
+			i.Tags = append(i.Tags, &acm.Tag{Key: aws.String(k)})
+		}
+		_, err = s.Client.RemoveTagsFromCertificate(i)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	s.scope.Cache().Delete(tagsCacheKey(arn))
+	s.recordCustomerTagKeys(customerTags)
+
+	logger.Info("Updated ACM certificate tags", "updated", len(tagsToBeAdded), "removed", len(tagsToBeRemoved))
+	return nil
+}
+
+// recordCustomerTagKeys records the keys of the customer tags set on the certificate, so that they can be removed
+// once they are dropped from the additional tags.
+func (s *Service) recordCustomerTagKeys(customerTags map[string]string) {
+	keys := make([]string, 0, len(customerTags))
+	for k := range customerTags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	s.scope.SetCertificateCustomerTagKeys(keys)
+}
+
+// tagsNeedUpdating compares the current tags of a certificate with the desired tags and returns the tags to be
+// added or changed and the keys of the previously set customer tags to be removed.
+func tagsNeedUpdating(currentTags map[string]string, desiredTags map[string]string, previousCustomerKeys []string) (map[string]string, []string) {
+	tagsToBeAdded := make(map[string]string)
+	for k, v := range desiredTags {
+		if val, found := currentTags[k]; !found || val != v {
+			tagsToBeAdded[k] = v
+		}
+	}
+
+	tagsToBeRemoved := make([]string, 0)
+	for _, k := range previousCustomerKeys {
+		if _, desired := desiredTags[k]; desired {
+			continue
+		}
+		if _, found := currentTags[k]; found {
+			tagsToBeRemoved = append(tagsToBeRemoved, k)
+		}
+	}
+	sort.Strings(tagsToBeRemoved)
+
+	return tagsToBeAdded, tagsToBeRemoved
+}
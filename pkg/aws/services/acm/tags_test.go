@@ -0,0 +1,172 @@
+package acm
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_tagsNeedUpdating(t *testing.T) {
+	desiredTags := map[string]string{
+		"giantswarm.io/cluster":       "lbj23",
+		"giantswarm.io/installation":  "wonderland",
+		"kubernetes.io/cluster/lbj23": "owned",
+		"customertag1":                "customertagvalue1",
+	}
+
+	tests := []struct {
+		name     string
+		current  map[string]string
+		previous []string
+		add      map[string]string
+		remove   []string
+	}{
+		{
+			name: "Tags unchanged",
+			current: map[string]string{
+				"giantswarm.io/cluster":       "lbj23",
+				"giantswarm.io/installation":  "wonderland",
+				"kubernetes.io/cluster/lbj23": "owned",
+				"customertag1":                "customertagvalue1",
+			},
+			add: map[string]string{},
+		},
+		{
+			name: "Customer tag missing",
+			current: map[string]string{
+				"giantswarm.io/cluster":       "lbj23",
+				"giantswarm.io/installation":  "wonderland",
+				"kubernetes.io/cluster/lbj23": "owned",
+			},
+			add: map[string]string{
+				"customertag1": "customertagvalue1",
+			},
+		},
+		{
+			name: "Customer tag changed",
+			current: map[string]string{
+				"giantswarm.io/cluster":       "lbj23",
+				"giantswarm.io/installation":  "wonderland",
+				"kubernetes.io/cluster/lbj23": "owned",
+				"customertag1":                "changed",
+			},
+			add: map[string]string{
+				"customertag1": "customertagvalue1",
+			},
+		},
+		{
+			name: "Unknown tag is kept",
+			current: map[string]string{
+				"giantswarm.io/cluster":       "lbj23",
+				"giantswarm.io/installation":  "wonderland",
+				"kubernetes.io/cluster/lbj23": "owned",
+				"customertag1":                "customertagvalue1",
+				"customertag2":                "customertagvalue2",
+			},
+			previous: []string{"customertag1"},
+			add:      map[string]string{},
+			remove:   []string{},
+		},
+		{
+			name: "Removed customer tag is removed",
+			current: map[string]string{
+				"giantswarm.io/cluster":       "lbj23",
+				"giantswarm.io/installation":  "wonderland",
+				"kubernetes.io/cluster/lbj23": "owned",
+				"customertag1":                "customertagvalue1",
+				"customertag2":                "customertagvalue2",
+			},
+			previous: []string{"customertag1", "customertag2"},
+			add:      map[string]string{},
+			remove:   []string{"customertag2"},
+		},
+		{
+			name: "Removed customer tag already gone",
+			current: map[string]string{
+				"giantswarm.io/cluster":       "lbj23",
+				"giantswarm.io/installation":  "wonderland",
+				"kubernetes.io/cluster/lbj23": "owned",
+				"customertag1":                "customertagvalue1",
+			},
+			previous: []string{"customertag1", "customertag2"},
+			add:      map[string]string{},
+			remove:   []string{},
+		},
+		{
+			name:    "No tags at all",
+			current: map[string]string{},
+			add:     desiredTags,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			add, remove := tagsNeedUpdating(tt.current, desiredTags, tt.previous)
+			if !reflect.DeepEqual(add, tt.add) {
+				t.Errorf("tagsNeedUpdating() Wanted tagsToBeAdded to be %v, was %v", tt.add, add)
+			}
+			if tt.remove == nil {
+				tt.remove = []string{}
+			}
+			if !reflect.DeepEqual(remove, tt.remove) {
+				t.Errorf("tagsNeedUpdating() Wanted tagsToBeRemoved to be %v, was %v", tt.remove, remove)
+			}
+		})
+	}
+}
+
+func Test_ensureTags(t *testing.T) {
+	arn := "arn:aws:acm:eu-west-1:123456789012:certificate/owned"
+	client := &fakeACMClient{tags: map[string]map[string]string{arn: {"foreign": "value"}}}
+	s := &Service{scope: newFakeScope(), Client: client}
+
+	// The steps run one after the other against the same certificate, like consecutive reconciliations.
+	steps := []struct {
+		name         string
+		customerTags map[string]string
+		want         map[string]string
+	}{
+		{
+			name:         "customer tags added",
+			customerTags: map[string]string{"customertag1": "value1", "customertag2": "value2"},
+			want: map[string]string{
+				"foreign":                     "value",
+				"giantswarm.io/cluster":       "lbj23",
+				"giantswarm.io/installation":  "wonderland",
+				"giantswarm.io/organization":  "wonderland",
+				"kubernetes.io/cluster/lbj23": "owned",
+				"customertag1":                "value1",
+				"customertag2":                "value2",
+			},
+		},
+		{
+			name:         "dropped customer tag removed, foreign tag kept",
+			customerTags: map[string]string{"customertag1": "value1"},
+			want: map[string]string{
+				"foreign":                     "value",
+				"giantswarm.io/cluster":       "lbj23",
+				"giantswarm.io/installation":  "wonderland",
+				"giantswarm.io/organization":  "wonderland",
+				"kubernetes.io/cluster/lbj23": "owned",
+				"customertag1":                "value1",
+			},
+		},
+		{
+			name: "all customer tags removed",
+			want: map[string]string{
+				"foreign":                     "value",
+				"giantswarm.io/cluster":       "lbj23",
+				"giantswarm.io/installation":  "wonderland",
+				"giantswarm.io/organization":  "wonderland",
+				"kubernetes.io/cluster/lbj23": "owned",
+			},
+		},
+	}
+	for _, step := range steps {
+		err := s.ensureTags(arn, step.customerTags)
+		if err != nil {
+			t.Fatalf("%s: ensureTags() error = %v", step.name, err)
+		}
+		if !reflect.DeepEqual(client.tags[arn], step.want) {
+			t.Errorf("%s: ensureTags() tags = %v, want %v", step.name, client.tags[arn], step.want)
+		}
+	}
+}
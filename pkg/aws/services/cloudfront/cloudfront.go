@@ -20,12 +20,29 @@ type Distribution struct {
 	OriginAccessIdentityId string
 }
 
+const (
+	// AWS managed cache policy id, caching is disabled for the distribution.
+	cachePolicyCachingDisabled = "4135ea2d-6df8-44a3-9df3-4b5a84be39ad"
+	// AWS managed cache policy id, caching honours the Cache-Control headers sent by the origin.
+	cachePolicyCachingOptimized = "658327ea-f89d-481b-9e2d-445e2e6c2a1e"
+)
+
 type DistributionConfig struct {
 	Aliases        []*string
 	CertificateArn string
 	CustomerTags   map[string]string
+	// EnableCaching makes CloudFront cache the OIDC documents according to the Cache-Control headers of the
+	// S3 objects.
+	EnableCaching bool
+	// ReplicaBucketName and ReplicaRegion describe an optional replica of the OIDC bucket. When set, the
+	// distribution uses an origin group which fails over from the primary bucket to the replica.
+	ReplicaBucketName string
+	ReplicaRegion     string
 }
 
+// originFailoverStatusCodes are the origin responses which make CloudFront retry the request against the replica.
+var originFailoverStatusCodes = []*int64{aws.Int64(403), aws.Int64(404), aws.Int64(500), aws.Int64(502), aws.Int64(503), aws.Int64(504)}
+
 func (s *Service) CreateOriginAccessIdentity() (string, error) {
 	i := &cloudfront.CreateCloudFrontOriginAccessIdentityInput{
 		CloudFrontOriginAccessIdentityConfig: &cloudfront.OriginAccessIdentityConfig{
@@ -70,6 +87,8 @@ func (s *Service) EnsureDistribution(config DistributionConfig) (*Distribution,
 		return nil, err
 	}
 
+	origins, originGroups, targetOriginId := s.origins(config, oaiId)
+
 	i := &cloudfront.CreateDistributionWithTagsInput{
 		DistributionConfigWithTags: &cloudfront.DistributionConfigWithTags{
 			DistributionConfig: &cloudfront.DistributionConfig{
@@ -80,27 +99,15 @@ func (s *Service) EnsureDistribution(config DistributionConfig) (*Distribution,
 				CallerReference: aws.String(s.scope.CallerReference()),
 				Comment:         aws.String(key.CloudFrontDistributionComment(s.scope.ClusterName())),
 				DefaultCacheBehavior: &cloudfront.DefaultCacheBehavior{
-					// AWS managed cache policy id, caching is disabled for the distribution.
-					CachePolicyId:        aws.String("4135ea2d-6df8-44a3-9df3-4b5a84be39ad"),
-					TargetOriginId:       aws.String(fmt.Sprintf("%s.s3.%s.%s", s.scope.BucketName(), s.scope.Region(), key.AWSEndpoint(s.scope.Region()))),
+					CachePolicyId:        aws.String(cachePolicyId(config)),
+					TargetOriginId:       aws.String(targetOriginId),
 					ViewerProtocolPolicy: aws.String("redirect-to-https"),
 				},
 				Enabled: aws.Bool(true),
-				Origins: &cloudfront.Origins{
-					Items: []*cloudfront.Origin{
-						{
-							Id:         aws.String(fmt.Sprintf("%s.s3.%s.%s", s.scope.BucketName(), s.scope.Region(), key.AWSEndpoint(s.scope.Region()))),
-							DomainName: aws.String(fmt.Sprintf("%s.s3.%s.%s", s.scope.BucketName(), s.scope.Region(), key.AWSEndpoint(s.scope.Region()))),
-							OriginShield: &cloudfront.OriginShield{
-								Enabled: aws.Bool(false),
-							},
-							S3OriginConfig: &cloudfront.S3OriginConfig{
-								OriginAccessIdentity: aws.String(fmt.Sprintf("origin-access-identity/cloudfront/%s", oaiId)),
-							},
-						},
-					},
-					Quantity: aws.Int64(1),
-				},
+				// The aliases point at the distribution with both A and AAAA alias records.
+				IsIPV6Enabled: aws.Bool(true),
+				OriginGroups:  originGroups,
+				Origins:       origins,
 				Restrictions: &cloudfront.Restrictions{
 					GeoRestriction: &cloudfront.GeoRestriction{
 						RestrictionType: aws.String("none"),
@@ -176,6 +183,11 @@ func (s *Service) EnsureDistribution(config DistributionConfig) (*Distribution,
 		dc := diff.Existing.DistributionConfig
 		dc.Aliases = i.DistributionConfigWithTags.DistributionConfig.Aliases
 		dc.ViewerCertificate = i.DistributionConfigWithTags.DistributionConfig.ViewerCertificate
+		dc.Origins = origins
+		dc.OriginGroups = originGroups
+		dc.DefaultCacheBehavior.TargetOriginId = aws.String(targetOriginId)
+		dc.DefaultCacheBehavior.CachePolicyId = aws.String(cachePolicyId(config))
+		dc.IsIPV6Enabled = aws.Bool(true)
 
 		_, err := s.Client.UpdateDistribution(&cloudfront.UpdateDistributionInput{
 			DistributionConfig: dc,
@@ -227,6 +239,82 @@ func (s *Service) EnsureDistribution(config DistributionConfig) (*Distribution,
 	return &Distribution{ARN: *diff.Existing.ARN, DistributionId: *diff.Existing.Id, Domain: *diff.Existing.DomainName, OriginAccessIdentityId: oaiId}, nil
 }
 
+// origins returns the S3 origins of the distribution and, if a replica bucket is configured, the origin group
+// failing over from the primary to the replica. The last return value is the ID the cache behavior must target.
+func (s *Service) origins(config DistributionConfig, oaiId string) (*cloudfront.Origins, *cloudfront.OriginGroups, string) {
+	primaryOriginId := s.primaryOriginDomain()
+
+	newOrigin := func(domain string) *cloudfront.Origin {
+		return &cloudfront.Origin{
+			Id:         aws.String(domain),
+			DomainName: aws.String(domain),
+			OriginShield: &cloudfront.OriginShield{
+				Enabled: aws.Bool(false),
+			},
+			S3OriginConfig: &cloudfront.S3OriginConfig{
+				OriginAccessIdentity: aws.String(fmt.Sprintf("origin-access-identity/cloudfront/%s", oaiId)),
+			},
+		}
+	}
+
+	// The primary origin must stay the first item, `findDistribution` relies on it.
+	origins := &cloudfront.Origins{
+		Items:    []*cloudfront.Origin{newOrigin(primaryOriginId)},
+		Quantity: aws.Int64(1),
+	}
+	originGroups := &cloudfront.OriginGroups{
+		Items:    []*cloudfront.OriginGroup{},
+		Quantity: aws.Int64(0),
+	}
+
+	replicaOriginId := replicaOriginDomain(config)
+	if replicaOriginId == "" {
+		return origins, originGroups, primaryOriginId
+	}
+
+	origins.Items = append(origins.Items, newOrigin(replicaOriginId))
+	origins.Quantity = aws.Int64(2)
+
+	groupId := fmt.Sprintf("%s-failover", s.scope.BucketName())
+	originGroups.Items = append(originGroups.Items, &cloudfront.OriginGroup{
+		Id: aws.String(groupId),
+		FailoverCriteria: &cloudfront.OriginGroupFailoverCriteria{
+			StatusCodes: &cloudfront.StatusCodes{
+				Items:    originFailoverStatusCodes,
+				Quantity: aws.Int64(int64(len(originFailoverStatusCodes))),
+			},
+		},
+		Members: &cloudfront.OriginGroupMembers{
+			Items: []*cloudfront.OriginGroupMember{
+				{OriginId: aws.String(primaryOriginId)},
+				{OriginId: aws.String(replicaOriginId)},
+			},
+			Quantity: aws.Int64(2),
+		},
+	})
+	originGroups.Quantity = aws.Int64(1)
+
+	return origins, originGroups, groupId
+}
+
+func cachePolicyId(config DistributionConfig) string {
+	if config.EnableCaching {
+		return cachePolicyCachingOptimized
+	}
+	return cachePolicyCachingDisabled
+}
+
+func (s *Service) primaryOriginDomain() string {
+	return fmt.Sprintf("%s.s3.%s.%s", s.scope.BucketName(), s.scope.Region(), key.AWSEndpoint(s.scope.Region()))
+}
+
+func replicaOriginDomain(config DistributionConfig) string {
+	if config.ReplicaBucketName == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s.s3.%s.%s", config.ReplicaBucketName, config.ReplicaRegion, key.AWSEndpoint(config.ReplicaRegion))
+}
+
 func (s *Service) findDistribution(CloudFrontAlias string) (*Distribution, error) {
 	// Check if distribution already exists
 	var err error
@@ -92,9 +92,47 @@ func (s *Service) distributionNeedsUpdate(distribution *cloudfront.Distribution,
 		changed = true
 	}
 
+	if distribution.DistributionConfig.DefaultCacheBehavior != nil &&
+		aws.StringValue(distribution.DistributionConfig.DefaultCacheBehavior.CachePolicyId) != cachePolicyId(config) {
+		s.scope.Logger().Info("Distribution cache policy needs to be updated")
+		changed = true
+	}
+
+	if distribution.DistributionConfig.IsIPV6Enabled != nil && !*distribution.DistributionConfig.IsIPV6Enabled {
+		s.scope.Logger().Info("Distribution IPv6 support needs to be enabled")
+		changed = true
+	}
+
+	if currentReplicaOriginDomain(distribution) != replicaOriginDomain(config) {
+		s.scope.Logger().Info("Distribution replica origin needs to be updated")
+		changed = true
+	}
+
 	return changed
 }
 
+// currentReplicaOriginDomain returns the domain of the failover origin of the distribution, or an empty string if
+// the distribution does not use an origin group.
+func currentReplicaOriginDomain(distribution *cloudfront.Distribution) string {
+	dc := distribution.DistributionConfig
+	if dc.OriginGroups == nil || len(dc.OriginGroups.Items) == 0 || dc.Origins == nil {
+		return ""
+	}
+
+	members := dc.OriginGroups.Items[0].Members
+	if members == nil || len(members.Items) < 2 || members.Items[1].OriginId == nil {
+		return ""
+	}
+
+	for _, origin := range dc.Origins.Items {
+		if origin.Id != nil && *origin.Id == *members.Items[1].OriginId && origin.DomainName != nil {
+			return *origin.DomainName
+		}
+	}
+
+	return ""
+}
+
 // tagsNeedUpdating compares current tags in the cloudfront distribution with default tags and customer tags
 // and returns two map with tags to be added and tags to be removed
 func tagsNeedUpdating(tags *cloudfront.Tags, internalTags map[string]string, config DistributionConfig) (tagsToBeAdded map[string]string, tagsToBeRemoved []string) {
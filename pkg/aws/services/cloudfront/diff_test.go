@@ -30,6 +30,26 @@ func TestService_distributionNeedsUpdate(t *testing.T) {
 			config: DistributionConfig{},
 			want:   false,
 		},
+		{
+			name: "IPv6 enabled",
+			distribution: &cloudfront.Distribution{
+				DistributionConfig: &cloudfront.DistributionConfig{
+					IsIPV6Enabled: aws.Bool(true),
+				},
+			},
+			config: DistributionConfig{},
+			want:   false,
+		},
+		{
+			name: "IPv6 disabled",
+			distribution: &cloudfront.Distribution{
+				DistributionConfig: &cloudfront.DistributionConfig{
+					IsIPV6Enabled: aws.Bool(false),
+				},
+			},
+			config: DistributionConfig{},
+			want:   true,
+		},
 		{
 			name: "Added alias",
 			distribution: &cloudfront.Distribution{
@@ -89,6 +109,111 @@ func TestService_distributionNeedsUpdate(t *testing.T) {
 			},
 			want: true,
 		},
+		{
+			name: "Caching unchanged",
+			distribution: &cloudfront.Distribution{
+				DistributionConfig: &cloudfront.DistributionConfig{
+					DefaultCacheBehavior: &cloudfront.DefaultCacheBehavior{
+						CachePolicyId: aws.String(cachePolicyCachingDisabled),
+					},
+				},
+			},
+			config: DistributionConfig{},
+			want:   false,
+		},
+		{
+			name: "Caching enabled",
+			distribution: &cloudfront.Distribution{
+				DistributionConfig: &cloudfront.DistributionConfig{
+					DefaultCacheBehavior: &cloudfront.DefaultCacheBehavior{
+						CachePolicyId: aws.String(cachePolicyCachingDisabled),
+					},
+				},
+			},
+			config: DistributionConfig{
+				EnableCaching: true,
+			},
+			want: true,
+		},
+		{
+			name: "Added replica origin",
+			distribution: &cloudfront.Distribution{
+				DistributionConfig: &cloudfront.DistributionConfig{
+					Aliases:           nil,
+					ViewerCertificate: nil,
+				},
+			},
+			config: DistributionConfig{
+				ReplicaBucketName: "bucket-replica",
+				ReplicaRegion:     "eu-central-1",
+			},
+			want: true,
+		},
+		{
+			name: "Unchanged replica origin",
+			distribution: &cloudfront.Distribution{
+				DistributionConfig: &cloudfront.DistributionConfig{
+					OriginGroups: &cloudfront.OriginGroups{
+						Items: []*cloudfront.OriginGroup{
+							{
+								Id: aws.String("bucket-failover"),
+								Members: &cloudfront.OriginGroupMembers{
+									Items: []*cloudfront.OriginGroupMember{
+										{OriginId: aws.String("bucket.s3.eu-west-1.amazonaws.com")},
+										{OriginId: aws.String("bucket-replica.s3.eu-central-1.amazonaws.com")},
+									},
+									Quantity: aws.Int64(2),
+								},
+							},
+						},
+						Quantity: aws.Int64(1),
+					},
+					Origins: &cloudfront.Origins{
+						Items: []*cloudfront.Origin{
+							{Id: aws.String("bucket.s3.eu-west-1.amazonaws.com"), DomainName: aws.String("bucket.s3.eu-west-1.amazonaws.com")},
+							{Id: aws.String("bucket-replica.s3.eu-central-1.amazonaws.com"), DomainName: aws.String("bucket-replica.s3.eu-central-1.amazonaws.com")},
+						},
+						Quantity: aws.Int64(2),
+					},
+				},
+			},
+			config: DistributionConfig{
+				ReplicaBucketName: "bucket-replica",
+				ReplicaRegion:     "eu-central-1",
+			},
+			want: false,
+		},
+		{
+			name: "Removed replica origin",
+			distribution: &cloudfront.Distribution{
+				DistributionConfig: &cloudfront.DistributionConfig{
+					OriginGroups: &cloudfront.OriginGroups{
+						Items: []*cloudfront.OriginGroup{
+							{
+								Id: aws.String("bucket-failover"),
+								Members: &cloudfront.OriginGroupMembers{
+									Items: []*cloudfront.OriginGroupMember{
+										{OriginId: aws.String("bucket.s3.eu-west-1.amazonaws.com")},
+										{OriginId: aws.String("bucket-replica.s3.eu-central-1.amazonaws.com")},
+									},
+									Quantity: aws.Int64(2),
+								},
+							},
+						},
+						Quantity: aws.Int64(1),
+					},
+					Origins: &cloudfront.Origins{
+						Items: []*cloudfront.Origin{
+							{Id: aws.String("bucket.s3.eu-west-1.amazonaws.com"), DomainName: aws.String("bucket.s3.eu-west-1.amazonaws.com")},
+							{Id: aws.String("bucket-replica.s3.eu-central-1.amazonaws.com"), DomainName: aws.String("bucket-replica.s3.eu-central-1.amazonaws.com")},
+						},
+						Quantity: aws.Int64(2),
+					},
+				},
+			},
+			config: DistributionConfig{},
+			want:   true,
+		},
 	}
 
 	for _, tt := range tests {
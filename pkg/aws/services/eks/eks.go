@@ -6,7 +6,8 @@ import (
 	"github.com/giantswarm/microerror"
 )
 
-// GetEKSOpenIDConnectProviderURL fetches OpenID Connect provider URL for the EKS cluster
+// GetEKSOpenIDConnectProviderURL fetches OpenID Connect provider URL for the EKS cluster. It fails with
+// oidcIssuerNotAvailableError while the cluster is being created or has no issuer.
 func (s *Service) GetEKSOpenIDConnectProviderURL(clusterName string) (string, error) {
 	i := &eks.DescribeClusterInput{
 		Name: aws.String(clusterName),
@@ -15,5 +16,20 @@ func (s *Service) GetEKSOpenIDConnectProviderURL(clusterName string) (string, er
 	if err != nil {
 		return "", microerror.Mask(err)
 	}
-	return *cluster.Cluster.Identity.Oidc.Issuer, nil
+
+	return oidcIssuer(cluster.Cluster)
+}
+
+// oidcIssuer returns the OIDC issuer of the cluster. The issuer is assigned during creation and kept while the
+// cluster is updated.
+func oidcIssuer(cluster *eks.Cluster) (string, error) {
+	status := aws.StringValue(cluster.Status)
+	if status != eks.ClusterStatusActive && status != eks.ClusterStatusUpdating {
+		return "", microerror.Maskf(oidcIssuerNotAvailableError, "EKS cluster has status %q", status)
+	}
+	if cluster.Identity == nil || cluster.Identity.Oidc == nil || aws.StringValue(cluster.Identity.Oidc.Issuer) == "" {
+		return "", microerror.Maskf(oidcIssuerNotAvailableError, "EKS cluster has no OIDC issuer")
+	}
+
+	return aws.StringValue(cluster.Identity.Oidc.Issuer), nil
 }
@@ -0,0 +1,37 @@
+package eks
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/eks"
+)
+
+func Test_oidcIssuer(t *testing.T) {
+	identity := &eks.Identity{Oidc: &eks.OIDC{Issuer: aws.String("https://oidc.eks.eu-west-1.amazonaws.com/id/EXAMPLE")}}
+
+	tests := []struct {
+		name             string
+		cluster          *eks.Cluster
+		want             string
+		wantNotAvailable bool
+	}{
+		{name: "active", cluster: &eks.Cluster{Status: aws.String(eks.ClusterStatusActive), Identity: identity}, want: "https://oidc.eks.eu-west-1.amazonaws.com/id/EXAMPLE"},
+		{name: "updating", cluster: &eks.Cluster{Status: aws.String(eks.ClusterStatusUpdating), Identity: identity}, want: "https://oidc.eks.eu-west-1.amazonaws.com/id/EXAMPLE"},
+		{name: "creating", cluster: &eks.Cluster{Status: aws.String(eks.ClusterStatusCreating)}, wantNotAvailable: true},
+		{name: "failed", cluster: &eks.Cluster{Status: aws.String(eks.ClusterStatusFailed), Identity: identity}, wantNotAvailable: true},
+		{name: "no issuer", cluster: &eks.Cluster{Status: aws.String(eks.ClusterStatusActive), Identity: &eks.Identity{}}, wantNotAvailable: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := oidcIssuer(tt.cluster)
+			if IsOIDCIssuerNotAvailable(err) != tt.wantNotAvailable {
+				t.Errorf("oidcIssuer() error = %v, wantNotAvailable %v", err, tt.wantNotAvailable)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("oidcIssuer() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,12 @@
+package eks
+
+import "github.com/giantswarm/microerror"
+
+var oidcIssuerNotAvailableError = &microerror.Error{
+	Kind: "oidcIssuerNotAvailableError",
+}
+
+// IsOIDCIssuerNotAvailable asserts oidcIssuerNotAvailableError.
+func IsOIDCIssuerNotAvailable(err error) bool {
+	return microerror.Cause(err) == oidcIssuerNotAvailableError
+}
@@ -0,0 +1,315 @@
+package eks
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/eks"
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/irsa-operator/pkg/key"
+	"github.com/giantswarm/irsa-operator/pkg/util"
+	"github.com/giantswarm/irsa-operator/pkg/util/record"
+)
+
+// describedAssociationsCacheExpiry is how long the described associations of a cluster are reused while neither the
+// desired nor the listed associations change, to eventually pick up roles changed outside of this operator.
+const describedAssociationsCacheExpiry = time.Hour
+
+// describedAssociations are the described associations of a cluster that matched the desired associations.
+type describedAssociations struct {
+	desired        []key.PodIdentityAssociation
+	associationIDs []string
+	associations   []*eks.PodIdentityAssociation
+}
+
+// podIdentityAssociationsDiff holds the changes needed to get from the existing to the desired associations.
+type podIdentityAssociationsDiff struct {
+	Create []key.PodIdentityAssociation
+	Update []podIdentityAssociationUpdate
+	Delete []*eks.PodIdentityAssociation
+	// Conflicts are desired associations whose service account is already associated by someone else.
+	Conflicts []*eks.PodIdentityAssociation
+}
+
+type podIdentityAssociationUpdate struct {
+	AssociationID string
+	RoleARN       string
+}
+
+// EnsurePodIdentityAgentAddon installs the EKS Pod Identity agent add-on if the cluster doesn't have it yet. An
+// existing add-on is left untouched, it may be managed by someone else.
+func (s *Service) EnsurePodIdentityAgentAddon(clusterName string, customerTags map[string]string) error {
+	_, err := s.Client.DescribeAddon(&eks.DescribeAddonInput{
+		AddonName:   aws.String(key.PodIdentityAgentAddonName),
+		ClusterName: aws.String(clusterName),
+	})
+	if err == nil {
+		return nil
+	} else if aerr, ok := err.(awserr.Error); !ok || aerr.Code() != eks.ErrCodeResourceNotFoundException {
+		return microerror.Mask(err)
+	}
+
+	s.scope.Logger().Info("Installing EKS Pod Identity agent add-on", "addon", key.PodIdentityAgentAddonName)
+	_, err = s.Client.CreateAddon(&eks.CreateAddonInput{
+		AddonName:   aws.String(key.PodIdentityAgentAddonName),
+		ClusterName: aws.String(clusterName),
+		Tags:        s.tags(customerTags),
+	})
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == eks.ErrCodeResourceInUseException {
+		// Created in the meantime.
+		return nil
+	} else if err != nil {
+		return microerror.Mask(err)
+	}
+
+	s.scope.Logger().Info("Installed EKS Pod Identity agent add-on", "addon", key.PodIdentityAgentAddonName)
+
+	return nil
+}
+
+// EnsurePodIdentityAssociations creates, updates and deletes the pod identity associations of the cluster that
+// were created by the operator, so that they match the desired ones. Service accounts that are already associated
+// by someone else are skipped with a warning event. While neither the desired nor the listed associations change,
+// the associations are not described again.
+func (s *Service) EnsurePodIdentityAssociations(clusterName string, desired []key.PodIdentityAssociation, customerTags map[string]string) error {
+	summaries, err := s.listPodIdentityAssociationSummaries(clusterName)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	cacheKey := describedAssociationsCacheKey(clusterName)
+	associationIDs := summaryIDs(summaries)
+
+	var existing []*eks.PodIdentityAssociation
+	if cachedValue, ok := s.scope.Cache().Get(cacheKey); ok && cachedValue.(*describedAssociations).matches(desired, associationIDs) {
+		existing = cachedValue.(*describedAssociations).associations
+	} else {
+		existing, err = s.describePodIdentityAssociations(clusterName, summaries)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	diff := diffPodIdentityAssociations(desired, existing, s.scope.Installation(), clusterName)
+
+	for _, association := range diff.Conflicts {
+		s.scope.Logger().Info("Service account is already associated by someone else, skipping", "namespace", aws.StringValue(association.Namespace), "serviceAccount", aws.StringValue(association.ServiceAccount), "associationId", aws.StringValue(association.AssociationId))
+		record.Warnf(s.scope.Cluster(), "PodIdentityAssociationConflict", "Service account %s/%s is already associated with role %s by someone else, not managing it", aws.StringValue(association.Namespace), aws.StringValue(association.ServiceAccount), aws.StringValue(association.RoleArn))
+	}
+
+	if len(diff.Create) == 0 && len(diff.Update) == 0 && len(diff.Delete) == 0 {
+		s.scope.Cache().Set(cacheKey, &describedAssociations{desired: desired, associationIDs: associationIDs, associations: existing}, describedAssociationsCacheExpiry)
+		return nil
+	}
+	s.scope.Cache().Delete(cacheKey)
+
+	for _, association := range diff.Create {
+		s.scope.Logger().Info("Creating pod identity association", "namespace", association.Namespace, "serviceAccount", association.ServiceAccount, "roleArn", association.RoleARN)
+		_, err := s.Client.CreatePodIdentityAssociation(&eks.CreatePodIdentityAssociationInput{
+			ClusterName:    aws.String(clusterName),
+			Namespace:      aws.String(association.Namespace),
+			RoleArn:        aws.String(association.RoleARN),
+			ServiceAccount: aws.String(association.ServiceAccount),
+			Tags:           s.associationTags(customerTags),
+		})
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	for _, update := range diff.Update {
+		s.scope.Logger().Info("Updating role of pod identity association", "associationId", update.AssociationID, "roleArn", update.RoleARN)
+		_, err := s.Client.UpdatePodIdentityAssociation(&eks.UpdatePodIdentityAssociationInput{
+			AssociationId: aws.String(update.AssociationID),
+			ClusterName:   aws.String(clusterName),
+			RoleArn:       aws.String(update.RoleARN),
+		})
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	for _, association := range diff.Delete {
+		err := s.deletePodIdentityAssociation(clusterName, association)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	return nil
+}
+
+// DeletePodIdentityAssociations deletes all pod identity associations of the cluster that were created by the
+// operator.
+func (s *Service) DeletePodIdentityAssociations(clusterName string) error {
+	s.scope.Cache().Delete(describedAssociationsCacheKey(clusterName))
+
+	existing, err := s.listPodIdentityAssociations(clusterName)
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == eks.ErrCodeResourceNotFoundException {
+		s.scope.Logger().Info("EKS cluster no longer exists, skipping deletion of pod identity associations")
+		return nil
+	} else if err != nil {
+		return microerror.Mask(err)
+	}
+
+	diff := diffPodIdentityAssociations(nil, existing, s.scope.Installation(), clusterName)
+	for _, association := range diff.Delete {
+		err := s.deletePodIdentityAssociation(clusterName, association)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	return nil
+}
+
+func (s *Service) deletePodIdentityAssociation(clusterName string, association *eks.PodIdentityAssociation) error {
+	s.scope.Logger().Info("Deleting pod identity association", "namespace", aws.StringValue(association.Namespace), "serviceAccount", aws.StringValue(association.ServiceAccount), "associationId", aws.StringValue(association.AssociationId))
+	_, err := s.Client.DeletePodIdentityAssociation(&eks.DeletePodIdentityAssociationInput{
+		AssociationId: association.AssociationId,
+		ClusterName:   aws.String(clusterName),
+	})
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == eks.ErrCodeResourceNotFoundException {
+		return nil
+	} else if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// listPodIdentityAssociations returns all pod identity associations of the cluster. The listing doesn't contain
+// roles and tags, so every association is described.
+func (s *Service) listPodIdentityAssociations(clusterName string) ([]*eks.PodIdentityAssociation, error) {
+	summaries, err := s.listPodIdentityAssociationSummaries(clusterName)
+	if err != nil {
+		// Not masked, callers check for a deleted cluster.
+		return nil, err
+	}
+
+	return s.describePodIdentityAssociations(clusterName, summaries)
+}
+
+func (s *Service) listPodIdentityAssociationSummaries(clusterName string) ([]*eks.PodIdentityAssociationSummary, error) {
+	var summaries []*eks.PodIdentityAssociationSummary
+	err := s.Client.ListPodIdentityAssociationsPages(&eks.ListPodIdentityAssociationsInput{
+		ClusterName: aws.String(clusterName),
+	}, func(page *eks.ListPodIdentityAssociationsOutput, lastPage bool) bool {
+		summaries = append(summaries, page.Associations...)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return summaries, nil
+}
+
+func (s *Service) describePodIdentityAssociations(clusterName string, summaries []*eks.PodIdentityAssociationSummary) ([]*eks.PodIdentityAssociation, error) {
+	associations := make([]*eks.PodIdentityAssociation, 0, len(summaries))
+	for _, summary := range summaries {
+		output, err := s.Client.DescribePodIdentityAssociation(&eks.DescribePodIdentityAssociationInput{
+			AssociationId: summary.AssociationId,
+			ClusterName:   aws.String(clusterName),
+		})
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == eks.ErrCodeResourceNotFoundException {
+			// Deleted since it was listed.
+			continue
+		} else if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		associations = append(associations, output.Association)
+	}
+
+	return associations, nil
+}
+
+// matches checks whether the associations were described for the same desired and listed associations.
+func (d *describedAssociations) matches(desired []key.PodIdentityAssociation, associationIDs []string) bool {
+	return reflect.DeepEqual(d.desired, desired) && reflect.DeepEqual(d.associationIDs, associationIDs)
+}
+
+func describedAssociationsCacheKey(clusterName string) string {
+	return fmt.Sprintf("eks/cluster=%q/described-pod-identity-associations", clusterName)
+}
+
+// summaryIDs returns the sorted IDs of the listed associations.
+func summaryIDs(summaries []*eks.PodIdentityAssociationSummary) []string {
+	ids := make([]string, 0, len(summaries))
+	for _, summary := range summaries {
+		ids = append(ids, aws.StringValue(summary.AssociationId))
+	}
+	sort.Strings(ids)
+
+	return ids
+}
+
+func (s *Service) tags(customerTags map[string]string) map[string]*string {
+	tags := map[string]*string{}
+	for k, v := range customerTags {
+		tags[k] = aws.String(v)
+	}
+	// Internal tags take precedence over customer tags.
+	tags[key.S3TagOrganization] = aws.String(util.RemoveOrg(s.scope.ClusterNamespace()))
+	tags[key.S3TagCluster] = aws.String(s.scope.ClusterName())
+	tags[fmt.Sprintf(key.S3TagCloudProvider, s.scope.ClusterName())] = aws.String("owned")
+	tags[key.S3TagInstallation] = aws.String(s.scope.Installation())
+
+	return tags
+}
+
+// associationTags returns the tags of the associations created by the operator. Other associations of the cluster,
+// e.g. the ones created by CAPA, carry the installation and cluster tags as well, so the operator marks its own.
+func (s *Service) associationTags(customerTags map[string]string) map[string]*string {
+	tags := s.tags(customerTags)
+	tags[key.TagManagedBy] = aws.String(key.TagManagedByOperator)
+
+	return tags
+}
+
+// diffPodIdentityAssociations compares the desired associations with the existing ones. Only existing associations
+// created by the operator for the cluster are updated or deleted.
+func diffPodIdentityAssociations(desired []key.PodIdentityAssociation, existing []*eks.PodIdentityAssociation, installation, clusterName string) podIdentityAssociationsDiff {
+	diff := podIdentityAssociationsDiff{}
+
+	existingByServiceAccount := map[string]*eks.PodIdentityAssociation{}
+	for _, association := range existing {
+		existingByServiceAccount[aws.StringValue(association.Namespace)+"/"+aws.StringValue(association.ServiceAccount)] = association
+	}
+
+	desiredServiceAccounts := map[string]bool{}
+	for _, association := range desired {
+		serviceAccount := association.Namespace + "/" + association.ServiceAccount
+		desiredServiceAccounts[serviceAccount] = true
+
+		current, ok := existingByServiceAccount[serviceAccount]
+		if !ok {
+			diff.Create = append(diff.Create, association)
+		} else if !isOwned(current, installation, clusterName) {
+			diff.Conflicts = append(diff.Conflicts, current)
+		} else if aws.StringValue(current.RoleArn) != association.RoleARN {
+			diff.Update = append(diff.Update, podIdentityAssociationUpdate{AssociationID: aws.StringValue(current.AssociationId), RoleARN: association.RoleARN})
+		}
+	}
+
+	for _, association := range existing {
+		serviceAccount := aws.StringValue(association.Namespace) + "/" + aws.StringValue(association.ServiceAccount)
+		if !desiredServiceAccounts[serviceAccount] && isOwned(association, installation, clusterName) {
+			diff.Delete = append(diff.Delete, association)
+		}
+	}
+
+	return diff
+}
+
+func isOwned(association *eks.PodIdentityAssociation, installation, clusterName string) bool {
+	return aws.StringValue(association.Tags[key.TagManagedBy]) == key.TagManagedByOperator &&
+		aws.StringValue(association.Tags[key.S3TagInstallation]) == installation &&
+		aws.StringValue(association.Tags[key.S3TagCluster]) == clusterName
+}
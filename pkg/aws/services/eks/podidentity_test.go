@@ -0,0 +1,232 @@
+package eks
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/eks"
+	"github.com/aws/aws-sdk-go/service/eks/eksiface"
+	"github.com/go-logr/logr"
+	gocache "github.com/patrickmn/go-cache"
+
+	"github.com/giantswarm/irsa-operator/pkg/aws/scope"
+	"github.com/giantswarm/irsa-operator/pkg/key"
+)
+
+type fakeScope struct {
+	scope.EKSScope
+	cache *gocache.Cache
+}
+
+func newFakeScope() *fakeScope {
+	return &fakeScope{cache: gocache.New(gocache.NoExpiration, 0)}
+}
+
+func (s *fakeScope) Cache() *gocache.Cache    { return s.cache }
+func (s *fakeScope) ClusterName() string      { return "test" }
+func (s *fakeScope) ClusterNamespace() string { return "org-test" }
+func (s *fakeScope) Installation() string     { return "golem" }
+func (s *fakeScope) Logger() logr.Logger      { return logr.Discard() }
+
+type fakeEKSClient struct {
+	eksiface.EKSAPI
+	associations map[string]*eks.PodIdentityAssociation
+	deleted      []string
+
+	describeCalls int
+}
+
+func (c *fakeEKSClient) ListPodIdentityAssociationsPages(input *eks.ListPodIdentityAssociationsInput, fn func(*eks.ListPodIdentityAssociationsOutput, bool) bool) error {
+	page := &eks.ListPodIdentityAssociationsOutput{}
+	for id := range c.associations {
+		page.Associations = append(page.Associations, &eks.PodIdentityAssociationSummary{AssociationId: aws.String(id)})
+	}
+	fn(page, true)
+
+	return nil
+}
+
+func (c *fakeEKSClient) DescribePodIdentityAssociation(input *eks.DescribePodIdentityAssociationInput) (*eks.DescribePodIdentityAssociationOutput, error) {
+	c.describeCalls++
+	return &eks.DescribePodIdentityAssociationOutput{Association: c.associations[aws.StringValue(input.AssociationId)]}, nil
+}
+
+func (c *fakeEKSClient) CreatePodIdentityAssociation(input *eks.CreatePodIdentityAssociationInput) (*eks.CreatePodIdentityAssociationOutput, error) {
+	id := aws.StringValue(input.Namespace) + "-" + aws.StringValue(input.ServiceAccount)
+	c.associations[id] = &eks.PodIdentityAssociation{
+		AssociationId:  aws.String(id),
+		Namespace:      input.Namespace,
+		RoleArn:        input.RoleArn,
+		ServiceAccount: input.ServiceAccount,
+		Tags:           input.Tags,
+	}
+	return &eks.CreatePodIdentityAssociationOutput{Association: c.associations[id]}, nil
+}
+
+func (c *fakeEKSClient) DeletePodIdentityAssociation(input *eks.DeletePodIdentityAssociationInput) (*eks.DeletePodIdentityAssociationOutput, error) {
+	c.deleted = append(c.deleted, aws.StringValue(input.AssociationId))
+	delete(c.associations, aws.StringValue(input.AssociationId))
+	return &eks.DeletePodIdentityAssociationOutput{}, nil
+}
+
+func Test_diffPodIdentityAssociations(t *testing.T) {
+	association := func(id, namespace, serviceAccount, roleARN, installation string) *eks.PodIdentityAssociation {
+		return &eks.PodIdentityAssociation{
+			AssociationId:  aws.String(id),
+			Namespace:      aws.String(namespace),
+			ServiceAccount: aws.String(serviceAccount),
+			RoleArn:        aws.String(roleARN),
+			Tags: map[string]*string{
+				key.TagManagedBy:      aws.String(key.TagManagedByOperator),
+				key.S3TagInstallation: aws.String(installation),
+				key.S3TagCluster:      aws.String("test"),
+			},
+		}
+	}
+
+	unchanged := association("a-1", "kube-system", "unchanged", "arn:aws:iam::123456789012:role/unchanged", "golem")
+	changed := association("a-2", "kube-system", "changed", "arn:aws:iam::123456789012:role/old", "golem")
+	removed := association("a-3", "kube-system", "removed", "arn:aws:iam::123456789012:role/removed", "golem")
+	foreign := association("a-4", "kube-system", "foreign", "arn:aws:iam::123456789012:role/foreign", "other")
+	foreignRemoved := association("a-5", "kube-system", "foreign-removed", "arn:aws:iam::123456789012:role/foreign", "other")
+	// Created by CAPA, which sets the installation and cluster tags as well.
+	unmarked := association("a-6", "kube-system", "unmarked", "arn:aws:iam::123456789012:role/unmarked", "golem")
+	delete(unmarked.Tags, key.TagManagedBy)
+	unmarkedRemoved := association("a-7", "kube-system", "unmarked-removed", "arn:aws:iam::123456789012:role/unmarked", "golem")
+	delete(unmarkedRemoved.Tags, key.TagManagedBy)
+
+	desired := []key.PodIdentityAssociation{
+		{Namespace: "kube-system", ServiceAccount: "unchanged", RoleARN: "arn:aws:iam::123456789012:role/unchanged"},
+		{Namespace: "kube-system", ServiceAccount: "changed", RoleARN: "arn:aws:iam::123456789012:role/new"},
+		{Namespace: "kube-system", ServiceAccount: "foreign", RoleARN: "arn:aws:iam::123456789012:role/mine"},
+		{Namespace: "default", ServiceAccount: "new", RoleARN: "arn:aws:iam::123456789012:role/new"},
+		{Namespace: "kube-system", ServiceAccount: "unmarked", RoleARN: "arn:aws:iam::123456789012:role/mine"},
+	}
+
+	got := diffPodIdentityAssociations(desired, []*eks.PodIdentityAssociation{unchanged, changed, removed, foreign, foreignRemoved, unmarked, unmarkedRemoved}, "golem", "test")
+
+	want := podIdentityAssociationsDiff{
+		Create:    []key.PodIdentityAssociation{desired[3]},
+		Update:    []podIdentityAssociationUpdate{{AssociationID: "a-2", RoleARN: "arn:aws:iam::123456789012:role/new"}},
+		Delete:    []*eks.PodIdentityAssociation{removed},
+		Conflicts: []*eks.PodIdentityAssociation{foreign, unmarked},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("diffPodIdentityAssociations() = %+v, want %+v", got, want)
+	}
+
+	got = diffPodIdentityAssociations(nil, []*eks.PodIdentityAssociation{unchanged, foreign}, "golem", "test")
+	if want := []*eks.PodIdentityAssociation{unchanged}; !reflect.DeepEqual(got.Delete, want) {
+		t.Errorf("diffPodIdentityAssociations() deletes %v without desired associations, want %v", got.Delete, want)
+	}
+}
+
+func Test_DeletePodIdentityAssociations(t *testing.T) {
+	association := func(id, installation, clusterName string) *eks.PodIdentityAssociation {
+		return &eks.PodIdentityAssociation{
+			AssociationId:  aws.String(id),
+			Namespace:      aws.String("kube-system"),
+			ServiceAccount: aws.String(id),
+			Tags: map[string]*string{
+				key.TagManagedBy:      aws.String(key.TagManagedByOperator),
+				key.S3TagInstallation: aws.String(installation),
+				key.S3TagCluster:      aws.String(clusterName),
+			},
+		}
+	}
+	unmarked := association("unmarked", "golem", "test")
+	delete(unmarked.Tags, key.TagManagedBy)
+
+	client := &fakeEKSClient{
+		associations: map[string]*eks.PodIdentityAssociation{
+			"owned":         association("owned", "golem", "test"),
+			"other-cluster": association("other-cluster", "golem", "other"),
+			"other-install": association("other-install", "other", "test"),
+			"unmarked":      unmarked,
+			"untagged":      {AssociationId: aws.String("untagged"), Namespace: aws.String("kube-system"), ServiceAccount: aws.String("untagged")},
+		},
+	}
+	s := &Service{scope: newFakeScope(), Client: client}
+
+	err := s.DeletePodIdentityAssociations("test")
+	if err != nil {
+		t.Fatalf("DeletePodIdentityAssociations() error = %v", err)
+	}
+	if want := []string{"owned"}; !reflect.DeepEqual(client.deleted, want) {
+		t.Errorf("DeletePodIdentityAssociations() deleted %v, want %v", client.deleted, want)
+	}
+}
+
+func Test_EnsurePodIdentityAssociations_describeCache(t *testing.T) {
+	client := &fakeEKSClient{associations: map[string]*eks.PodIdentityAssociation{}}
+	s := &Service{scope: newFakeScope(), Client: client}
+
+	desired := []key.PodIdentityAssociation{
+		{Namespace: "kube-system", ServiceAccount: "ebs-csi", RoleARN: "arn:aws:iam::123456789012:role/ebs-csi"},
+	}
+	changed := []key.PodIdentityAssociation{
+		{Namespace: "kube-system", ServiceAccount: "ebs-csi", RoleARN: "arn:aws:iam::123456789012:role/ebs-csi"},
+		{Namespace: "kube-system", ServiceAccount: "efs-csi", RoleARN: "arn:aws:iam::123456789012:role/efs-csi"},
+	}
+
+	// The steps run one after the other against the same cache, like consecutive reconciliations.
+	steps := []struct {
+		name          string
+		desired       []key.PodIdentityAssociation
+		change        func()
+		wantDescribed int
+	}{
+		{
+			name:          "association created",
+			desired:       desired,
+			wantDescribed: 0,
+		},
+		{
+			name:          "created association described",
+			desired:       desired,
+			wantDescribed: 1,
+		},
+		{
+			name:          "nothing changed",
+			desired:       desired,
+			wantDescribed: 0,
+		},
+		{
+			name:    "association added by someone else",
+			desired: desired,
+			change: func() {
+				client.associations["foreign"] = &eks.PodIdentityAssociation{AssociationId: aws.String("foreign"), Namespace: aws.String("default"), ServiceAccount: aws.String("foreign")}
+			},
+			wantDescribed: 2,
+		},
+		{
+			name:          "nothing changed again",
+			desired:       desired,
+			wantDescribed: 0,
+		},
+		{
+			name:          "annotation changed",
+			desired:       changed,
+			wantDescribed: 2,
+		},
+	}
+	for _, step := range steps {
+		if step.change != nil {
+			step.change()
+		}
+
+		client.describeCalls = 0
+		err := s.EnsurePodIdentityAssociations("test", step.desired, nil)
+		if err != nil {
+			t.Fatalf("%s: EnsurePodIdentityAssociations() error = %v", step.name, err)
+		}
+		if client.describeCalls != step.wantDescribed {
+			t.Errorf("%s: EnsurePodIdentityAssociations() described %d associations, want %d", step.name, client.describeCalls, step.wantDescribed)
+		}
+	}
+
+	if got := aws.StringValue(client.associations["kube-system-efs-csi"].Tags[key.TagManagedBy]); got != key.TagManagedByOperator {
+		t.Errorf("EnsurePodIdentityAssociations() created association tagged %q = %q, want %q", key.TagManagedBy, got, key.TagManagedByOperator)
+	}
+}
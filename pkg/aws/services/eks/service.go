@@ -13,7 +13,7 @@ type Service struct {
 }
 
 // NewService returns a new service given the S3 api client.
-func NewService(clusterScope scope.IAMScope) *Service {
+func NewService(clusterScope scope.EKSScope) *Service {
 	return &Service{
 		scope:  clusterScope,
 		Client: scope.NewEKSClient(clusterScope, clusterScope.ARN(), clusterScope.Cluster()),
@@ -0,0 +1,21 @@
+package iam
+
+import "github.com/giantswarm/microerror"
+
+var noVerifiedChainError = &microerror.Error{
+	Kind: "noVerifiedChainError",
+}
+
+// IsNoVerifiedChain asserts noVerifiedChainError.
+func IsNoVerifiedChain(err error) bool {
+	return microerror.Cause(err) == noVerifiedChainError
+}
+
+var executionFailedError = &microerror.Error{
+	Kind: "executionFailedError",
+}
+
+// IsExecutionFailed asserts executionFailedError.
+func IsExecutionFailed(err error) bool {
+	return microerror.Cause(err) == executionFailedError
+}
@@ -0,0 +1,154 @@
+package iam
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/giantswarm/microerror"
+	gocache "github.com/patrickmn/go-cache"
+)
+
+// oidcProviderMaxAge is how long an indexed OIDC provider is trusted before it is fetched again, to eventually pick
+// up changes made outside of this operator.
+const oidcProviderMaxAge = time.Hour
+
+// oidcProviderIndex holds the OIDC providers of an AWS account. It is shared by the reconciliations of all clusters
+// in the account, so that listing the providers of one cluster doesn't cost one `GetOpenIDConnectProvider` call per
+// provider in the account every time.
+type oidcProviderIndex struct {
+	mu        sync.Mutex
+	providers map[string]*indexedOIDCProvider
+	// invalidations counts the changes of each provider, to tell whether a provider changed while it was fetched.
+	invalidations map[string]int
+}
+
+type indexedOIDCProvider struct {
+	provider  *iam.GetOpenIDConnectProviderOutput
+	fetchedAt time.Time
+}
+
+func newOIDCProviderIndex() *oidcProviderIndex {
+	return &oidcProviderIndex{
+		providers:     map[string]*indexedOIDCProvider{},
+		invalidations: map[string]int{},
+	}
+}
+
+// oidcProviderIndex returns the index of the account of the assumed role, creating it on first use.
+func (s *Service) oidcProviderIndex() (*oidcProviderIndex, error) {
+	roleARN, err := arn.Parse(s.roleARN)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	cacheKey := fmt.Sprintf("iam/account=%q/oidc-provider-index", roleARN.AccountID)
+
+	// `Add` fails if another reconciliation created the index in the meantime, which is then used instead.
+	_ = s.scope.Cache().Add(cacheKey, newOIDCProviderIndex(), gocache.NoExpiration)
+
+	cachedValue, ok := s.scope.Cache().Get(cacheKey)
+	if !ok {
+		return nil, microerror.Maskf(executionFailedError, "OIDC provider index for account %q not found in cache", roleARN.AccountID)
+	}
+
+	return cachedValue.(*oidcProviderIndex), nil
+}
+
+// ListOIDCProviders returns all OIDC providers of the account by ARN. Only providers that are new, were changed by
+// this operator or are older than oidcProviderMaxAge are fetched.
+func (s *Service) ListOIDCProviders() (map[string]*iam.GetOpenIDConnectProviderOutput, error) {
+	index, err := s.oidcProviderIndex()
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	output, err := s.Client.ListOpenIDConnectProviders(&iam.ListOpenIDConnectProvidersInput{})
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	now := time.Now()
+	current := make(map[string]*indexedOIDCProvider, len(output.OpenIDConnectProviderList))
+	// The providers to fetch along with their number of invalidations at the time of the lookup.
+	outdated := map[string]int{}
+
+	index.mu.Lock()
+	for _, entry := range output.OpenIDConnectProviderList {
+		providerArn := aws.StringValue(entry.Arn)
+
+		if indexed, ok := index.providers[providerArn]; ok && now.Sub(indexed.fetchedAt) < oidcProviderMaxAge {
+			current[providerArn] = indexed
+			continue
+		}
+		outdated[providerArn] = index.invalidations[providerArn]
+	}
+	index.mu.Unlock()
+
+	// The lock is not held while fetching, so that the reconciliations of other clusters in the account don't wait
+	// for our IAM requests.
+	for providerArn := range outdated {
+		p, err := s.Client.GetOpenIDConnectProvider(&iam.GetOpenIDConnectProviderInput{
+			OpenIDConnectProviderArn: aws.String(providerArn),
+		})
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == iam.ErrCodeNoSuchEntityException {
+			// Deleted since it was listed.
+			continue
+		} else if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		current[providerArn] = &indexedOIDCProvider{provider: p, fetchedAt: now}
+	}
+
+	index.mu.Lock()
+	defer index.mu.Unlock()
+
+	// Providers missing from the listing were deleted. Fetched providers are only indexed if they were not changed in
+	// the meantime, and entries of concurrent listings are kept if they are newer. Providers changed since the
+	// lookup are left out, so that the next listing fetches them again.
+	providers := make(map[string]*indexedOIDCProvider, len(current))
+	for providerArn, listed := range current {
+		existing, isIndexed := index.providers[providerArn]
+		invalidations, isFetched := outdated[providerArn]
+
+		switch {
+		case isFetched && index.invalidations[providerArn] == invalidations && (!isIndexed || !existing.fetchedAt.After(listed.fetchedAt)):
+			providers[providerArn] = listed
+		case isIndexed:
+			providers[providerArn] = existing
+		}
+	}
+	for providerArn := range index.invalidations {
+		if _, ok := current[providerArn]; !ok {
+			delete(index.invalidations, providerArn)
+		}
+	}
+	index.providers = providers
+
+	ret := make(map[string]*iam.GetOpenIDConnectProviderOutput, len(current))
+	for providerArn, indexed := range current {
+		ret[providerArn] = indexed.provider
+	}
+
+	return ret, nil
+}
+
+// invalidateOIDCProvider makes the next listing fetch the provider again. It has to be called after every change
+// to a provider.
+func (s *Service) invalidateOIDCProvider(providerArn string) {
+	index, err := s.oidcProviderIndex()
+	if err != nil {
+		s.scope.Logger().Error(err, "failed to invalidate OIDC provider in index", "providerArn", providerArn)
+		return
+	}
+
+	index.mu.Lock()
+	defer index.mu.Unlock()
+
+	delete(index.providers, providerArn)
+	index.invalidations[providerArn]++
+}
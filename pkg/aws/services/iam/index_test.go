@@ -0,0 +1,246 @@
+package iam
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/iam/iamiface"
+)
+
+type fakeIAMClient struct {
+	iamiface.IAMAPI
+	providers map[string]*iam.GetOpenIDConnectProviderOutput
+	// deletedAfterListing are listed, but can't be fetched anymore.
+	deletedAfterListing []string
+	// onGet is called before a provider is fetched.
+	onGet func(providerArn string)
+
+	// simulateErr is returned by SimulatePrincipalPolicyPages, which denies the simulateDenied actions.
+	simulateErr    error
+	simulateDenied []string
+
+	getCalls      []string
+	simulateCalls int
+}
+
+func (c *fakeIAMClient) ListOpenIDConnectProviders(*iam.ListOpenIDConnectProvidersInput) (*iam.ListOpenIDConnectProvidersOutput, error) {
+	output := &iam.ListOpenIDConnectProvidersOutput{}
+	for providerArn := range c.providers {
+		output.OpenIDConnectProviderList = append(output.OpenIDConnectProviderList, &iam.OpenIDConnectProviderListEntry{Arn: aws.String(providerArn)})
+	}
+	for _, providerArn := range c.deletedAfterListing {
+		output.OpenIDConnectProviderList = append(output.OpenIDConnectProviderList, &iam.OpenIDConnectProviderListEntry{Arn: aws.String(providerArn)})
+	}
+	return output, nil
+}
+
+func (c *fakeIAMClient) GetOpenIDConnectProvider(input *iam.GetOpenIDConnectProviderInput) (*iam.GetOpenIDConnectProviderOutput, error) {
+	providerArn := aws.StringValue(input.OpenIDConnectProviderArn)
+	c.getCalls = append(c.getCalls, providerArn)
+	if c.onGet != nil {
+		c.onGet(providerArn)
+	}
+
+	provider, ok := c.providers[providerArn]
+	if !ok {
+		return nil, awserr.New(iam.ErrCodeNoSuchEntityException, "not found", nil)
+	}
+	return provider, nil
+}
+
+func oidcProvider(url string) *iam.GetOpenIDConnectProviderOutput {
+	return &iam.GetOpenIDConnectProviderOutput{Url: aws.String(url)}
+}
+
+const (
+	providerArn1 = "arn:aws:iam::123456789012:oidc-provider/irsa.lbj23.example.com"
+	providerArn2 = "arn:aws:iam::123456789012:oidc-provider/irsa.lbj24.example.com"
+	providerArn3 = "arn:aws:iam::123456789012:oidc-provider/irsa.lbj25.example.com"
+)
+
+func newIndexTestService(client *fakeIAMClient) *Service {
+	return &Service{
+		scope:   newFakeScope(),
+		Client:  client,
+		roleARN: "arn:aws:iam::123456789012:role/test",
+	}
+}
+
+// listOIDCProviders lists the providers and returns their ARNs along with the fetched ones since the last call.
+func listOIDCProviders(t *testing.T, s *Service, client *fakeIAMClient) (listed []string, fetched []string) {
+	t.Helper()
+
+	client.getCalls = nil
+	providers, err := s.ListOIDCProviders()
+	if err != nil {
+		t.Fatalf("ListOIDCProviders() error = %v", err)
+	}
+
+	listed = []string{}
+	for providerArn, provider := range providers {
+		if provider != client.providers[providerArn] {
+			t.Errorf("ListOIDCProviders() returned outdated provider %s", providerArn)
+		}
+		listed = append(listed, providerArn)
+	}
+	sort.Strings(listed)
+
+	fetched = append([]string{}, client.getCalls...)
+	sort.Strings(fetched)
+
+	return listed, fetched
+}
+
+func Test_ListOIDCProviders_incremental(t *testing.T) {
+	client := &fakeIAMClient{
+		providers: map[string]*iam.GetOpenIDConnectProviderOutput{
+			providerArn1: oidcProvider("irsa.lbj23.example.com"),
+			providerArn2: oidcProvider("irsa.lbj24.example.com"),
+		},
+	}
+	s := newIndexTestService(client)
+
+	steps := []struct {
+		name        string
+		change      func()
+		wantListed  []string
+		wantFetched []string
+	}{
+		{
+			name:        "all providers are fetched initially",
+			wantListed:  []string{providerArn1, providerArn2},
+			wantFetched: []string{providerArn1, providerArn2},
+		},
+		{
+			name:        "indexed providers are not fetched again",
+			wantListed:  []string{providerArn1, providerArn2},
+			wantFetched: []string{},
+		},
+		{
+			name: "new providers are fetched",
+			change: func() {
+				client.providers[providerArn3] = oidcProvider("irsa.lbj25.example.com")
+			},
+			wantListed:  []string{providerArn1, providerArn2, providerArn3},
+			wantFetched: []string{providerArn3},
+		},
+		{
+			name: "deleted providers are dropped",
+			change: func() {
+				delete(client.providers, providerArn2)
+			},
+			wantListed:  []string{providerArn1, providerArn3},
+			wantFetched: []string{},
+		},
+		{
+			name: "providers deleted after the listing are skipped",
+			change: func() {
+				client.deletedAfterListing = []string{providerArn2}
+			},
+			wantListed:  []string{providerArn1, providerArn3},
+			wantFetched: []string{providerArn2},
+		},
+		{
+			name: "invalidated providers are fetched again",
+			change: func() {
+				client.deletedAfterListing = nil
+				client.providers[providerArn1] = oidcProvider("irsa.lbj23.example.com")
+				s.invalidateOIDCProvider(providerArn1)
+			},
+			wantListed:  []string{providerArn1, providerArn3},
+			wantFetched: []string{providerArn1},
+		},
+		{
+			name: "outdated providers are fetched again",
+			change: func() {
+				index, err := s.oidcProviderIndex()
+				if err != nil {
+					t.Fatal(err)
+				}
+				index.providers[providerArn3].fetchedAt = time.Now().Add(-oidcProviderMaxAge)
+			},
+			wantListed:  []string{providerArn1, providerArn3},
+			wantFetched: []string{providerArn3},
+		},
+	}
+	for _, step := range steps {
+		if step.change != nil {
+			step.change()
+		}
+
+		listed, fetched := listOIDCProviders(t, s, client)
+		if !reflect.DeepEqual(listed, step.wantListed) {
+			t.Errorf("%s: ListOIDCProviders() listed %v, want %v", step.name, listed, step.wantListed)
+		}
+		if !reflect.DeepEqual(fetched, step.wantFetched) {
+			t.Errorf("%s: ListOIDCProviders() fetched %v, want %v", step.name, fetched, step.wantFetched)
+		}
+	}
+}
+
+func Test_ListOIDCProviders_invalidatedWhileFetching(t *testing.T) {
+	client := &fakeIAMClient{
+		providers: map[string]*iam.GetOpenIDConnectProviderOutput{
+			providerArn1: oidcProvider("irsa.lbj23.example.com"),
+			providerArn2: oidcProvider("irsa.lbj24.example.com"),
+		},
+	}
+	s := newIndexTestService(client)
+
+	// Another reconciliation changes the provider while this one fetches it, so the fetched version is outdated.
+	client.onGet = func(providerArn string) {
+		if providerArn == providerArn1 {
+			s.invalidateOIDCProvider(providerArn1)
+		}
+	}
+	listed, fetched := listOIDCProviders(t, s, client)
+	if !reflect.DeepEqual(listed, []string{providerArn1, providerArn2}) || !reflect.DeepEqual(fetched, []string{providerArn1, providerArn2}) {
+		t.Fatalf("ListOIDCProviders() listed %v and fetched %v, want both providers", listed, fetched)
+	}
+
+	client.onGet = nil
+	_, fetched = listOIDCProviders(t, s, client)
+	if !reflect.DeepEqual(fetched, []string{providerArn1}) {
+		t.Errorf("ListOIDCProviders() fetched %v, want the provider changed while it was fetched", fetched)
+	}
+
+	_, fetched = listOIDCProviders(t, s, client)
+	if len(fetched) != 0 {
+		t.Errorf("ListOIDCProviders() fetched %v, want none", fetched)
+	}
+}
+
+func Test_oidcProviderIndex_perAccount(t *testing.T) {
+	s := newIndexTestService(&fakeIAMClient{})
+	other := &Service{scope: s.scope, roleARN: "arn:aws:iam::210987654321:role/test"}
+
+	index, err := s.oidcProviderIndex()
+	if err != nil {
+		t.Fatalf("oidcProviderIndex() error = %v", err)
+	}
+	sameIndex, err := newIndexTestService(&fakeIAMClient{}).oidcProviderIndex()
+	if err != nil {
+		t.Fatalf("oidcProviderIndex() error = %v", err)
+	}
+	otherIndex, err := other.oidcProviderIndex()
+	if err != nil {
+		t.Fatalf("oidcProviderIndex() error = %v", err)
+	}
+
+	if index == otherIndex {
+		t.Errorf("oidcProviderIndex() returned the same index for different accounts")
+	}
+	if sameIndex == index {
+		t.Errorf("oidcProviderIndex() shared the index across caches")
+	}
+
+	s.roleARN = "invalid"
+	if _, err := s.oidcProviderIndex(); err == nil {
+		t.Errorf("oidcProviderIndex() expected error for invalid role ARN")
+	}
+}
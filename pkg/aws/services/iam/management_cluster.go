@@ -0,0 +1,121 @@
+package iam
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/irsa-operator/pkg/key"
+	"github.com/giantswarm/irsa-operator/pkg/util"
+)
+
+// EnsureManagementClusterOIDCProvider makes sure the OIDC provider of the management cluster exists in the account
+// of the workload cluster, so that roles in it can trust service accounts of the management cluster. The provider is
+// shared by all clusters of the installation in the account, so client IDs of other clusters are kept and customer
+// tags of a single cluster are not applied.
+func (s *Service) EnsureManagementClusterOIDCProvider(identityProviderURL string, clientIDs []string) error {
+	all, err := s.ListOIDCProviders()
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	providers := filterOIDCProvidersByCluster(all, s.scope.Installation(), s.scope.Installation())
+	if len(providers) == 0 {
+		for _, existing := range all {
+			if util.EnsureHTTPS(aws.StringValue(existing.Url)) == util.EnsureHTTPS(identityProviderURL) {
+				s.scope.Logger().Info("MC OIDC provider already exists but is not managed by the operator, skipping", "identityProviderURL", identityProviderURL)
+				return nil
+			}
+		}
+	}
+
+	// The thumbprint mode of a single cluster doesn't apply to the shared provider either.
+	thumbprints, err := s.thumbprints([]string{identityProviderURL}, key.ThumbprintModeRoot)
+	if err != nil {
+		return err
+	}
+
+	desiredTags := []*iam.Tag{
+		{
+			Key:   aws.String(key.S3TagInstallation),
+			Value: aws.String(s.scope.Installation()),
+		},
+		{
+			Key:   aws.String(key.S3TagCluster),
+			Value: aws.String(s.scope.Installation()),
+		},
+	}
+
+	return s.ensureOIDCProvider(providers, identityProviderURL, thumbprints, uniqueClientIDs(clientIDs), key.UnknownAudiencesKeep, desiredTags)
+}
+
+// DeleteManagementClusterOIDCProviderIfUnused deletes the OIDC provider of the management cluster from the account
+// of the workload cluster once no other cluster of the installation has OIDC providers in the account anymore. It has
+// to be called after the providers of the deleted cluster are gone.
+func (s *Service) DeleteManagementClusterOIDCProviderIfUnused() error {
+	all, err := s.ListOIDCProviders()
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	if users := oidcProviderUsers(all, s.scope.Installation()); len(users) > 0 {
+		s.scope.Logger().Info(fmt.Sprintf("MC OIDC provider is still used by %d clusters, skipping deletion", len(users)), "clusters", users)
+		return nil
+	}
+
+	for providerArn := range filterOIDCProvidersByCluster(all, s.scope.Installation(), s.scope.Installation()) {
+		err = s.DeleteOIDCProvider(providerArn, s.scope.Logger())
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// filterOIDCProvidersByCluster returns the providers tagged with the installation and cluster.
+func filterOIDCProvidersByCluster(providers map[string]*iam.GetOpenIDConnectProviderOutput, installation, clusterName string) map[string]*iam.GetOpenIDConnectProviderOutput {
+	ret := make(map[string]*iam.GetOpenIDConnectProviderOutput)
+	for providerArn, p := range providers {
+		if tagValue(p.Tags, key.S3TagInstallation) == installation && tagValue(p.Tags, key.S3TagCluster) == clusterName {
+			ret[providerArn] = p
+		}
+	}
+
+	return ret
+}
+
+// oidcProviderUsers returns the sorted names of the workload clusters of the installation that have OIDC providers,
+// which is what keeps the provider of the management cluster in use.
+func oidcProviderUsers(providers map[string]*iam.GetOpenIDConnectProviderOutput, installation string) []string {
+	seen := make(map[string]bool)
+	users := make([]string, 0)
+	for _, p := range providers {
+		if tagValue(p.Tags, key.S3TagInstallation) != installation {
+			continue
+		}
+
+		clusterName := tagValue(p.Tags, key.S3TagCluster)
+		if clusterName == "" || clusterName == installation || seen[clusterName] {
+			continue
+		}
+		seen[clusterName] = true
+		users = append(users, clusterName)
+	}
+	sort.Strings(users)
+
+	return users
+}
+
+func tagValue(tags []*iam.Tag, tagKey string) string {
+	for _, tag := range tags {
+		if aws.StringValue(tag.Key) == tagKey {
+			return aws.StringValue(tag.Value)
+		}
+	}
+
+	return ""
+}
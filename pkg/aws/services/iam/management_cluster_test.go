@@ -0,0 +1,68 @@
+package iam
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/iam"
+
+	"github.com/giantswarm/irsa-operator/pkg/key"
+)
+
+func testProvider(installation, clusterName string) *iam.GetOpenIDConnectProviderOutput {
+	return &iam.GetOpenIDConnectProviderOutput{
+		Tags: []*iam.Tag{
+			{Key: aws.String(key.S3TagInstallation), Value: aws.String(installation)},
+			{Key: aws.String(key.S3TagCluster), Value: aws.String(clusterName)},
+		},
+	}
+}
+
+func Test_oidcProviderUsers(t *testing.T) {
+	tests := []struct {
+		name      string
+		providers map[string]*iam.GetOpenIDConnectProviderOutput
+		want      []string
+	}{
+		{
+			name: "only the MC provider",
+			providers: map[string]*iam.GetOpenIDConnectProviderOutput{
+				"mc": testProvider("golem", "golem"),
+			},
+			want: []string{},
+		},
+		{
+			name: "clusters of the installation",
+			providers: map[string]*iam.GetOpenIDConnectProviderOutput{
+				"mc":          testProvider("golem", "golem"),
+				"b-alias":     testProvider("golem", "b"),
+				"b-cf":        testProvider("golem", "b"),
+				"a-alias":     testProvider("golem", "a"),
+				"other":       testProvider("grizzly", "c"),
+				"not-managed": {},
+			},
+			want: []string{"a", "b"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := oidcProviderUsers(tt.providers, "golem"); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("oidcProviderUsers() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_filterOIDCProvidersByCluster(t *testing.T) {
+	providers := map[string]*iam.GetOpenIDConnectProviderOutput{
+		"mc":    testProvider("golem", "golem"),
+		"a":     testProvider("golem", "a"),
+		"other": testProvider("grizzly", "golem"),
+	}
+
+	got := filterOIDCProvidersByCluster(providers, "golem", "golem")
+	if len(got) != 1 || got["mc"] == nil {
+		t.Errorf("filterOIDCProvidersByCluster() = %v, want only the MC provider", got)
+	}
+}
@@ -1,14 +1,8 @@
 package iam
 
 import (
-	"bytes"
-	"crypto/sha1" //nolint:gosec
 	"fmt"
-	"net/http"
-	"net/url"
-	"os"
 	"strings"
-	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
@@ -16,39 +10,29 @@ import (
 	"github.com/blang/semver"
 	"github.com/giantswarm/microerror"
 	"github.com/go-logr/logr"
-	"github.com/pkg/errors"
+	gocache "github.com/patrickmn/go-cache"
 
 	"github.com/giantswarm/irsa-operator/pkg/key"
 	"github.com/giantswarm/irsa-operator/pkg/util"
+	"github.com/giantswarm/irsa-operator/pkg/util/record"
 	"github.com/giantswarm/irsa-operator/pkg/util/slicediff"
 	"github.com/giantswarm/irsa-operator/pkg/util/tagsdiff"
 )
 
-func (s *Service) EnsureOIDCProviders(identityProviderURLs []string, identityProviderURLsToDelete []string, clientID string, customerTags map[string]string) error {
-	providers, err := s.findOIDCProviders()
+// EnsureOIDCProviders makes sure there is an OIDC provider with the given client IDs (audiences) for each of the
+// identity provider URLs. Other client IDs found on the providers are handled according to the unknown audiences
+// policy of the cluster.
+func (s *Service) EnsureOIDCProviders(identityProviderURLs []string, identityProviderURLsToDelete []string, clientIDs []string, customerTags map[string]string) error {
+	clientIDs = uniqueClientIDs(clientIDs)
+
+	providers, err := s.findOIDCProviders(s.scope.ClusterName())
 	if err != nil {
 		return microerror.Mask(err)
 	}
 
-	thumbprints := make([]*string, 0)
-	thumbprintsSeen := make(map[string]bool)
-	for _, identityProviderURL := range identityProviderURLs {
-		tps, err := s.caThumbPrints(identityProviderURL)
-		if err != nil {
-			return err
-		}
-
-		// avoid duplicates
-		for _, tp := range tps {
-			// Avoid pointer aliasing in Go <1.22 by creating a loop-scoped variable. Also ensure same case so we don't
-			// get such duplicates.
-			tp := strings.ToLower(tp)
-
-			if _, seen := thumbprintsSeen[tp]; !seen {
-				thumbprints = append(thumbprints, &tp)
-				thumbprintsSeen[tp] = true
-			}
-		}
+	thumbprints, err := s.thumbprints(identityProviderURLs, s.scope.ThumbprintMode())
+	if err != nil {
+		return err
 	}
 
 	// Ensure there is one provider for each of the URLs
@@ -92,123 +76,207 @@ func (s *Service) EnsureOIDCProviders(identityProviderURLs []string, identityPro
 
 		desiredTags = util.FilterUniqueTags(desiredTags)
 
-		// Check if one of the providers is already using the right URL.
-		found := false
+		err = s.ensureOIDCProvider(providers, identityProviderURL, thumbprints, clientIDs, s.scope.UnknownAudiencesPolicy(), desiredTags)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, identityProviderURLToDelete := range identityProviderURLsToDelete {
+		logger := s.scope.Logger().WithValues("identityProviderURLToDelete", identityProviderURLToDelete)
+		foundProviderArn := ""
 		for arn, existing := range providers {
-			if util.EnsureHTTPS(*existing.Url) == util.EnsureHTTPS(identityProviderURL) {
-				found = true
-				thumbprintsDiff := slicediff.DiffIgnoreCase(existing.ThumbprintList, thumbprints)
-				clientidsDiff := slicediff.DiffIgnoreCase(existing.ClientIDList, []*string{&clientID})
-				tagsDiff := tagsdiff.Diff(existing.Tags, desiredTags)
-
-				for _, add := range clientidsDiff.Added {
-					s.scope.Logger().Info(fmt.Sprintf("Adding client id %s to OIDCProvider for URL %s", add, identityProviderURL))
-					_, err = s.Client.AddClientIDToOpenIDConnectProvider(&iam.AddClientIDToOpenIDConnectProviderInput{
-						ClientID:                 aws.String(add),
+			if util.EnsureHTTPS(*existing.Url) == util.EnsureHTTPS(identityProviderURLToDelete) {
+				foundProviderArn = arn
+				break
+			}
+		}
+
+		if foundProviderArn == "" {
+			logger.Info("OIDC provider for this URL does not exist, no need to delete")
+			continue
+		}
+		err = s.DeleteOIDCProvider(foundProviderArn, logger)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ensureOIDCProvider creates the OIDC provider for the URL or updates the one found in providers to have the given
+// thumbprints, client IDs and tags. Nil thumbprints leave the thumbprints of an existing provider untouched.
+func (s *Service) ensureOIDCProvider(providers map[string]*iam.GetOpenIDConnectProviderOutput, identityProviderURL string, thumbprints []*string, clientIDs []string, unknownAudiencesPolicy string, desiredTags []*iam.Tag) error {
+	// Check if one of the providers is already using the right URL.
+	for arn, existing := range providers {
+		if util.EnsureHTTPS(*existing.Url) == util.EnsureHTTPS(identityProviderURL) {
+			thumbprintsDiff := slicediff.DiffIgnoreCase(existing.ThumbprintList, thumbprints)
+			addedClientIDs, unknownClientIDs := diffClientIDs(existing.ClientIDList, clientIDs)
+			tagsDiff := tagsdiff.Diff(existing.Tags, desiredTags)
+
+			for _, add := range addedClientIDs {
+				s.scope.Logger().Info(fmt.Sprintf("Adding client id %s to OIDCProvider for URL %s", add, identityProviderURL))
+				_, err := s.Client.AddClientIDToOpenIDConnectProvider(&iam.AddClientIDToOpenIDConnectProviderInput{
+					ClientID:                 aws.String(add),
+					OpenIDConnectProviderArn: &arn,
+				})
+				s.invalidateOIDCProvider(arn)
+				if err != nil {
+					return microerror.Mask(err)
+				}
+				s.scope.Logger().Info(fmt.Sprintf("Added client id %s to OIDCProvider for URL %s", add, identityProviderURL))
+			}
+			if unknownAudiencesPolicy == key.UnknownAudiencesWarn {
+				s.warnUnknownClientIDs(arn, identityProviderURL, unknownClientIDs)
+			} else {
+				s.scope.Cache().Delete(unknownClientIDsCacheKey(arn))
+			}
+			for _, remove := range unknownClientIDs {
+				if unknownAudiencesPolicy != key.UnknownAudiencesRemove {
+					s.scope.Logger().Info(fmt.Sprintf("Keeping unknown client id %s on OIDCProvider for URL %s", remove, identityProviderURL))
+					continue
+				}
+
+				s.scope.Logger().Info(fmt.Sprintf("Removing client id %s to OIDCProvider for URL %s", remove, identityProviderURL))
+				_, err := s.Client.RemoveClientIDFromOpenIDConnectProvider(&iam.RemoveClientIDFromOpenIDConnectProviderInput{
+					ClientID:                 aws.String(remove),
+					OpenIDConnectProviderArn: &arn,
+				})
+				s.invalidateOIDCProvider(arn)
+				if err != nil {
+					return microerror.Mask(err)
+				}
+				s.scope.Logger().Info(fmt.Sprintf("Removed client id %s to OIDCProvider for URL %s", remove, identityProviderURL))
+			}
+
+			if thumbprints != nil && thumbprintsDiff.Changed() {
+				s.scope.Logger().Info(fmt.Sprintf("Updating thumbprints on OIDCProvider for URL %s", identityProviderURL))
+				_, err := s.Client.UpdateOpenIDConnectProviderThumbprint(&iam.UpdateOpenIDConnectProviderThumbprintInput{
+					OpenIDConnectProviderArn: &arn,
+					ThumbprintList:           thumbprints,
+				})
+				s.invalidateOIDCProvider(arn)
+				if err != nil {
+					return microerror.Mask(err)
+				}
+				s.scope.Logger().Info(fmt.Sprintf("Updated thumbprints on OIDCProvider for URL %s", identityProviderURL))
+
+			} else {
+				s.scope.Logger().Info(fmt.Sprintf("OIDCProvider for URL %s already exists and is up to date", identityProviderURL))
+			}
+
+			if tagsDiff.Changed {
+				if len(tagsDiff.Added) > 0 {
+					s.scope.Logger().Info(fmt.Sprintf("Updating tags on OIDCProvider for URL %s to add %v", identityProviderURL, tagsDiff.Added))
+					_, err := s.Client.TagOpenIDConnectProvider(&iam.TagOpenIDConnectProviderInput{
 						OpenIDConnectProviderArn: &arn,
+						Tags:                     desiredTags,
 					})
+					s.invalidateOIDCProvider(arn)
 					if err != nil {
 						return microerror.Mask(err)
 					}
-					s.scope.Logger().Info(fmt.Sprintf("Added client id %s to OIDCProvider for URL %s", add, identityProviderURL))
+					s.scope.Logger().Info(fmt.Sprintf("Updated tags on OIDCProvider for URL %s", identityProviderURL))
 				}
-				for _, remove := range clientidsDiff.Removed {
-					s.scope.Logger().Info(fmt.Sprintf("Removing client id %s to OIDCProvider for URL %s", remove, identityProviderURL))
-					_, err = s.Client.RemoveClientIDFromOpenIDConnectProvider(&iam.RemoveClientIDFromOpenIDConnectProviderInput{
-						ClientID:                 aws.String(remove),
+				if len(tagsDiff.Removed) > 0 {
+					s.scope.Logger().Info(fmt.Sprintf("Removing %d undesired tags on OIDCProvider for URL %s", len(tagsDiff.Removed), identityProviderURL))
+					_, err := s.Client.UntagOpenIDConnectProvider(&iam.UntagOpenIDConnectProviderInput{
 						OpenIDConnectProviderArn: &arn,
+						TagKeys:                  tagsDiff.Removed,
 					})
+					s.invalidateOIDCProvider(arn)
 					if err != nil {
 						return microerror.Mask(err)
 					}
-					s.scope.Logger().Info(fmt.Sprintf("Removed client id %s to OIDCProvider for URL %s", remove, identityProviderURL))
+					s.scope.Logger().Info(fmt.Sprintf("Removed undesired tags on OIDCProvider for URL %s", identityProviderURL))
 				}
+			}
 
-				if thumbprintsDiff.Changed() {
-					s.scope.Logger().Info(fmt.Sprintf("Updating thumbprints on OIDCProvider for URL %s", identityProviderURL))
-					_, err := s.Client.UpdateOpenIDConnectProviderThumbprint(&iam.UpdateOpenIDConnectProviderThumbprintInput{
-						OpenIDConnectProviderArn: &arn,
-						ThumbprintList:           thumbprints,
-					})
-					if err != nil {
-						return microerror.Mask(err)
-					}
-					s.scope.Logger().Info(fmt.Sprintf("Updated thumbprints on OIDCProvider for URL %s", identityProviderURL))
+			return nil
+		}
+	}
 
-				} else {
-					s.scope.Logger().Info(fmt.Sprintf("OIDCProvider for URL %s already exists and is up to date", identityProviderURL))
-				}
+	s.scope.Logger().Info(fmt.Sprintf("Creating OIDCProvider for URL %s", identityProviderURL))
 
-				if tagsDiff.Changed {
-					if len(tagsDiff.Added) > 0 {
-						s.scope.Logger().Info(fmt.Sprintf("Updating tags on OIDCProvider for URL %s to add %v", identityProviderURL, tagsDiff.Added))
-						_, err := s.Client.TagOpenIDConnectProvider(&iam.TagOpenIDConnectProviderInput{
-							OpenIDConnectProviderArn: &arn,
-							Tags:                     desiredTags,
-						})
-						if err != nil {
-							return microerror.Mask(err)
-						}
-						s.scope.Logger().Info(fmt.Sprintf("Updated tags on OIDCProvider for URL %s", identityProviderURL))
-					}
-					if len(tagsDiff.Removed) > 0 {
-						s.scope.Logger().Info(fmt.Sprintf("Removing %d undesired tags on OIDCProvider for URL %s", len(tagsDiff.Removed), identityProviderURL))
-						_, err := s.Client.UntagOpenIDConnectProvider(&iam.UntagOpenIDConnectProviderInput{
-							OpenIDConnectProviderArn: &arn,
-							TagKeys:                  tagsDiff.Removed,
-						})
-						if err != nil {
-							return microerror.Mask(err)
-						}
-						s.scope.Logger().Info(fmt.Sprintf("Removed undesired tags on OIDCProvider for URL %s", identityProviderURL))
-					}
-				}
+	i := &iam.CreateOpenIDConnectProviderInput{
+		Url:            aws.String(identityProviderURL),
+		ThumbprintList: thumbprints,
+		ClientIDList:   aws.StringSlice(clientIDs),
+		Tags:           desiredTags,
+	}
 
-				break
-			}
-		}
+	_, err := s.Client.CreateOpenIDConnectProvider(i)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	s.scope.Logger().Info(fmt.Sprintf("Created OIDC provider for URL %s", identityProviderURL))
+
+	return nil
+}
 
-		if found {
+// warnUnknownClientIDs emits a warning event for each unknown client ID of the provider that was not known in the
+// previous reconciliation, so that the same client IDs are not reported over and over again.
+func (s *Service) warnUnknownClientIDs(arn, identityProviderURL string, unknownClientIDs []string) {
+	cacheKey := unknownClientIDsCacheKey(arn)
+
+	previous := map[string]bool{}
+	if cachedValue, ok := s.scope.Cache().Get(cacheKey); ok {
+		previous = cachedValue.(map[string]bool)
+	}
+
+	current := make(map[string]bool, len(unknownClientIDs))
+	for _, clientID := range unknownClientIDs {
+		current[clientID] = true
+		if previous[clientID] {
 			continue
 		}
+		record.Warnf(s.scope.Cluster(), "UnknownOIDCAudience", "OIDC provider for URL %s has client id %s that is not configured in annotation %s", identityProviderURL, clientID, key.IRSAAdditionalAudiencesAnnotation)
+	}
 
-		s.scope.Logger().Info(fmt.Sprintf("Creating OIDCProvider for URL %s", identityProviderURL))
+	s.scope.Cache().Set(cacheKey, current, gocache.NoExpiration)
+}
 
-		i := &iam.CreateOpenIDConnectProviderInput{
-			Url:            aws.String(identityProviderURL),
-			ThumbprintList: thumbprints,
-			ClientIDList:   []*string{aws.String(clientID)},
-			Tags:           desiredTags,
-		}
+func unknownClientIDsCacheKey(arn string) string {
+	return fmt.Sprintf("iam/oidc-provider=%q/unknown-client-ids", arn)
+}
 
-		_, err = s.Client.CreateOpenIDConnectProvider(i)
-		if err != nil {
-			return microerror.Mask(err)
+// uniqueClientIDs drops duplicate client IDs, keeping the order.
+func uniqueClientIDs(clientIDs []string) []string {
+	unique := make([]string, 0, len(clientIDs))
+	seen := make(map[string]bool, len(clientIDs))
+	for _, clientID := range clientIDs {
+		if !seen[strings.ToLower(clientID)] {
+			seen[strings.ToLower(clientID)] = true
+			unique = append(unique, clientID)
 		}
-		s.scope.Logger().Info(fmt.Sprintf("Created OIDC provider for URL %s", identityProviderURL))
 	}
 
-	for _, identityProviderURLToDelete := range identityProviderURLsToDelete {
-		logger := s.scope.Logger().WithValues("identityProviderURLToDelete", identityProviderURLToDelete)
-		foundProviderArn := ""
-		for arn, existing := range providers {
-			if util.EnsureHTTPS(*existing.Url) == util.EnsureHTTPS(identityProviderURLToDelete) {
-				foundProviderArn = arn
-				break
-			}
-		}
+	return unique
+}
 
-		if foundProviderArn == "" {
-			logger.Info("OIDC provider for this URL does not exist, no need to delete")
-			continue
+// diffClientIDs returns the desired client IDs missing on the provider and the ones it has that are not desired.
+// Client IDs are compared ignoring case, like the operator always did, so that providers with client IDs in a
+// different case are not changed. The returned client IDs keep their case.
+func diffClientIDs(existing []*string, desired []string) (added []string, unknown []string) {
+	existingSet := make(map[string]bool, len(existing))
+	for _, clientID := range existing {
+		existingSet[strings.ToLower(aws.StringValue(clientID))] = true
+	}
+	desiredSet := make(map[string]bool, len(desired))
+	for _, clientID := range desired {
+		desiredSet[strings.ToLower(clientID)] = true
+		if !existingSet[strings.ToLower(clientID)] {
+			added = append(added, clientID)
 		}
-		err = s.deleteOIDCProvider(foundProviderArn, logger)
-		if err != nil {
-			return err
+	}
+	for _, clientID := range existing {
+		if !desiredSet[strings.ToLower(aws.StringValue(clientID))] {
+			unknown = append(unknown, aws.StringValue(clientID))
 		}
 	}
 
-	return nil
+	return added, unknown
 }
 
 func (s *Service) internalTags() map[string]string {
@@ -220,39 +288,15 @@ func (s *Service) internalTags() map[string]string {
 	}
 }
 
-func (s *Service) findOIDCProviders() (map[string]*iam.GetOpenIDConnectProviderOutput, error) {
+// findOIDCProviders returns the OIDC providers of the account tagged with the installation and the given cluster.
+func (s *Service) findOIDCProviders(clusterName string) (map[string]*iam.GetOpenIDConnectProviderOutput, error) {
 	s.scope.Logger().Info("Looking for existing OIDC providers")
-	output, err := s.Client.ListOpenIDConnectProviders(&iam.ListOpenIDConnectProvidersInput{})
+	providers, err := s.ListOIDCProviders()
 	if err != nil {
 		return nil, microerror.Mask(err)
 	}
 
-	ret := make(map[string]*iam.GetOpenIDConnectProviderOutput, 0)
-
-	for _, providerArn := range output.OpenIDConnectProviderList {
-		p, err := s.Client.GetOpenIDConnectProvider(&iam.GetOpenIDConnectProviderInput{
-			OpenIDConnectProviderArn: providerArn.Arn,
-		})
-		if err != nil {
-			return nil, microerror.Mask(err)
-		}
-
-		// Check if tags match
-		installationTagFound := false
-		clusterTagFound := false
-		for _, tag := range p.Tags {
-			if *tag.Key == key.S3TagInstallation && *tag.Value == s.scope.Installation() {
-				installationTagFound = true
-			}
-			if *tag.Key == key.S3TagCluster && *tag.Value == s.scope.ClusterName() {
-				clusterTagFound = true
-			}
-		}
-
-		if installationTagFound && clusterTagFound {
-			ret[*providerArn.Arn] = p
-		}
-	}
+	ret := filterOIDCProvidersByCluster(providers, s.scope.Installation(), clusterName)
 
 	if len(ret) == 0 {
 		s.scope.Logger().Info("Did not find any OIDC provider")
@@ -290,7 +334,9 @@ func (s *Service) ListCustomerOIDCTags(release *semver.Version, cfDomain, accoun
 	return oidcTags, nil
 }
 
-func (s *Service) deleteOIDCProvider(providerArn string, logger logr.Logger) error {
+// DeleteOIDCProvider deletes the OIDC provider and removes it from the index of the account. Providers that don't
+// exist anymore are skipped.
+func (s *Service) DeleteOIDCProvider(providerArn string, logger logr.Logger) error {
 	logger = logger.WithValues("providerArn", providerArn)
 
 	i := &iam.DeleteOpenIDConnectProviderInput{
@@ -298,6 +344,7 @@ func (s *Service) deleteOIDCProvider(providerArn string, logger logr.Logger) err
 	}
 
 	_, err := s.Client.DeleteOpenIDConnectProvider(i)
+	s.invalidateOIDCProvider(providerArn)
 	if err != nil {
 		if aerr, ok := err.(awserr.Error); ok {
 			switch aerr.Code() {
@@ -313,13 +360,13 @@ func (s *Service) deleteOIDCProvider(providerArn string, logger logr.Logger) err
 }
 
 func (s *Service) DeleteOIDCProviders() error {
-	providers, err := s.findOIDCProviders()
+	providers, err := s.findOIDCProviders(s.scope.ClusterName())
 	if err != nil {
 		return microerror.Mask(err)
 	}
 
 	for providerArn := range providers {
-		err := s.deleteOIDCProvider(providerArn, s.scope.Logger())
+		err := s.DeleteOIDCProvider(providerArn, s.scope.Logger())
 		if err != nil {
 			return err
 		}
@@ -327,49 +374,3 @@ func (s *Service) DeleteOIDCProviders() error {
 
 	return nil
 }
-
-func (s *Service) caThumbPrints(ep string) ([]string, error) {
-	ret := make([]string, 0)
-
-	// Root CA certificate.
-	{
-		client := &http.Client{
-			Timeout: time.Second * 10,
-		}
-
-		// check PROXY env
-		if v, ok := os.LookupEnv("HTTPS_PROXY"); ok {
-			proxy, err := url.Parse(v)
-			if err != nil {
-				return nil, microerror.Mask(err)
-			}
-			client.Transport = &http.Transport{Proxy: http.ProxyURL(proxy)}
-		}
-
-		resp, err := client.Get(ep)
-		if err != nil {
-			return nil, microerror.Mask(errors.Wrapf(err, "failed to get %s", ep))
-		}
-		defer func() {
-			if err := resp.Body.Close(); err != nil {
-				s.scope.Logger().Error(err, "failed to close response body", "ep", ep)
-			}
-		}()
-
-		var fingerprint [20]byte
-		// Get the latest Root CA from Certificate Chain
-		for _, peers := range resp.TLS.PeerCertificates {
-			if peers.IsCA {
-				fingerprint = sha1.Sum(peers.Raw) //nolint:gosec
-			}
-		}
-
-		var buf bytes.Buffer
-		for _, f := range fingerprint {
-			fmt.Fprintf(&buf, "%02X", f)
-		}
-		ret = append(ret, strings.ToLower(buf.String()))
-	}
-
-	return ret, nil
-}
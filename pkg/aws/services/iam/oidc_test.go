@@ -0,0 +1,119 @@
+package iam
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/go-logr/logr"
+	gocache "github.com/patrickmn/go-cache"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8srecord "k8s.io/client-go/tools/record"
+
+	"github.com/giantswarm/irsa-operator/pkg/aws/scope"
+	"github.com/giantswarm/irsa-operator/pkg/util/record"
+)
+
+func Test_diffClientIDs(t *testing.T) {
+	tests := []struct {
+		name        string
+		existing    []string
+		desired     []string
+		wantAdded   []string
+		wantUnknown []string
+	}{
+		{
+			name:     "up to date",
+			existing: []string{"sts.amazonaws.com", "vault"},
+			desired:  []string{"sts.amazonaws.com", "vault"},
+		},
+		{
+			name:      "additional audience",
+			existing:  []string{"sts.amazonaws.com"},
+			desired:   []string{"sts.amazonaws.com", "vault"},
+			wantAdded: []string{"vault"},
+		},
+		{
+			name:        "unknown audience",
+			existing:    []string{"sts.amazonaws.com", "vault"},
+			desired:     []string{"sts.amazonaws.com"},
+			wantUnknown: []string{"vault"},
+		},
+		{
+			name:     "case-insensitive",
+			existing: []string{"sts.amazonaws.com", "Vault"},
+			desired:  []string{"sts.amazonaws.com", "vault"},
+		},
+		{
+			name:        "case is kept",
+			existing:    []string{"sts.amazonaws.com", "Vault"},
+			desired:     []string{"sts.amazonaws.com", "Boundary"},
+			wantAdded:   []string{"Boundary"},
+			wantUnknown: []string{"Vault"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			added, unknown := diffClientIDs(aws.StringSlice(tt.existing), tt.desired)
+			if !reflect.DeepEqual(added, tt.wantAdded) {
+				t.Errorf("diffClientIDs() added = %v, want %v", added, tt.wantAdded)
+			}
+			if !reflect.DeepEqual(unknown, tt.wantUnknown) {
+				t.Errorf("diffClientIDs() unknown = %v, want %v", unknown, tt.wantUnknown)
+			}
+		})
+	}
+}
+
+func Test_uniqueClientIDs(t *testing.T) {
+	got := uniqueClientIDs([]string{"sts.amazonaws.com", "vault", "sts.amazonaws.com", "Vault"})
+	want := []string{"sts.amazonaws.com", "vault"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("uniqueClientIDs() = %v, want %v", got, want)
+	}
+}
+
+type fakeScope struct {
+	scope.IAMScope
+	cache   *gocache.Cache
+	cluster runtime.Object
+}
+
+func newFakeScope() *fakeScope {
+	return &fakeScope{cache: gocache.New(gocache.NoExpiration, 0)}
+}
+
+func (s *fakeScope) Cache() *gocache.Cache   { return s.cache }
+func (s *fakeScope) Cluster() runtime.Object { return s.cluster }
+func (s *fakeScope) Logger() logr.Logger     { return logr.Discard() }
+
+func Test_warnUnknownClientIDs(t *testing.T) {
+	recorder := k8srecord.NewFakeRecorder(100)
+	record.InitFromRecorder(recorder)
+
+	// The steps run one after the other against the same cache, like consecutive reconciliations.
+	steps := []struct {
+		name       string
+		unknown    []string
+		wantEvents int
+	}{
+		{name: "no unknown client ids", unknown: nil, wantEvents: 0},
+		{name: "unknown client id", unknown: []string{"vault"}, wantEvents: 1},
+		{name: "same unknown client id", unknown: []string{"vault"}, wantEvents: 0},
+		{name: "another unknown client id", unknown: []string{"vault", "boundary"}, wantEvents: 1},
+		{name: "client id removed", unknown: []string{"boundary"}, wantEvents: 0},
+		{name: "client id back again", unknown: []string{"vault", "boundary"}, wantEvents: 1},
+	}
+
+	s := &Service{scope: newFakeScope()}
+	for _, step := range steps {
+		s.warnUnknownClientIDs("arn", "https://irsa.example.com", step.unknown)
+
+		if len(recorder.Events) != step.wantEvents {
+			t.Errorf("%s: warnUnknownClientIDs() emitted %d events, want %d", step.name, len(recorder.Events), step.wantEvents)
+		}
+		for len(recorder.Events) > 0 {
+			<-recorder.Events
+		}
+	}
+}
@@ -0,0 +1,237 @@
+package iam
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/giantswarm/microerror"
+	capi "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+
+	"github.com/giantswarm/irsa-operator/pkg/key"
+	"github.com/giantswarm/irsa-operator/pkg/util/record"
+)
+
+const preflightCacheExpiry = 15 * time.Minute
+
+var (
+	oidcProviderActions = []string{
+		"iam:AddClientIDToOpenIDConnectProvider",
+		"iam:CreateOpenIDConnectProvider",
+		"iam:DeleteOpenIDConnectProvider",
+		"iam:GetOpenIDConnectProvider",
+		"iam:ListOpenIDConnectProviderTags",
+		"iam:ListOpenIDConnectProviders",
+		"iam:RemoveClientIDFromOpenIDConnectProvider",
+		"iam:SimulatePrincipalPolicy",
+		"iam:TagOpenIDConnectProvider",
+		"iam:UntagOpenIDConnectProvider",
+		"iam:UpdateOpenIDConnectProviderThumbprint",
+	}
+	s3Actions = []string{
+		"s3:CreateBucket",
+		"s3:DeleteBucket",
+		"s3:DeleteObject",
+		"s3:GetBucketVersioning",
+		"s3:GetObject",
+		"s3:ListBucket",
+		"s3:PutBucketPolicy",
+		"s3:PutBucketPublicAccessBlock",
+		"s3:PutBucketTagging",
+		"s3:PutEncryptionConfiguration",
+		"s3:PutObject",
+	}
+	cloudFrontActions = []string{
+		"acm:AddTagsToCertificate",
+		"acm:DeleteCertificate",
+		"acm:DescribeCertificate",
+		"acm:ImportCertificate",
+		"acm:ListCertificates",
+		"acm:ListTagsForCertificate",
+		"acm:RequestCertificate",
+		"cloudfront:CreateCloudFrontOriginAccessIdentity",
+		"cloudfront:CreateDistribution",
+		"cloudfront:DeleteCloudFrontOriginAccessIdentity",
+		"cloudfront:DeleteDistribution",
+		"cloudfront:GetCloudFrontOriginAccessIdentity",
+		"cloudfront:GetDistribution",
+		"cloudfront:ListDistributions",
+		"cloudfront:ListTagsForResource",
+		"cloudfront:TagResource",
+		"cloudfront:UntagResource",
+		"cloudfront:UpdateDistribution",
+		// Replicas removed from the annotation are found through the replication configuration.
+		"s3:GetBucketLocation",
+		"s3:GetReplicationConfiguration",
+	}
+	route53Actions = []string{
+		"route53:ChangeResourceRecordSets",
+		"route53:GetChange",
+		"route53:ListHostedZonesByName",
+		"route53:ListResourceRecordSets",
+	}
+	replicationActions = []string{
+		"iam:CreateRole",
+		"iam:DeleteRole",
+		"iam:DeleteRolePolicy",
+		"iam:GetRole",
+		"iam:PassRole",
+		"iam:PutRolePolicy",
+		"s3:DeleteObjectVersion",
+		"s3:ListBucketVersions",
+		"s3:PutBucketVersioning",
+		"s3:PutReplicationConfiguration",
+	}
+	eksActions = []string{
+		"eks:DescribeCluster",
+	}
+	// PodIdentityActions are only needed for EKS clusters whose pod identity associations are managed.
+	PodIdentityActions = []string{
+		"eks:CreateAddon",
+		"eks:CreatePodIdentityAssociation",
+		"eks:DeletePodIdentityAssociation",
+		"eks:DescribeAddon",
+		"eks:DescribePodIdentityAssociation",
+		"eks:ListPodIdentityAssociations",
+		"eks:TagResource",
+		"eks:UpdatePodIdentityAssociation",
+		"iam:GetRole",
+		"iam:PassRole",
+	}
+)
+
+// RequiredActions returns the AWS actions the operator performs with the cluster role in the hosting mode. Route53
+// actions are only needed if the records are managed with the cluster role, replication actions only if the bucket
+// has a replica.
+func RequiredActions(hostingMode string, route53, replica bool) []string {
+	actions := append([]string{}, oidcProviderActions...)
+
+	switch hostingMode {
+	case key.HostingModeCloudFront:
+		actions = append(actions, s3Actions...)
+		actions = append(actions, cloudFrontActions...)
+		if route53 {
+			actions = append(actions, route53Actions...)
+		}
+		if replica {
+			actions = append(actions, replicationActions...)
+		}
+	case key.HostingModeS3:
+		actions = append(actions, s3Actions...)
+	case key.HostingModeEKS:
+		actions = append(actions, eksActions...)
+	}
+
+	sort.Strings(actions)
+
+	return actions
+}
+
+// AdditionalAccountActions returns the AWS actions the operator performs with the roles of additional accounts,
+// in which it only manages the cluster's OIDC providers.
+func AdditionalAccountActions() []string {
+	actions := append([]string{}, oidcProviderActions...)
+	sort.Strings(actions)
+
+	return actions
+}
+
+// Preflight simulates the actions for the cluster role, and AdditionalAccountActions for the roles of the additional
+// account services, and reports the denied ones as the IRSAPermissionsReady condition, if the cluster object has
+// conditions, and as warning event per role. The result is cached per role ARN. It returns the denied actions of the
+// cluster role.
+//
+// The simulation doesn't know the resources the operator will create, so policies restricting actions to specific
+// resources can show up as denied. Denied actions are therefore only reported and don't stop the reconciliation.
+func (s *Service) Preflight(actions []string, additionalAccounts ...*Service) ([]string, error) {
+	denied, err := s.deniedActions(actions)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	var messages []string
+	if len(denied) > 0 {
+		s.scope.Logger().Info("Cluster role is missing permissions", "roleARN", s.roleARN, "deniedActions", denied)
+		messages = append(messages, fmt.Sprintf("Role %s is not allowed to perform %s", s.roleARN, strings.Join(denied, ", ")))
+	}
+
+	for _, additionalAccount := range additionalAccounts {
+		additionalDenied, err := additionalAccount.deniedActions(AdditionalAccountActions())
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		if len(additionalDenied) > 0 {
+			s.scope.Logger().Info("Additional account role is missing permissions", "roleARN", additionalAccount.roleARN, "deniedActions", additionalDenied)
+			messages = append(messages, fmt.Sprintf("Role %s is not allowed to perform %s", additionalAccount.roleARN, strings.Join(additionalDenied, ", ")))
+		}
+	}
+
+	setter, hasConditions := s.scope.Cluster().(conditions.Setter)
+
+	if len(messages) == 0 {
+		if hasConditions {
+			conditions.MarkTrue(setter, key.IRSAPermissionsCondition)
+		}
+		return nil, nil
+	}
+
+	if hasConditions {
+		conditions.MarkFalse(setter, key.IRSAPermissionsCondition, "ActionsDenied", capi.ConditionSeverityWarning, "%s", strings.Join(messages, "; "))
+	}
+	for _, message := range messages {
+		record.Warn(s.scope.Cluster(), "MissingIAMPermissions", message)
+	}
+
+	return denied, nil
+}
+
+func (s *Service) deniedActions(actions []string) ([]string, error) {
+	sum := sha256.Sum256([]byte(strings.Join(actions, ",")))
+	cacheKey := fmt.Sprintf("iam/role=%q/denied-actions=%s", s.roleARN, hex.EncodeToString(sum[:])[:10])
+
+	if cachedValue, ok := s.scope.Cache().Get(cacheKey); ok {
+		return cachedValue.([]string), nil
+	}
+
+	results := make([]*iam.EvaluationResult, 0, len(actions))
+	err := s.Client.SimulatePrincipalPolicyPages(&iam.SimulatePrincipalPolicyInput{
+		ActionNames:     aws.StringSlice(actions),
+		PolicySourceArn: aws.String(s.roleARN),
+	}, func(page *iam.SimulatePolicyResponse, lastPage bool) bool {
+		results = append(results, page.EvaluationResults...)
+		return true
+	})
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == "AccessDenied" {
+		// Without the permission to simulate its own policies, the role can't be checked any further.
+		denied := []string{"iam:SimulatePrincipalPolicy"}
+		s.scope.Cache().Set(cacheKey, denied, preflightCacheExpiry)
+		return denied, nil
+	} else if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	denied := deniedActions(results)
+	s.scope.Cache().Set(cacheKey, denied, preflightCacheExpiry)
+
+	return denied, nil
+}
+
+// deniedActions returns the sorted actions of the evaluation results that are not allowed.
+func deniedActions(results []*iam.EvaluationResult) []string {
+	denied := make([]string, 0)
+	for _, result := range results {
+		if aws.StringValue(result.EvalDecision) != iam.PolicyEvaluationDecisionTypeAllowed {
+			denied = append(denied, aws.StringValue(result.EvalActionName))
+		}
+	}
+	sort.Strings(denied)
+
+	return denied
+}
@@ -0,0 +1,207 @@
+package iam
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/iam"
+	capa "sigs.k8s.io/cluster-api-provider-aws/v2/api/v1beta2"
+	"sigs.k8s.io/cluster-api/util/conditions"
+
+	"github.com/giantswarm/irsa-operator/pkg/key"
+)
+
+func Test_deniedActions(t *testing.T) {
+	results := []*iam.EvaluationResult{
+		{EvalActionName: aws.String("s3:PutObject"), EvalDecision: aws.String(iam.PolicyEvaluationDecisionTypeAllowed)},
+		{EvalActionName: aws.String("route53:GetChange"), EvalDecision: aws.String(iam.PolicyEvaluationDecisionTypeImplicitDeny)},
+		{EvalActionName: aws.String("acm:RequestCertificate"), EvalDecision: aws.String(iam.PolicyEvaluationDecisionTypeExplicitDeny)},
+	}
+
+	want := []string{"acm:RequestCertificate", "route53:GetChange"}
+	if got := deniedActions(results); !reflect.DeepEqual(got, want) {
+		t.Errorf("deniedActions() = %v, want %v", got, want)
+	}
+}
+
+func (c *fakeIAMClient) SimulatePrincipalPolicyPages(input *iam.SimulatePrincipalPolicyInput, fn func(*iam.SimulatePolicyResponse, bool) bool) error {
+	c.simulateCalls++
+	if c.simulateErr != nil {
+		return c.simulateErr
+	}
+
+	page := &iam.SimulatePolicyResponse{}
+	for _, action := range input.ActionNames {
+		decision := iam.PolicyEvaluationDecisionTypeAllowed
+		for _, denied := range c.simulateDenied {
+			if aws.StringValue(action) == denied {
+				decision = iam.PolicyEvaluationDecisionTypeImplicitDeny
+			}
+		}
+		page.EvaluationResults = append(page.EvaluationResults, &iam.EvaluationResult{
+			EvalActionName: action,
+			EvalDecision:   aws.String(decision),
+		})
+	}
+	fn(page, true)
+
+	return nil
+}
+
+func Test_Service_deniedActions_cache(t *testing.T) {
+	tests := []struct {
+		name        string
+		simulateErr error
+		want        []string
+	}{
+		{
+			name: "allowed",
+			want: []string{},
+		},
+		{
+			name:        "simulation denied",
+			simulateErr: awserr.New("AccessDenied", "not allowed", nil),
+			want:        []string{"iam:SimulatePrincipalPolicy"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &fakeIAMClient{simulateErr: tt.simulateErr}
+			s := newIndexTestService(client)
+
+			for i := 0; i < 2; i++ {
+				got, err := s.deniedActions([]string{"s3:PutObject"})
+				if err != nil {
+					t.Fatalf("deniedActions() error = %v", err)
+				}
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("deniedActions() = %v, want %v", got, tt.want)
+				}
+			}
+			if client.simulateCalls != 1 {
+				t.Errorf("deniedActions() simulated the policy %d times, want 1", client.simulateCalls)
+			}
+		})
+	}
+}
+
+func Test_Preflight(t *testing.T) {
+	tests := []struct {
+		name              string
+		clusterDenied     []string
+		additionalDenied  []string
+		wantDenied        []string
+		wantConditionTrue bool
+		wantMessage       string
+	}{
+		{
+			name:              "all allowed",
+			wantConditionTrue: true,
+		},
+		{
+			name:          "cluster role denied",
+			clusterDenied: []string{"s3:PutObject"},
+			wantDenied:    []string{"s3:PutObject"},
+			wantMessage:   "Role arn:aws:iam::123456789012:role/test is not allowed to perform s3:PutObject",
+		},
+		{
+			name:             "additional account role denied",
+			additionalDenied: []string{"iam:CreateOpenIDConnectProvider"},
+			wantDenied:       []string{},
+			wantMessage:      "Role arn:aws:iam::210987654321:role/irsa is not allowed to perform iam:CreateOpenIDConnectProvider",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cluster := &capa.AWSCluster{}
+			fakeScope := newFakeScope()
+			fakeScope.cluster = cluster
+
+			s := &Service{scope: fakeScope, Client: &fakeIAMClient{simulateDenied: tt.clusterDenied}, roleARN: "arn:aws:iam::123456789012:role/test"}
+			additionalAccount := &Service{scope: fakeScope, Client: &fakeIAMClient{simulateDenied: tt.additionalDenied}, roleARN: "arn:aws:iam::210987654321:role/irsa"}
+
+			denied, err := s.Preflight([]string{"s3:PutObject"}, additionalAccount)
+			if err != nil {
+				t.Fatalf("Preflight() error = %v", err)
+			}
+			if len(denied) != len(tt.wantDenied) || (len(denied) > 0 && !reflect.DeepEqual(denied, tt.wantDenied)) {
+				t.Errorf("Preflight() = %v, want %v", denied, tt.wantDenied)
+			}
+
+			if got := conditions.IsTrue(cluster, key.IRSAPermissionsCondition); got != tt.wantConditionTrue {
+				t.Errorf("Preflight() condition true = %v, want %v", got, tt.wantConditionTrue)
+			}
+			if got := conditions.GetMessage(cluster, key.IRSAPermissionsCondition); got != tt.wantMessage {
+				t.Errorf("Preflight() condition message = %q, want %q", got, tt.wantMessage)
+			}
+		})
+	}
+}
+
+func Test_RequiredActions(t *testing.T) {
+	contains := func(actions []string, action string) bool {
+		for _, a := range actions {
+			if a == action {
+				return true
+			}
+		}
+		return false
+	}
+
+	tests := []struct {
+		name        string
+		hostingMode string
+		route53     bool
+		replica     bool
+		want        []string
+		wantNot     []string
+	}{
+		{
+			name:        "cloudfront",
+			hostingMode: key.HostingModeCloudFront,
+			want:        []string{"iam:CreateOpenIDConnectProvider", "s3:PutObject", "cloudfront:CreateDistribution", "acm:ListCertificates"},
+			wantNot:     []string{"route53:ChangeResourceRecordSets", "s3:PutReplicationConfiguration"},
+		},
+		{
+			name:        "cloudfront with route53 and replica",
+			hostingMode: key.HostingModeCloudFront,
+			route53:     true,
+			replica:     true,
+			want:        []string{"route53:ChangeResourceRecordSets", "s3:PutReplicationConfiguration", "iam:PassRole"},
+		},
+		{
+			name:        "s3",
+			hostingMode: key.HostingModeS3,
+			route53:     true,
+			want:        []string{"s3:PutObject"},
+			wantNot:     []string{"cloudfront:CreateDistribution", "route53:ChangeResourceRecordSets"},
+		},
+		{
+			name:        "eks",
+			hostingMode: key.HostingModeEKS,
+			want:        []string{"eks:DescribeCluster", "iam:CreateOpenIDConnectProvider"},
+			wantNot:     []string{"s3:PutObject"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := RequiredActions(tt.hostingMode, tt.route53, tt.replica)
+			if !sort.StringsAreSorted(got) {
+				t.Errorf("RequiredActions() = %v is not sorted", got)
+			}
+			for _, action := range tt.want {
+				if !contains(got, action) {
+					t.Errorf("RequiredActions() is missing %s", action)
+				}
+			}
+			for _, action := range tt.wantNot {
+				if contains(got, action) {
+					t.Errorf("RequiredActions() contains %s", action)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package iam
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/irsa-operator/pkg/key"
+	"github.com/giantswarm/irsa-operator/pkg/util"
+)
+
+const s3ReplicationPolicyName = "irsa-s3-replication"
+
+const s3ReplicationTrustPolicy = `{
+	"Version": "2012-10-17",
+	"Statement": [
+		{
+			"Effect": "Allow",
+			"Principal": {
+				"Service": "s3.amazonaws.com"
+			},
+			"Action": "sts:AssumeRole"
+		}
+	]
+}`
+
+const s3ReplicationPolicy = `{
+	"Version": "2012-10-17",
+	"Statement": [
+		{
+			"Effect": "Allow",
+			"Action": [
+				"s3:GetReplicationConfiguration",
+				"s3:ListBucket"
+			],
+			"Resource": "arn:{{.ARNPrefix}}:s3:::{{.BucketName}}"
+		},
+		{
+			"Effect": "Allow",
+			"Action": [
+				"s3:GetObjectVersionForReplication",
+				"s3:GetObjectVersionAcl",
+				"s3:GetObjectVersionTagging"
+			],
+			"Resource": "arn:{{.ARNPrefix}}:s3:::{{.BucketName}}/*"
+		},
+		{
+			"Effect": "Allow",
+			"Action": [
+				"s3:ReplicateObject",
+				"s3:ReplicateDelete",
+				"s3:ReplicateTags"
+			],
+			"Resource": "arn:{{.ARNPrefix}}:s3:::{{.ReplicaBucketName}}/*"
+		}
+	]
+}`
+
+// EnsureS3ReplicationRole makes sure the IAM role assumed by S3 to replicate bucketName into replicaBucketName
+// exists and carries the required permissions. It returns the ARN of the role.
+func (s *Service) EnsureS3ReplicationRole(roleName, bucketName, replicaBucketName string, customerTags map[string]string) (string, error) {
+	logger := s.scope.Logger().WithValues("roleName", roleName)
+
+	var roleArn string
+	o, err := s.Client.GetRole(&iam.GetRoleInput{RoleName: aws.String(roleName)})
+	if err == nil {
+		roleArn = *o.Role.Arn
+	} else if aerr, ok := err.(awserr.Error); ok && aerr.Code() == iam.ErrCodeNoSuchEntityException {
+		tags := make([]*iam.Tag, 0)
+		for k, v := range s.internalTags() {
+			tags = append(tags, &iam.Tag{Key: aws.String(k), Value: aws.String(v)})
+		}
+		for k, v := range customerTags {
+			tags = append(tags, &iam.Tag{Key: aws.String(k), Value: aws.String(v)})
+		}
+
+		logger.Info("Creating S3 replication role")
+		created, err := s.Client.CreateRole(&iam.CreateRoleInput{
+			AssumeRolePolicyDocument: aws.String(s3ReplicationTrustPolicy),
+			Description:              aws.String(fmt.Sprintf("Replication of the IRSA OIDC bucket of cluster %s", s.scope.ClusterName())),
+			RoleName:                 aws.String(roleName),
+			Tags:                     util.FilterUniqueTags(tags),
+		})
+		if err != nil {
+			return "", microerror.Mask(err)
+		}
+		roleArn = *created.Role.Arn
+		logger.Info("Created S3 replication role")
+	} else {
+		return "", microerror.Mask(err)
+	}
+
+	t, err := template.New("").Parse(s3ReplicationPolicy)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+	values := struct {
+		ARNPrefix         string
+		BucketName        string
+		ReplicaBucketName string
+	}{
+		key.ARNPrefix(s.scope.Region()),
+		bucketName,
+		replicaBucketName,
+	}
+
+	var buf bytes.Buffer
+	err = t.Execute(&buf, values)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	_, err = s.Client.PutRolePolicy(&iam.PutRolePolicyInput{
+		PolicyDocument: aws.String(buf.String()),
+		PolicyName:     aws.String(s3ReplicationPolicyName),
+		RoleName:       aws.String(roleName),
+	})
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	logger.Info("Ensured S3 replication role")
+	return roleArn, nil
+}
+
+// DeleteS3ReplicationRole deletes the IAM role created by EnsureS3ReplicationRole.
+func (s *Service) DeleteS3ReplicationRole(roleName string) error {
+	logger := s.scope.Logger().WithValues("roleName", roleName)
+
+	_, err := s.Client.DeleteRolePolicy(&iam.DeleteRolePolicyInput{
+		PolicyName: aws.String(s3ReplicationPolicyName),
+		RoleName:   aws.String(roleName),
+	})
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == iam.ErrCodeNoSuchEntityException {
+		// Policy or role is already gone, fall through.
+	} else if err != nil {
+		return microerror.Mask(err)
+	}
+
+	_, err = s.Client.DeleteRole(&iam.DeleteRoleInput{RoleName: aws.String(roleName)})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok {
+			switch aerr.Code() {
+			case iam.ErrCodeNoSuchEntityException:
+				logger.Info("S3 replication role no longer exists, skipping deletion")
+				return nil
+			}
+		}
+		return microerror.Mask(err)
+	}
+
+	logger.Info("Deleted S3 replication role")
+	return nil
+}
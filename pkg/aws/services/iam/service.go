@@ -10,12 +10,22 @@ import (
 type Service struct {
 	scope  scope.IAMScope
 	Client iamiface.IAMAPI
+
+	// roleARN is the role assumed for IAM, which is the cluster's role unless the service manages another account.
+	roleARN string
 }
 
 // NewService returns a new service given the S3 api client.
 func NewService(clusterScope scope.IAMScope) *Service {
+	return NewServiceForRole(clusterScope, clusterScope.ARN())
+}
+
+// NewServiceForRole returns a new service for the cluster that assumes the given role, to manage the cluster's OIDC
+// providers in the role's account.
+func NewServiceForRole(clusterScope scope.IAMScope, roleARN string) *Service {
 	return &Service{
-		scope:  clusterScope,
-		Client: scope.NewIAMClient(clusterScope, clusterScope.ARN(), clusterScope.Cluster()),
+		scope:   clusterScope,
+		Client:  scope.NewIAMClient(clusterScope, roleARN, clusterScope.Cluster()),
+		roleARN: roleARN,
 	}
 }
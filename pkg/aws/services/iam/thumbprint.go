@@ -0,0 +1,127 @@
+package iam
+
+import (
+	"crypto/sha1" //nolint:gosec
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+
+	"github.com/giantswarm/irsa-operator/pkg/key"
+)
+
+// thumbprints returns the deduplicated thumbprints of all identity provider URLs for the thumbprint mode, or nil if
+// thumbprints are unmanaged.
+func (s *Service) thumbprints(identityProviderURLs []string, mode string) ([]*string, error) {
+	// Without thumbprints, AWS verifies the issuer against its own trust store.
+	if mode == key.ThumbprintModeUnmanaged {
+		return nil, nil
+	}
+
+	thumbprints := make([]*string, 0)
+	thumbprintsSeen := make(map[string]bool)
+	for _, identityProviderURL := range identityProviderURLs {
+		tps, err := s.caThumbPrints(identityProviderURL, mode == key.ThumbprintModeChain)
+		if err != nil {
+			return nil, err
+		}
+
+		// avoid duplicates
+		for _, tp := range tps {
+			// Avoid pointer aliasing in Go <1.22 by creating a loop-scoped variable. Also ensure same case so we don't
+			// get such duplicates.
+			tp := strings.ToLower(tp)
+
+			if _, seen := thumbprintsSeen[tp]; !seen {
+				thumbprints = append(thumbprints, &tp)
+				thumbprintsSeen[tp] = true
+			}
+		}
+	}
+
+	return thumbprints, nil
+}
+
+func (s *Service) caThumbPrints(ep string, includeIntermediates bool) ([]string, error) {
+	client, err := newThumbprintClient(nil)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return fetchThumbprints(client, ep, includeIntermediates, s.scope.Logger())
+}
+
+// newThumbprintClient returns an HTTP client verifying certificates against rootCAs, or the system roots if nil.
+func newThumbprintClient(rootCAs *x509.CertPool) (*http.Client, error) {
+	transport := &http.Transport{
+		TLSClientConfig: &tls.Config{
+			MinVersion: tls.VersionTLS12,
+			RootCAs:    rootCAs,
+		},
+	}
+
+	// check PROXY env
+	if v, ok := os.LookupEnv("HTTPS_PROXY"); ok {
+		proxy, err := url.Parse(v)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+
+	return &http.Client{
+		Timeout:   time.Second * 10,
+		Transport: transport,
+	}, nil
+}
+
+// fetchThumbprints returns the SHA-1 thumbprints of the root certificates of all verified chains of the endpoint,
+// as well as of their intermediates if includeIntermediates is set. The leaf certificate is never included, it is
+// rotated far too often.
+func fetchThumbprints(client *http.Client, ep string, includeIntermediates bool, logger logr.Logger) ([]string, error) {
+	resp, err := client.Get(ep)
+	if err != nil {
+		return nil, microerror.Mask(errors.Wrapf(err, "failed to get %s", ep))
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			logger.Error(err, "failed to close response body", "ep", ep)
+		}
+	}()
+
+	if resp.TLS == nil || len(resp.TLS.VerifiedChains) == 0 {
+		return nil, microerror.Maskf(noVerifiedChainError, "no verified TLS certificate chain for %s", ep)
+	}
+
+	thumbprints := make([]string, 0)
+	seen := make(map[string]bool)
+	add := func(cert *x509.Certificate) {
+		sum := sha1.Sum(cert.Raw) //nolint:gosec
+		thumbprint := hex.EncodeToString(sum[:])
+		if !seen[thumbprint] {
+			seen[thumbprint] = true
+			thumbprints = append(thumbprints, thumbprint)
+		}
+	}
+
+	for _, chain := range resp.TLS.VerifiedChains {
+		// A verified chain starts with the leaf and ends with a certificate from the trust store.
+		add(chain[len(chain)-1])
+
+		if includeIntermediates {
+			for i := 1; i < len(chain)-1; i++ {
+				add(chain[i])
+			}
+		}
+	}
+
+	return thumbprints, nil
+}
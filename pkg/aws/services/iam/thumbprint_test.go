@@ -0,0 +1,180 @@
+package iam
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha1" //nolint:gosec
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCert(t *testing.T, template *x509.Certificate, parent *testCA) *testCA {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parentCert, parentKey := template, key
+	if parent != nil {
+		parentCert, parentKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parentCert, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &testCA{cert: cert, key: key}
+}
+
+func newTestChain(t *testing.T) (root, intermediate, leaf *testCA) {
+	t.Helper()
+
+	ca := func(serial int64, name string) *x509.Certificate {
+		return &x509.Certificate{
+			SerialNumber:          big.NewInt(serial),
+			Subject:               pkix.Name{CommonName: name},
+			NotBefore:             time.Now().Add(-time.Hour),
+			NotAfter:              time.Now().Add(time.Hour),
+			KeyUsage:              x509.KeyUsageCertSign,
+			BasicConstraintsValid: true,
+			IsCA:                  true,
+		}
+	}
+
+	root = newTestCert(t, ca(1, "root"), nil)
+	intermediate = newTestCert(t, ca(2, "intermediate"), root)
+	leaf = newTestCert(t, &x509.Certificate{
+		SerialNumber: big.NewInt(3),
+		Subject:      pkix.Name{CommonName: "issuer"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}, intermediate)
+
+	return root, intermediate, leaf
+}
+
+func newTestServer(t *testing.T, leaf, intermediate *testCA) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.TLS = &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		Certificates: []tls.Certificate{
+			{
+				Certificate: [][]byte{leaf.cert.Raw, intermediate.cert.Raw},
+				PrivateKey:  leaf.key,
+			},
+		},
+	}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func thumbprint(cert *x509.Certificate) string {
+	sum := sha1.Sum(cert.Raw) //nolint:gosec
+	return hex.EncodeToString(sum[:])
+}
+
+func Test_fetchThumbprints(t *testing.T) {
+	t.Setenv("HTTPS_PROXY", "")
+	if err := os.Unsetenv("HTTPS_PROXY"); err != nil {
+		t.Fatal(err)
+	}
+
+	root, intermediate, leaf := newTestChain(t)
+	server := newTestServer(t, leaf, intermediate)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(root.cert)
+
+	otherRoot, _, _ := newTestChain(t)
+	otherRoots := x509.NewCertPool()
+	otherRoots.AddCert(otherRoot.cert)
+
+	tests := []struct {
+		name                 string
+		roots                *x509.CertPool
+		includeIntermediates bool
+		want                 []string
+		wantErr              bool
+	}{
+		{
+			name:  "root only",
+			roots: roots,
+			want:  []string{thumbprint(root.cert)},
+		},
+		{
+			name:                 "root and intermediates",
+			roots:                roots,
+			includeIntermediates: true,
+			want:                 []string{thumbprint(root.cert), thumbprint(intermediate.cert)},
+		},
+		{
+			name:    "untrusted root",
+			roots:   otherRoots,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := newThumbprintClient(tt.roots)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := fetchThumbprints(client, server.URL, tt.includeIntermediates, logr.Discard())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("fetchThumbprints() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("fetchThumbprints() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_fetchThumbprints_noVerifiedChain(t *testing.T) {
+	_, intermediate, leaf := newTestChain(t)
+	server := newTestServer(t, leaf, intermediate)
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true}, //nolint:gosec
+		},
+	}
+
+	_, err := fetchThumbprints(client, server.URL, false, logr.Discard())
+	if !IsNoVerifiedChain(err) {
+		t.Errorf("fetchThumbprints() error = %v, want noVerifiedChainError", err)
+	}
+}
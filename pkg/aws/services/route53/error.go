@@ -5,3 +5,17 @@ import "github.com/giantswarm/microerror"
 var zoneNotFoundError = &microerror.Error{
 	Kind: "zoneNotFoundError",
 }
+
+// IsZoneNotFound asserts zoneNotFoundError.
+func IsZoneNotFound(err error) bool {
+	return microerror.Cause(err) == zoneNotFoundError
+}
+
+var recordOwnershipConflictError = &microerror.Error{
+	Kind: "recordOwnershipConflictError",
+}
+
+// IsRecordOwnershipConflict asserts recordOwnershipConflictError.
+func IsRecordOwnershipConflict(err error) bool {
+	return microerror.Cause(err) == recordOwnershipConflictError
+}
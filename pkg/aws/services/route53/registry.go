@@ -0,0 +1,123 @@
+package route53
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/irsa-operator/pkg/util/record"
+)
+
+// Every record managed by the operator gets a TXT record named `<type>-<name>` next to it, similar to the
+// external-dns TXT registry. It tells which installation and cluster own the record, so that we never overwrite or
+// delete records created by someone else.
+
+const ownershipHeritage = "heritage=irsa-operator"
+
+func ownershipRecordName(recordType, name string) string {
+	return fmt.Sprintf("%s-%s", strings.ToLower(recordType), name)
+}
+
+func (s *Service) ownershipValue() string {
+	return fmt.Sprintf("%q", fmt.Sprintf("%s,irsa-operator/installation=%s,irsa-operator/cluster=%s/%s", ownershipHeritage, s.scope.Installation(), s.scope.ClusterNamespace(), s.scope.ClusterName()))
+}
+
+// getOwnershipRecord returns the ownership TXT record of the record with the given type and name, or nil if there
+// is none.
+func (s *Service) getOwnershipRecord(hostedZoneID, recordType, name string) (*route53.ResourceRecordSet, error) {
+	cacheKey := s.ownershipCacheKey(hostedZoneID, recordType, name)
+
+	if cachedValue, ok := s.scope.Cache().Get(cacheKey); ok {
+		return cachedValue.(*route53.ResourceRecordSet), nil
+	}
+
+	recordSets, err := s.listRecordSets(hostedZoneID, ownershipRecordName(recordType, name))
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	ownershipRecord := findRecordSet(recordSets, route53.RRTypeTxt)
+
+	// Every ensured record costs one lookup per record type, which quickly adds up to the Route53 rate limit. Other
+	// owners claiming our records is rare, and the entries are dropped whenever we change the records ourselves.
+	s.scope.Cache().Set(cacheKey, ownershipRecord, 10*time.Minute)
+
+	return ownershipRecord, nil
+}
+
+// forgetOwnershipRecords drops the cached ownership records of all record types managed for name.
+func (s *Service) forgetOwnershipRecords(hostedZoneID, name string) {
+	for _, recordType := range []string{route53.RRTypeA, route53.RRTypeAaaa, route53.RRTypeCname} {
+		s.scope.Cache().Delete(s.ownershipCacheKey(hostedZoneID, recordType, name))
+	}
+}
+
+func (s *Service) ownershipCacheKey(hostedZoneID, recordType, name string) string {
+	return fmt.Sprintf("route53/arn=%q/zoneId=%q/name=%q/type=%s/ownership", s.scope.DNSRoleARN(), hostedZoneID, name, recordType)
+}
+
+// isOwnedByUs checks whether the ownership TXT record belongs to this installation and cluster.
+func (s *Service) isOwnedByUs(ownershipRecord *route53.ResourceRecordSet) bool {
+	for _, r := range ownershipRecord.ResourceRecords {
+		if aws.StringValue(r.Value) == s.ownershipValue() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// claimRecord checks whether we may write the record with the given type and name. Records without ownership
+// record are adopted if they already point at target, which is the case for records created by earlier versions of
+// the operator. It returns the current ownership record, if any, or recordOwnershipConflictError after emitting a
+// warning event.
+func (s *Service) claimRecord(hostedZoneID, recordType, name string, existing *route53.ResourceRecordSet, target string) (*route53.ResourceRecordSet, error) {
+	ownershipRecord, err := s.getOwnershipRecord(hostedZoneID, recordType, name)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	if ownershipRecord != nil && !s.isOwnedByUs(ownershipRecord) {
+		return nil, s.conflict(hostedZoneID, recordType, name, "it is owned by someone else")
+	}
+	if ownershipRecord == nil && existing != nil && !pointsTo(existing, target) {
+		return nil, s.conflict(hostedZoneID, recordType, name, "it already exists with a different target")
+	}
+
+	return ownershipRecord, nil
+}
+
+// findRecordSet returns the record set with the given type, or nil if there is none.
+func findRecordSet(recordSets []*route53.ResourceRecordSet, recordType string) *route53.ResourceRecordSet {
+	for _, recordSet := range recordSets {
+		if aws.StringValue(recordSet.Type) == recordType {
+			return recordSet
+		}
+	}
+
+	return nil
+}
+
+func (s *Service) desiredOwnershipRecord(recordType, name string) *route53.ResourceRecordSet {
+	return &route53.ResourceRecordSet{
+		Name: aws.String(ownershipRecordName(recordType, name)),
+		ResourceRecords: []*route53.ResourceRecord{
+			{
+				Value: aws.String(s.ownershipValue()),
+			},
+		},
+		TTL:  aws.Int64(600),
+		Type: aws.String(route53.RRTypeTxt),
+	}
+}
+
+func (s *Service) conflict(hostedZoneID, recordType, name, reason string) error {
+	s.scope.Logger().Info("Not touching DNS record since "+reason, "zoneId", hostedZoneID, "type", recordType, "name", name)
+	record.Warnf(s.scope.Cluster(), "DNSRecordConflict", "Not touching %s record %s in hosted zone %s since %s", recordType, name, hostedZoneID, reason)
+
+	return microerror.Maskf(recordOwnershipConflictError, "%s record %s in hosted zone %s: %s", recordType, name, hostedZoneID, reason)
+}
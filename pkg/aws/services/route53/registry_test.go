@@ -0,0 +1,277 @@
+package route53
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/aws/aws-sdk-go/service/route53/route53iface"
+	"github.com/go-logr/logr"
+	gocache "github.com/patrickmn/go-cache"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/giantswarm/irsa-operator/pkg/aws/scope"
+)
+
+type fakeScope struct {
+	scope.Route53Scope
+	cache *gocache.Cache
+}
+
+func newFakeScope() *fakeScope {
+	return &fakeScope{cache: gocache.New(gocache.NoExpiration, 0)}
+}
+
+func (s *fakeScope) Cache() *gocache.Cache    { return s.cache }
+func (s *fakeScope) Cluster() runtime.Object  { return nil }
+func (s *fakeScope) ClusterName() string      { return "lbj23" }
+func (s *fakeScope) ClusterNamespace() string { return "org-wonderland" }
+func (s *fakeScope) DNSRoleARN() string       { return "arn:aws:iam::123456789012:role/dns" }
+func (s *fakeScope) Installation() string     { return "wonderland" }
+func (s *fakeScope) Logger() logr.Logger      { return logr.Discard() }
+
+type fakeRoute53Client struct {
+	route53iface.Route53API
+	recordSets []*route53.ResourceRecordSet
+	// zonePages holds the response of ListHostedZonesByName by `<DNSName>/<HostedZoneId>` of the request.
+	zonePages map[string]*route53.ListHostedZonesByNameOutput
+
+	changes              []*route53.Change
+	listHostedZonesCalls []string
+	listRecordSetsCalls  []string
+}
+
+func (c *fakeRoute53Client) ListResourceRecordSets(input *route53.ListResourceRecordSetsInput) (*route53.ListResourceRecordSetsOutput, error) {
+	c.listRecordSetsCalls = append(c.listRecordSetsCalls, aws.StringValue(input.StartRecordName))
+	return &route53.ListResourceRecordSetsOutput{ResourceRecordSets: c.recordSets}, nil
+}
+
+func (c *fakeRoute53Client) ChangeResourceRecordSets(input *route53.ChangeResourceRecordSetsInput) (*route53.ChangeResourceRecordSetsOutput, error) {
+	c.changes = append(c.changes, input.ChangeBatch.Changes...)
+	return &route53.ChangeResourceRecordSetsOutput{
+		ChangeInfo: &route53.ChangeInfo{Id: aws.String("change"), Status: aws.String(route53.ChangeStatusInsync)},
+	}, nil
+}
+
+func (c *fakeRoute53Client) ListHostedZonesByName(input *route53.ListHostedZonesByNameInput) (*route53.ListHostedZonesByNameOutput, error) {
+	page := aws.StringValue(input.DNSName) + "/" + aws.StringValue(input.HostedZoneId)
+	c.listHostedZonesCalls = append(c.listHostedZonesCalls, page)
+
+	if output, ok := c.zonePages[page]; ok {
+		return output, nil
+	}
+	return &route53.ListHostedZonesByNameOutput{}, nil
+}
+
+func cnameRecordSet(name, value string) *route53.ResourceRecordSet {
+	return &route53.ResourceRecordSet{
+		Name:            aws.String(name),
+		ResourceRecords: []*route53.ResourceRecord{{Value: aws.String(value)}},
+		TTL:             aws.Int64(600),
+		Type:            aws.String(route53.RRTypeCname),
+	}
+}
+
+func txtRecordSet(name, value string) *route53.ResourceRecordSet {
+	return &route53.ResourceRecordSet{
+		Name:            aws.String(name),
+		ResourceRecords: []*route53.ResourceRecord{{Value: aws.String(value)}},
+		TTL:             aws.Int64(600),
+		Type:            aws.String(route53.RRTypeTxt),
+	}
+}
+
+const (
+	ourOwnership   = `"heritage=irsa-operator,irsa-operator/installation=wonderland,irsa-operator/cluster=org-wonderland/lbj23"`
+	otherOwnership = `"heritage=irsa-operator,irsa-operator/installation=wonderland,irsa-operator/cluster=org-other/other"`
+)
+
+func Test_ownershipValue(t *testing.T) {
+	s := &Service{scope: newFakeScope()}
+
+	if got := s.ownershipValue(); got != ourOwnership {
+		t.Errorf("ownershipValue() = %s, want %s", got, ourOwnership)
+	}
+}
+
+func Test_claimRecord(t *testing.T) {
+	tests := []struct {
+		name                string
+		recordSets          []*route53.ResourceRecordSet
+		existing            *route53.ResourceRecordSet
+		wantConflict        bool
+		wantOwnershipRecord bool
+	}{
+		{
+			name: "new record",
+		},
+		{
+			name:     "unowned record pointing at target is adopted",
+			existing: cnameRecordSet("irsa.example.com", "d111.cloudfront.net"),
+		},
+		{
+			name:         "unowned record with other target",
+			existing:     cnameRecordSet("irsa.example.com", "d222.cloudfront.net"),
+			wantConflict: true,
+		},
+		{
+			name: "record owned by us",
+			recordSets: []*route53.ResourceRecordSet{
+				txtRecordSet("cname-irsa.example.com", ourOwnership),
+			},
+			existing:            cnameRecordSet("irsa.example.com", "d222.cloudfront.net"),
+			wantOwnershipRecord: true,
+		},
+		{
+			name: "record owned by another cluster",
+			recordSets: []*route53.ResourceRecordSet{
+				txtRecordSet("cname-irsa.example.com", otherOwnership),
+			},
+			existing:     cnameRecordSet("irsa.example.com", "d111.cloudfront.net"),
+			wantConflict: true,
+		},
+		{
+			name: "ownership record of another cluster without record",
+			recordSets: []*route53.ResourceRecordSet{
+				txtRecordSet("cname-irsa.example.com", otherOwnership),
+			},
+			wantConflict: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Service{
+				scope:  newFakeScope(),
+				Client: &fakeRoute53Client{recordSets: tt.recordSets},
+			}
+
+			ownershipRecord, err := s.claimRecord("Z1", route53.RRTypeCname, "irsa.example.com", tt.existing, "d111.cloudfront.net")
+			if IsRecordOwnershipConflict(err) != tt.wantConflict {
+				t.Fatalf("claimRecord() error = %v, want conflict %v", err, tt.wantConflict)
+			}
+			if !tt.wantConflict && err != nil {
+				t.Fatalf("claimRecord() error = %v", err)
+			}
+			if (ownershipRecord != nil) != tt.wantOwnershipRecord {
+				t.Errorf("claimRecord() ownership record = %v, want %v", ownershipRecord, tt.wantOwnershipRecord)
+			}
+		})
+	}
+}
+
+func Test_EnsureDNSRecord(t *testing.T) {
+	cname := CNAME{Name: "irsa.example.com", Value: "d111.cloudfront.net"}
+
+	tests := []struct {
+		name         string
+		recordSets   []*route53.ResourceRecordSet
+		wantConflict bool
+	}{
+		{
+			name: "new record is claimed",
+		},
+		{
+			name: "unowned record pointing at target is claimed",
+			recordSets: []*route53.ResourceRecordSet{
+				cnameRecordSet("irsa.example.com", "d111.cloudfront.net"),
+			},
+		},
+		{
+			name: "record owned by us is updated",
+			recordSets: []*route53.ResourceRecordSet{
+				cnameRecordSet("irsa.example.com", "d222.cloudfront.net"),
+				txtRecordSet("cname-irsa.example.com", ourOwnership),
+			},
+		},
+		{
+			name: "record owned by another cluster is left alone",
+			recordSets: []*route53.ResourceRecordSet{
+				cnameRecordSet("irsa.example.com", "d111.cloudfront.net"),
+				txtRecordSet("cname-irsa.example.com", otherOwnership),
+			},
+			wantConflict: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &fakeRoute53Client{recordSets: tt.recordSets}
+			s := &Service{scope: newFakeScope(), Client: client}
+
+			err := s.EnsureDNSRecord("Z1", cname)
+			if IsRecordOwnershipConflict(err) != tt.wantConflict {
+				t.Fatalf("EnsureDNSRecord() error = %v, want conflict %v", err, tt.wantConflict)
+			}
+
+			if tt.wantConflict {
+				if len(client.changes) != 0 {
+					t.Errorf("EnsureDNSRecord() made changes %v despite conflict", client.changes)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("EnsureDNSRecord() error = %v", err)
+			}
+
+			if len(client.changes) != 2 {
+				t.Fatalf("EnsureDNSRecord() made %d changes, want 2", len(client.changes))
+			}
+			ownershipRecord := client.changes[0].ResourceRecordSet
+			if aws.StringValue(ownershipRecord.Name) != "cname-irsa.example.com" || aws.StringValue(ownershipRecord.ResourceRecords[0].Value) != ourOwnership {
+				t.Errorf("EnsureDNSRecord() ownership record = %v", ownershipRecord)
+			}
+			if !pointsTo(client.changes[1].ResourceRecordSet, cname.Value) {
+				t.Errorf("EnsureDNSRecord() record = %v, want it to point at %s", client.changes[1].ResourceRecordSet, cname.Value)
+			}
+		})
+	}
+}
+
+func Test_getOwnershipRecord_cache(t *testing.T) {
+	client := &fakeRoute53Client{
+		recordSets: []*route53.ResourceRecordSet{
+			txtRecordSet("cname-irsa.example.com", ourOwnership),
+		},
+	}
+	s := &Service{scope: newFakeScope(), Client: client}
+
+	for i := 0; i < 2; i++ {
+		ownershipRecord, err := s.getOwnershipRecord("Z1", route53.RRTypeCname, "irsa.example.com")
+		if err != nil {
+			t.Fatalf("getOwnershipRecord() error = %v", err)
+		}
+		if ownershipRecord == nil {
+			t.Fatalf("getOwnershipRecord() = nil, want ownership record")
+		}
+	}
+	if len(client.listRecordSetsCalls) != 1 {
+		t.Errorf("getOwnershipRecord() listed record sets %d times, want 1", len(client.listRecordSetsCalls))
+	}
+
+	// Missing ownership records are cached as well.
+	for i := 0; i < 2; i++ {
+		ownershipRecord, err := s.getOwnershipRecord("Z1", route53.RRTypeA, "irsa.example.com")
+		if err != nil {
+			t.Fatalf("getOwnershipRecord() error = %v", err)
+		}
+		if ownershipRecord != nil {
+			t.Fatalf("getOwnershipRecord() = %v, want nil", ownershipRecord)
+		}
+	}
+	if len(client.listRecordSetsCalls) != 2 {
+		t.Errorf("getOwnershipRecord() listed record sets %d times, want 2", len(client.listRecordSetsCalls))
+	}
+
+	// Our own changes drop the cached ownership records.
+	err := s.EnsureDNSRecord("Z1", CNAME{Name: "irsa.example.com", Value: "d111.cloudfront.net"})
+	if err != nil {
+		t.Fatalf("EnsureDNSRecord() error = %v", err)
+	}
+	calls := len(client.listRecordSetsCalls)
+	_, err = s.getOwnershipRecord("Z1", route53.RRTypeCname, "irsa.example.com")
+	if err != nil {
+		t.Fatalf("getOwnershipRecord() error = %v", err)
+	}
+	if len(client.listRecordSetsCalls) != calls+1 {
+		t.Errorf("getOwnershipRecord() used the cache after the record was changed")
+	}
+}
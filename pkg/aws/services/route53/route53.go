@@ -6,10 +6,17 @@ import (
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/route53"
 	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/irsa-operator/pkg/key"
 )
 
+// cloudFrontHostedZoneID is the fixed hosted zone ID to use for alias records pointing to CloudFront distributions.
+// See https://docs.aws.amazon.com/Route53/latest/APIReference/API_AliasTarget.html.
+const cloudFrontHostedZoneID = "Z2FDTNDATAQYW2"
+
 type CNAME struct {
 	Name  string
 	Value string
@@ -23,16 +30,32 @@ func (s *Service) FindPrivateHostedZone(basename string) (string, error) {
 	return s.findHostedZone(basename, false)
 }
 
-func (s *Service) findHostedZone(zoneName string, public bool) (string, error) {
-	s.scope.Logger().Info("Searching route53 hosted zone ID", "zoneName", zoneName)
+// findHostedZone returns the ID of the closest public or private hosted zone enclosing the given domain name,
+// walking up the domain labels until a zone is found. This way, names below a delegated parent zone are found as
+// well, e.g. when the base domain is not a zone itself.
+func (s *Service) findHostedZone(domain string, public bool) (string, error) {
+	s.scope.Logger().Info("Searching route53 hosted zone ID", "domain", domain)
 
-	makeCacheKey := func(zoneName string, public bool) string {
-		return fmt.Sprintf("route53/arn=%q/zoneName=%q/public=%v/id", s.scope.ARN(), zoneName, public)
+	for zoneName := strings.ToLower(strings.TrimSuffix(domain, ".")); strings.Contains(zoneName, "."); zoneName = key.ParentDomain(zoneName) {
+		zoneId, err := s.findHostedZoneByName(zoneName, public)
+		if err != nil {
+			return "", microerror.Mask(err)
+		}
+
+		if zoneId != "" {
+			return zoneId, nil
+		}
 	}
 
-	if cachedValue, ok := s.scope.Cache().Get(makeCacheKey(zoneName, public)); ok {
+	return "", microerror.Maskf(zoneNotFoundError, "no hosted zone found for domain %q (public=%v)", domain, public)
+}
+
+// findHostedZoneByName returns the ID of the hosted zone with exactly the given name, or an empty string if there
+// is none.
+func (s *Service) findHostedZoneByName(zoneName string, public bool) (string, error) {
+	if cachedValue, ok := s.scope.Cache().Get(s.hostedZoneCacheKey(zoneName, public)); ok {
 		zoneId := cachedValue.(string)
-		s.scope.Logger().Info("Using Route53 hosted zone ID from cache", "arn", s.scope.ARN(), "zoneId", zoneId, "zoneName", zoneName)
+		s.scope.Logger().Info("Using Route53 hosted zone ID from cache", "arn", s.scope.DNSRoleARN(), "zoneId", zoneId, "zoneName", zoneName)
 		return zoneId, nil
 	}
 
@@ -42,33 +65,52 @@ func (s *Service) findHostedZone(zoneName string, public bool) (string, error) {
 	// comparison - if that exact zone name does not exist, AWS may still return other zones!
 	//
 	// See https://docs.aws.amazon.com/Route53/latest/APIReference/API_ListHostedZonesByName.html.
-	listResponse, err := s.Client.ListHostedZonesByName(&route53.ListHostedZonesByNameInput{
+	input := &route53.ListHostedZonesByNameInput{
 		DNSName:  aws.String(zoneName),
 		MaxItems: aws.String("100"),
-	})
-	if err != nil {
-		return "", microerror.Mask(err)
 	}
 
-	for _, zone := range listResponse.HostedZones {
-		s.scope.Cache().Set(
-			makeCacheKey(strings.TrimSuffix(*zone.Name, "."), !*zone.Config.PrivateZone),
-			*zone.Id,
-			// We requeue every few minutes to update OIDC certificate thumbprints (see controller code), and there's no
-			// reason to think that a DNS zone ID was changed/deleted for the purposes of irsa-operator. So cache results
-			// long enough to last 2 reconciliations (= cache longer than controller's requeue interval).
-			7*time.Minute)
-	}
+	wantedAWSZoneName := zoneName + "."
+	zoneId := ""
+	for {
+		listResponse, err := s.Client.ListHostedZonesByName(input)
+		if err != nil {
+			return "", microerror.Mask(err)
+		}
+
+		for _, zone := range listResponse.HostedZones {
+			s.scope.Cache().Set(
+				s.hostedZoneCacheKey(strings.TrimSuffix(*zone.Name, "."), !*zone.Config.PrivateZone),
+				*zone.Id,
+				// We requeue every few minutes to update OIDC certificate thumbprints (see controller code), and there's no
+				// reason to think that a DNS zone ID was changed/deleted for the purposes of irsa-operator. So cache results
+				// long enough to last 2 reconciliations (= cache longer than controller's requeue interval).
+				7*time.Minute)
+
+			// We return the first zone found that matches the name and is public or not according to the parameter.
+			if zoneId == "" && *zone.Name == wantedAWSZoneName && public == !*zone.Config.PrivateZone {
+				zoneId = *zone.Id
+			}
+		}
 
-	// We return the first zone found that matches the basename and is public or not according to the parameter.
-	wantedAWSZoneName := strings.TrimSuffix(zoneName, ".") + "."
-	for _, zone := range listResponse.HostedZones {
-		if *zone.Name == wantedAWSZoneName && public == !*zone.Config.PrivateZone {
-			return *zone.Id, nil
+		// Zones are sorted by name, so all zones with the wanted name are at the start of the listing. Only continue
+		// with the next page if it may still contain some.
+		lastZone := len(listResponse.HostedZones) - 1
+		if zoneId != "" || !aws.BoolValue(listResponse.IsTruncated) || lastZone < 0 || *listResponse.HostedZones[lastZone].Name != wantedAWSZoneName {
+			break
 		}
+
+		input.DNSName = listResponse.NextDNSName
+		input.HostedZoneId = listResponse.NextHostedZoneId
+	}
+
+	if zoneId == "" {
+		// Remember that there is no such zone as well, so that walking up the domain labels doesn't cost a request
+		// per label on every reconciliation.
+		s.scope.Cache().Set(s.hostedZoneCacheKey(zoneName, public), "", 7*time.Minute)
 	}
 
-	return "", microerror.Mask(zoneNotFoundError)
+	return zoneId, nil
 }
 
 func (s *Service) EnsureDNSRecord(hostedZoneID string, cname CNAME) error {
@@ -76,7 +118,7 @@ func (s *Service) EnsureDNSRecord(hostedZoneID string, cname CNAME) error {
 
 	logger.Info("Ensuring CNAME record")
 
-	cacheKey := fmt.Sprintf("route53/arn=%q/zoneId=%q/cname-name=%q/cname-value", s.scope.ARN(), hostedZoneID, cname.Name)
+	cacheKey := s.cnameCacheKey(hostedZoneID, cname.Name)
 
 	if cachedValue, ok := s.scope.Cache().Get(cacheKey); ok {
 		cachedCNAMEValue := cachedValue.(string)
@@ -89,9 +131,23 @@ func (s *Service) EnsureDNSRecord(hostedZoneID string, cname CNAME) error {
 		}
 	}
 
+	existing, err := s.listRecordSets(hostedZoneID, cname.Name)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	_, err = s.claimRecord(hostedZoneID, route53.RRTypeCname, cname.Name, findRecordSet(existing, route53.RRTypeCname), cname.Value)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
 	input := &route53.ChangeResourceRecordSetsInput{
 		ChangeBatch: &route53.ChangeBatch{
 			Changes: []*route53.Change{
+				{
+					Action:            aws.String(route53.ChangeActionUpsert),
+					ResourceRecordSet: s.desiredOwnershipRecord(route53.RRTypeCname, cname.Name),
+				},
 				{
 					Action: aws.String(route53.ChangeActionUpsert),
 					ResourceRecordSet: &route53.ResourceRecordSet{
@@ -110,10 +166,13 @@ func (s *Service) EnsureDNSRecord(hostedZoneID string, cname CNAME) error {
 		HostedZoneId: aws.String(hostedZoneID),
 	}
 
-	_, err := s.Client.ChangeResourceRecordSets(input)
+	output, err := s.Client.ChangeResourceRecordSets(input)
 	if err != nil {
 		return microerror.Mask(err)
 	}
+	s.forgetOwnershipRecords(hostedZoneID, cname.Name)
+
+	s.trackChange(hostedZoneID, cname.Name, output.ChangeInfo)
 
 	// No other operator touches the `irsa.<basedomain>` DNS record, so we can remember for a long period
 	// that the DNS record was already upserted.
@@ -123,3 +182,290 @@ func (s *Service) EnsureDNSRecord(hostedZoneID string, cname CNAME) error {
 
 	return nil
 }
+
+// IsChangeInSync checks whether the last change made to the records for name has propagated to all Route53 DNS
+// servers. Changes are tracked across reconciliations, so callers can requeue instead of blocking while the change
+// is still pending.
+//
+// The change IDs only live in the in-memory cache and Route53 can't list the changes of a record, so a change
+// pending while the operator restarts is considered in sync afterwards. At worst, the following steps then run
+// before the records propagated, as they always did before changes were tracked.
+func (s *Service) IsChangeInSync(hostedZoneID, name string) (bool, error) {
+	cacheKey := s.changeCacheKey(hostedZoneID, name)
+
+	cachedValue, ok := s.scope.Cache().Get(cacheKey)
+	if !ok {
+		// Either nothing was changed recently or the change is known to be in sync already.
+		return true, nil
+	}
+	changeID := cachedValue.(string)
+
+	output, err := s.Client.GetChange(&route53.GetChangeInput{Id: aws.String(changeID)})
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == route53.ErrCodeNoSuchChange {
+		// Changes are only kept for a while, old ones have certainly propagated.
+		s.scope.Cache().Delete(cacheKey)
+		return true, nil
+	} else if err != nil {
+		return false, microerror.Mask(err)
+	}
+
+	if aws.StringValue(output.ChangeInfo.Status) != route53.ChangeStatusInsync {
+		s.scope.Logger().Info("Route53 change is still pending", "zoneId", hostedZoneID, "name", name, "changeId", changeID)
+		return false, nil
+	}
+
+	s.scope.Cache().Delete(cacheKey)
+
+	return true, nil
+}
+
+// trackChange remembers the change for IsChangeInSync.
+func (s *Service) trackChange(hostedZoneID, name string, changeInfo *route53.ChangeInfo) {
+	if changeInfo == nil || aws.StringValue(changeInfo.Status) == route53.ChangeStatusInsync {
+		return
+	}
+
+	// Changes usually propagate within 60 seconds, so the expiration is only a safety net for lost changes.
+	s.scope.Cache().Set(s.changeCacheKey(hostedZoneID, name), aws.StringValue(changeInfo.Id), time.Hour)
+}
+
+// RecordExists checks whether the CNAME record exists with the given value.
+func (s *Service) RecordExists(hostedZoneID string, cname CNAME) (bool, error) {
+	cacheKey := s.recordExistsCacheKey(hostedZoneID, cname.Name)
+
+	if cachedValue, ok := s.scope.Cache().Get(cacheKey); ok {
+		return cachedValue.(string) == cname.Value, nil
+	}
+
+	recordSets, err := s.listRecordSets(hostedZoneID, cname.Name)
+	if err != nil {
+		return false, microerror.Mask(err)
+	}
+
+	value := ""
+	for _, recordSet := range recordSets {
+		if *recordSet.Type != route53.RRTypeCname {
+			continue
+		}
+		for _, record := range recordSet.ResourceRecords {
+			if strings.EqualFold(key.EnsureTrailingDot(*record.Value), key.EnsureTrailingDot(cname.Value)) {
+				value = cname.Value
+			}
+		}
+	}
+
+	// Records vanishing is what we want to detect, but that is not urgent, so don't hit the Route53 rate limit
+	// on every reconciliation.
+	s.scope.Cache().Set(cacheKey, value, 10*time.Minute)
+
+	return value == cname.Value, nil
+}
+
+// EnsureAliasRecords makes sure alias A and AAAA records for name point at the given CloudFront distribution domain.
+// A CNAME record previously used for the same name is deleted in the same change batch, so the name keeps
+// resolving during the migration.
+func (s *Service) EnsureAliasRecords(hostedZoneID, name, cloudFrontDomain string) error {
+	logger := s.scope.Logger().WithValues("zoneId", hostedZoneID, "name", name, "target", cloudFrontDomain)
+
+	logger.Info("Ensuring alias records")
+
+	cacheKey := s.aliasCacheKey(hostedZoneID, name)
+
+	if cachedValue, ok := s.scope.Cache().Get(cacheKey); ok {
+		if cachedValue.(string) == cloudFrontDomain {
+			// Avoid making excess Route53 requests that lead to rate limiting if we recently
+			// upserted these exact alias records
+			logger.Info("Alias records were recently ensured, skipping upsert")
+			return nil
+		}
+	}
+
+	existing, err := s.listRecordSets(hostedZoneID, name)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	changes := make([]*route53.Change, 0, 6)
+	if recordSet := findRecordSet(existing, route53.RRTypeCname); recordSet != nil {
+		ownershipRecord, err := s.claimRecord(hostedZoneID, route53.RRTypeCname, name, recordSet, cloudFrontDomain)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		logger.Info("Replacing CNAME record with alias records")
+		// Deletions have to match the existing record sets exactly.
+		changes = append(changes, &route53.Change{
+			Action:            aws.String(route53.ChangeActionDelete),
+			ResourceRecordSet: recordSet,
+		})
+		if ownershipRecord != nil {
+			changes = append(changes, &route53.Change{
+				Action:            aws.String(route53.ChangeActionDelete),
+				ResourceRecordSet: ownershipRecord,
+			})
+		}
+	}
+
+	for _, recordType := range []string{route53.RRTypeA, route53.RRTypeAaaa} {
+		_, err := s.claimRecord(hostedZoneID, recordType, name, findRecordSet(existing, recordType), cloudFrontDomain)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		changes = append(changes, &route53.Change{
+			Action:            aws.String(route53.ChangeActionUpsert),
+			ResourceRecordSet: s.desiredOwnershipRecord(recordType, name),
+		})
+		changes = append(changes, &route53.Change{
+			Action: aws.String(route53.ChangeActionUpsert),
+			ResourceRecordSet: &route53.ResourceRecordSet{
+				AliasTarget: &route53.AliasTarget{
+					DNSName:              aws.String(key.EnsureTrailingDot(cloudFrontDomain)),
+					EvaluateTargetHealth: aws.Bool(false),
+					HostedZoneId:         aws.String(cloudFrontHostedZoneID),
+				},
+				Name: aws.String(name),
+				Type: aws.String(recordType),
+			},
+		})
+	}
+
+	output, err := s.Client.ChangeResourceRecordSets(&route53.ChangeResourceRecordSetsInput{
+		ChangeBatch:  &route53.ChangeBatch{Changes: changes},
+		HostedZoneId: aws.String(hostedZoneID),
+	})
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	s.forgetOwnershipRecords(hostedZoneID, name)
+
+	s.trackChange(hostedZoneID, name, output.ChangeInfo)
+
+	s.scope.Cache().Set(cacheKey, cloudFrontDomain, 10*time.Minute)
+
+	logger.Info("Ensured alias records")
+
+	return nil
+}
+
+// listRecordSets returns all record sets with exactly the given name.
+func (s *Service) listRecordSets(hostedZoneID, name string) ([]*route53.ResourceRecordSet, error) {
+	output, err := s.Client.ListResourceRecordSets(&route53.ListResourceRecordSetsInput{
+		HostedZoneId:    aws.String(hostedZoneID),
+		MaxItems:        aws.String("10"),
+		StartRecordName: aws.String(name),
+	})
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	// The listing starts at the given name, but continues with the following names in the zone.
+	recordSets := make([]*route53.ResourceRecordSet, 0)
+	for _, recordSet := range output.ResourceRecordSets {
+		if strings.EqualFold(key.EnsureTrailingDot(*recordSet.Name), key.EnsureTrailingDot(name)) {
+			recordSets = append(recordSets, recordSet)
+		}
+	}
+
+	return recordSets, nil
+}
+
+// DeleteDNSRecords deletes the records for name that still point at target, either CNAME records with target as
+// value or alias records with target as DNS name. Records pointing elsewhere were changed by someone else and
+// are left alone.
+func (s *Service) DeleteDNSRecords(hostedZoneID, name, target string) error {
+	logger := s.scope.Logger().WithValues("zoneId", hostedZoneID, "name", name, "target", target)
+
+	recordSets, err := s.listRecordSets(hostedZoneID, name)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	changes := make([]*route53.Change, 0)
+	for _, recordSet := range recordSets {
+		if pointsTo(recordSet, target) {
+			ownershipRecord, err := s.getOwnershipRecord(hostedZoneID, *recordSet.Type, name)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+			if ownershipRecord != nil && !s.isOwnedByUs(ownershipRecord) {
+				// The conflict is reported as event, but must not block the deletion of the cluster.
+				_ = s.conflict(hostedZoneID, *recordSet.Type, name, "it is owned by someone else")
+				continue
+			}
+
+			// Deletions have to match the existing record sets exactly.
+			changes = append(changes, &route53.Change{
+				Action:            aws.String(route53.ChangeActionDelete),
+				ResourceRecordSet: recordSet,
+			})
+			if ownershipRecord != nil {
+				changes = append(changes, &route53.Change{
+					Action:            aws.String(route53.ChangeActionDelete),
+					ResourceRecordSet: ownershipRecord,
+				})
+			}
+		} else if *recordSet.Type == route53.RRTypeCname || recordSet.AliasTarget != nil {
+			logger.Info("DNS record does not point at the expected target anymore, skipping deletion", "type", *recordSet.Type)
+		}
+	}
+
+	if len(changes) == 0 {
+		logger.Info("No DNS records to delete")
+		return nil
+	}
+
+	_, err = s.Client.ChangeResourceRecordSets(&route53.ChangeResourceRecordSetsInput{
+		ChangeBatch:  &route53.ChangeBatch{Changes: changes},
+		HostedZoneId: aws.String(hostedZoneID),
+	})
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	s.scope.Cache().Delete(s.cnameCacheKey(hostedZoneID, name))
+	s.scope.Cache().Delete(s.recordExistsCacheKey(hostedZoneID, name))
+	s.scope.Cache().Delete(s.aliasCacheKey(hostedZoneID, name))
+	s.scope.Cache().Delete(s.changeCacheKey(hostedZoneID, name))
+	s.forgetOwnershipRecords(hostedZoneID, name)
+
+	logger.Info("Deleted DNS records", "count", len(changes))
+
+	return nil
+}
+
+// pointsTo checks whether the record set is a CNAME with target as value or an alias record with target as DNS
+// name.
+func pointsTo(recordSet *route53.ResourceRecordSet, target string) bool {
+	target = key.EnsureTrailingDot(target)
+
+	if recordSet.AliasTarget != nil {
+		return strings.EqualFold(key.EnsureTrailingDot(aws.StringValue(recordSet.AliasTarget.DNSName)), target)
+	}
+
+	if aws.StringValue(recordSet.Type) != route53.RRTypeCname || len(recordSet.ResourceRecords) != 1 {
+		return false
+	}
+
+	return strings.EqualFold(key.EnsureTrailingDot(aws.StringValue(recordSet.ResourceRecords[0].Value)), target)
+}
+
+func (s *Service) cnameCacheKey(hostedZoneID, name string) string {
+	return fmt.Sprintf("route53/arn=%q/zoneId=%q/cname-name=%q/cname-value", s.scope.DNSRoleARN(), hostedZoneID, name)
+}
+
+func (s *Service) recordExistsCacheKey(hostedZoneID, name string) string {
+	return fmt.Sprintf("route53/arn=%q/zoneId=%q/cname-name=%q/exists", s.scope.DNSRoleARN(), hostedZoneID, name)
+}
+
+func (s *Service) aliasCacheKey(hostedZoneID, name string) string {
+	return fmt.Sprintf("route53/arn=%q/zoneId=%q/alias-name=%q/alias-target", s.scope.DNSRoleARN(), hostedZoneID, name)
+}
+
+func (s *Service) hostedZoneCacheKey(zoneName string, public bool) string {
+	return fmt.Sprintf("route53/arn=%q/zoneName=%q/public=%v/id", s.scope.DNSRoleARN(), zoneName, public)
+}
+
+func (s *Service) changeCacheKey(hostedZoneID, name string) string {
+	return fmt.Sprintf("route53/arn=%q/zoneId=%q/name=%q/change-id", s.scope.DNSRoleARN(), hostedZoneID, name)
+}
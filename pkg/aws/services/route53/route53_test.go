@@ -0,0 +1,248 @@
+package route53
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/route53"
+)
+
+func Test_pointsTo(t *testing.T) {
+	tests := []struct {
+		name      string
+		recordSet *route53.ResourceRecordSet
+		want      bool
+	}{
+		{
+			name: "CNAME with matching value",
+			recordSet: &route53.ResourceRecordSet{
+				Type:            aws.String(route53.RRTypeCname),
+				ResourceRecords: []*route53.ResourceRecord{{Value: aws.String("d111.cloudfront.net.")}},
+			},
+			want: true,
+		},
+		{
+			name: "CNAME with other value",
+			recordSet: &route53.ResourceRecordSet{
+				Type:            aws.String(route53.RRTypeCname),
+				ResourceRecords: []*route53.ResourceRecord{{Value: aws.String("d222.cloudfront.net.")}},
+			},
+			want: false,
+		},
+		{
+			name: "Alias with matching DNS name in different case",
+			recordSet: &route53.ResourceRecordSet{
+				Type:        aws.String(route53.RRTypeAaaa),
+				AliasTarget: &route53.AliasTarget{DNSName: aws.String("D111.cloudfront.net")},
+			},
+			want: true,
+		},
+		{
+			name: "Alias with other DNS name",
+			recordSet: &route53.ResourceRecordSet{
+				Type:        aws.String(route53.RRTypeA),
+				AliasTarget: &route53.AliasTarget{DNSName: aws.String("d222.cloudfront.net.")},
+			},
+			want: false,
+		},
+		{
+			name: "TXT record with matching value",
+			recordSet: &route53.ResourceRecordSet{
+				Type:            aws.String(route53.RRTypeTxt),
+				ResourceRecords: []*route53.ResourceRecord{{Value: aws.String("d111.cloudfront.net.")}},
+			},
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := pointsTo(tt.recordSet, "d111.cloudfront.net"); got != tt.want {
+				t.Errorf("pointsTo() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_findRecordSet(t *testing.T) {
+	recordSets := []*route53.ResourceRecordSet{
+		{Name: aws.String("irsa.example.com."), Type: aws.String(route53.RRTypeA)},
+		{Name: aws.String("irsa.example.com."), Type: aws.String(route53.RRTypeTxt)},
+	}
+
+	if got := findRecordSet(recordSets, route53.RRTypeTxt); got != recordSets[1] {
+		t.Errorf("findRecordSet(TXT) = %v, want %v", got, recordSets[1])
+	}
+	if got := findRecordSet(recordSets, route53.RRTypeCname); got != nil {
+		t.Errorf("findRecordSet(CNAME) = %v, want nil", got)
+	}
+}
+
+func Test_ownershipRecordName(t *testing.T) {
+	if got := ownershipRecordName(route53.RRTypeAaaa, "irsa.example.com"); got != "aaaa-irsa.example.com" {
+		t.Errorf("ownershipRecordName() = %q, want %q", got, "aaaa-irsa.example.com")
+	}
+}
+
+func hostedZone(id, name string, public bool) *route53.HostedZone {
+	return &route53.HostedZone{
+		Config: &route53.HostedZoneConfig{PrivateZone: aws.Bool(!public)},
+		Id:     aws.String(id),
+		Name:   aws.String(name),
+	}
+}
+
+func Test_findHostedZone(t *testing.T) {
+	tests := []struct {
+		name      string
+		domain    string
+		public    bool
+		zonePages map[string]*route53.ListHostedZonesByNameOutput
+		want      string
+		wantCalls []string
+	}{
+		{
+			name:   "zone with the domain name",
+			domain: "lbj23.example.com",
+			public: true,
+			zonePages: map[string]*route53.ListHostedZonesByNameOutput{
+				"lbj23.example.com/": {
+					HostedZones: []*route53.HostedZone{
+						hostedZone("Z1", "lbj23.example.com.", false),
+						hostedZone("Z2", "lbj23.example.com.", true),
+						hostedZone("Z3", "other.example.com.", false),
+					},
+				},
+			},
+			want:      "Z2",
+			wantCalls: []string{"lbj23.example.com/"},
+		},
+		{
+			name:   "zone on the next page",
+			domain: "lbj23.example.com",
+			public: true,
+			zonePages: map[string]*route53.ListHostedZonesByNameOutput{
+				"lbj23.example.com/": {
+					HostedZones: []*route53.HostedZone{
+						hostedZone("Z1", "lbj23.example.com.", false),
+					},
+					IsTruncated:      aws.Bool(true),
+					NextDNSName:      aws.String("lbj23.example.com."),
+					NextHostedZoneId: aws.String("Z2"),
+				},
+				"lbj23.example.com./Z2": {
+					HostedZones: []*route53.HostedZone{
+						hostedZone("Z2", "lbj23.example.com.", true),
+					},
+				},
+			},
+			want:      "Z2",
+			wantCalls: []string{"lbj23.example.com/", "lbj23.example.com./Z2"},
+		},
+		{
+			name:   "next page is skipped once the listing moved past the name",
+			domain: "lbj23.example.com",
+			public: true,
+			zonePages: map[string]*route53.ListHostedZonesByNameOutput{
+				"lbj23.example.com/": {
+					HostedZones: []*route53.HostedZone{
+						hostedZone("Z1", "lbj23.example.com.", false),
+						hostedZone("Z3", "other.example.com.", true),
+					},
+					IsTruncated:      aws.Bool(true),
+					NextDNSName:      aws.String("zzz.example.com."),
+					NextHostedZoneId: aws.String("Z4"),
+				},
+				"example.com/": {
+					HostedZones: []*route53.HostedZone{
+						hostedZone("Z5", "example.com.", true),
+					},
+				},
+			},
+			want:      "Z5",
+			wantCalls: []string{"lbj23.example.com/", "example.com/"},
+		},
+		{
+			name:   "zone of a parent domain",
+			domain: "irsa.lbj23.example.com.",
+			public: false,
+			zonePages: map[string]*route53.ListHostedZonesByNameOutput{
+				"example.com/": {
+					HostedZones: []*route53.HostedZone{
+						hostedZone("Z5", "example.com.", false),
+					},
+				},
+			},
+			want:      "Z5",
+			wantCalls: []string{"irsa.lbj23.example.com/", "lbj23.example.com/", "example.com/"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &fakeRoute53Client{zonePages: tt.zonePages}
+			s := &Service{scope: newFakeScope(), Client: client}
+
+			got, err := s.findHostedZone(tt.domain, tt.public)
+			if err != nil {
+				t.Fatalf("findHostedZone() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("findHostedZone() = %q, want %q", got, tt.want)
+			}
+			if !reflect.DeepEqual(client.listHostedZonesCalls, tt.wantCalls) {
+				t.Errorf("findHostedZone() listed %v, want %v", client.listHostedZonesCalls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func Test_findHostedZone_cache(t *testing.T) {
+	client := &fakeRoute53Client{
+		zonePages: map[string]*route53.ListHostedZonesByNameOutput{
+			"example.com/": {
+				HostedZones: []*route53.HostedZone{
+					hostedZone("Z5", "example.com.", true),
+					hostedZone("Z6", "lbj24.example.com.", true),
+				},
+			},
+		},
+	}
+	s := &Service{scope: newFakeScope(), Client: client}
+
+	for i := 0; i < 2; i++ {
+		got, err := s.findHostedZone("irsa.lbj23.example.com", true)
+		if err != nil {
+			t.Fatalf("findHostedZone() error = %v", err)
+		}
+		if got != "Z5" {
+			t.Errorf("findHostedZone() = %q, want %q", got, "Z5")
+		}
+	}
+	// Missing zones are cached as well, so the second lookup doesn't make any request.
+	if len(client.listHostedZonesCalls) != 3 {
+		t.Errorf("findHostedZone() listed %v, want 3 requests", client.listHostedZonesCalls)
+	}
+
+	// Other zones of a listing are cached along the way.
+	got, err := s.findHostedZone("lbj24.example.com", true)
+	if err != nil {
+		t.Fatalf("findHostedZone() error = %v", err)
+	}
+	if got != "Z6" || len(client.listHostedZonesCalls) != 3 {
+		t.Errorf("findHostedZone() = %q after %d requests, want %q from the cache", got, len(client.listHostedZonesCalls), "Z6")
+	}
+
+	// Not found at all.
+	_, err = s.findHostedZone("irsa.example.org", false)
+	if !IsZoneNotFound(err) {
+		t.Errorf("findHostedZone() error = %v, want zone not found", err)
+	}
+	calls := len(client.listHostedZonesCalls)
+	_, err = s.findHostedZone("irsa.example.org", false)
+	if !IsZoneNotFound(err) {
+		t.Errorf("findHostedZone() error = %v, want zone not found", err)
+	}
+	if len(client.listHostedZonesCalls) != calls {
+		t.Errorf("findHostedZone() listed %v, want the missing zones from the cache", client.listHostedZonesCalls[calls:])
+	}
+}
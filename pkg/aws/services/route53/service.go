@@ -13,9 +13,9 @@ type Service struct {
 }
 
 // NewService returns a new service given the Cloudfront api client.
-func NewService(clusterScope scope.IAMScope) *Service {
+func NewService(clusterScope scope.Route53Scope) *Service {
 	return &Service{
 		scope:  clusterScope,
-		Client: scope.NewRoute53Client(clusterScope, clusterScope.ARN(), clusterScope.Cluster()),
+		Client: scope.NewRoute53Client(clusterScope, clusterScope.DNSRoleARN(), clusterScope.Cluster()),
 	}
 }
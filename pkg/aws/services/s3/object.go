@@ -3,15 +3,17 @@ package s3
 import (
 	"bytes" //#nosec
 	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
 	"fmt"
-	"strings"
+	"io"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/blang/semver"
 	"github.com/giantswarm/microerror"
-	"github.com/peak/s3hash"
 
 	"github.com/giantswarm/irsa-operator/pkg/key"
 	oidc2 "github.com/giantswarm/irsa-operator/pkg/oidc"
@@ -20,9 +22,10 @@ import (
 var objects = []string{".well-known/openid-configuration", "keys.json"}
 
 type FileObject struct {
-	FileName    string
-	Content     *bytes.Reader
-	ContentType string
+	FileName     string
+	Content      *bytes.Reader
+	ContentType  string
+	CacheControl string
 }
 
 func (s *Service) UploadFiles(release *semver.Version, domain, bucketName string, privateKey *rsa.PrivateKey) error {
@@ -36,50 +39,48 @@ func (s *Service) UploadFiles(release *semver.Version, domain, bucketName string
 		return microerror.Mask(err)
 	}
 
-	files := []FileObject{
-		{
-			FileName:    objects[0],
-			Content:     discoveryFile,
-			ContentType: "application/json",
-		},
-		{
-			FileName:    objects[1],
-			Content:     keysFile,
-			ContentType: "application/json",
-		},
-	}
+	files := fileObjects(discoveryFile, keysFile, s.scope.DiscoveryCacheMaxAge(), s.scope.JWKSCacheMaxAge())
 
 	s.scope.Logger().Info("Uploading files to bucket", "bucket", bucketName)
 	for _, i := range files {
 		content := *i.Content
 		fileName := i.FileName
-		i0 := &s3.HeadObjectInput{
-			Bucket: aws.String(bucketName),
-			Key:    aws.String(fileName),
-		}
 
-		eTagCalc, err := s3hash.Calculate(i.Content, int64(i.Content.Len()))
+		checksum, err := checksumSHA256(i.Content)
 		if err != nil {
 			return microerror.Mask(err)
 		}
 
+		// ETags can't be used to detect changes because they are not the MD5 of the content for objects
+		// encrypted with SSE-KMS, so we compare the SHA-256 checksum stored with the object instead.
+		// Objects uploaded without checksum by older versions are uploaded again.
+		ho, err := s.Client.HeadObject(&s3.HeadObjectInput{
+			Bucket:       aws.String(bucketName),
+			Key:          aws.String(fileName),
+			ChecksumMode: aws.String(s3.ChecksumModeEnabled),
+		})
+
 		var update bool
-		ho, err := s.Client.HeadObject(i0)
-		if ho.ETag != nil {
-			if strings.ReplaceAll(*ho.ETag, "\"", "") != eTagCalc {
-				s.scope.Logger().Info(fmt.Sprintf("Hashdiff of object '%s' detected, reuploading", fileName), "bucket", bucketName)
+		if err == nil {
+			if aws.StringValue(ho.ChecksumSHA256) != checksum {
+				s.scope.Logger().Info(fmt.Sprintf("Checksum of object '%s' differs, reuploading", fileName), "bucket", bucketName)
+				update = true
+			} else if aws.StringValue(ho.CacheControl) != i.CacheControl {
+				s.scope.Logger().Info(fmt.Sprintf("Cache-Control of object '%s' differs, reuploading", fileName), "bucket", bucketName)
 				update = true
 			}
-
 		}
 
 		if err != nil || update {
 			input := s3.PutObjectInput{
-				Bucket:        aws.String(bucketName),
-				Key:           aws.String(fileName),
-				ACL:           aws.String("public-read"),
-				ContentType:   aws.String(i.ContentType),
-				ContentLength: aws.Int64(int64(content.Len())),
+				Bucket:            aws.String(bucketName),
+				Key:               aws.String(fileName),
+				ACL:               aws.String("public-read"),
+				CacheControl:      aws.String(i.CacheControl),
+				ChecksumAlgorithm: aws.String(s3.ChecksumAlgorithmSha256),
+				ChecksumSHA256:    aws.String(checksum),
+				ContentType:       aws.String(i.ContentType),
+				ContentLength:     aws.Int64(int64(content.Len())),
 
 				Body: &content,
 			}
@@ -102,6 +103,45 @@ func (s *Service) UploadFiles(release *semver.Version, domain, bucketName string
 	return nil
 }
 
+// fileObjects returns the OIDC documents to upload, each with the Cache-Control header for its max age.
+func fileObjects(discoveryFile, keysFile *bytes.Reader, discoveryCacheMaxAge, jwksCacheMaxAge time.Duration) []FileObject {
+	return []FileObject{
+		{
+			FileName:     objects[0],
+			Content:      discoveryFile,
+			ContentType:  "application/json",
+			CacheControl: cacheControl(discoveryCacheMaxAge),
+		},
+		{
+			FileName:     objects[1],
+			Content:      keysFile,
+			ContentType:  "application/json",
+			CacheControl: cacheControl(jwksCacheMaxAge),
+		},
+	}
+}
+
+// checksumSHA256 returns the base64 encoded SHA-256 checksum of the content in the format used by S3. The reader
+// is rewound afterwards.
+func checksumSHA256(content *bytes.Reader) (string, error) {
+	h := sha256.New()
+	_, err := content.WriteTo(h)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = content.Seek(0, io.SeekStart)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(h.Sum(nil)), nil
+}
+
+func cacheControl(maxAge time.Duration) string {
+	return fmt.Sprintf("max-age=%d", int64(maxAge.Seconds()))
+}
+
 func (s *Service) DeleteFiles(bucketName string) error {
 	var deleteObjects []*s3.ObjectIdentifier
 	for _, obj := range objects {
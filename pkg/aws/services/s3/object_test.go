@@ -0,0 +1,76 @@
+package s3
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+)
+
+func Test_checksumSHA256(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{name: "empty", content: "", want: "47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU="},
+		{name: "content", content: "hello", want: "LPJNul+wow4m6DsqxbninhsWHlwfp0JecwQzYpOLmCQ="},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := bytes.NewReader([]byte(tt.content))
+
+			got, err := checksumSHA256(content)
+			if err != nil {
+				t.Fatalf("checksumSHA256() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("checksumSHA256() = %v, want %v", got, tt.want)
+			}
+
+			// The content is uploaded after computing the checksum, so it has to be readable again.
+			rest, err := io.ReadAll(content)
+			if err != nil {
+				t.Fatalf("reading content after checksumSHA256() error = %v", err)
+			}
+			if string(rest) != tt.content {
+				t.Errorf("content after checksumSHA256() = %q, want %q", rest, tt.content)
+			}
+		})
+	}
+}
+
+func Test_fileObjects(t *testing.T) {
+	files := fileObjects(bytes.NewReader(nil), bytes.NewReader(nil), 5*time.Minute, 24*time.Hour)
+
+	want := map[string]string{
+		".well-known/openid-configuration": "max-age=300",
+		"keys.json":                        "max-age=86400",
+	}
+	if len(files) != len(want) {
+		t.Fatalf("fileObjects() returned %d files, want %d", len(files), len(want))
+	}
+	for _, file := range files {
+		if got := file.CacheControl; got != want[file.FileName] {
+			t.Errorf("fileObjects() Cache-Control of %s = %q, want %q", file.FileName, got, want[file.FileName])
+		}
+	}
+}
+
+func Test_cacheControl(t *testing.T) {
+	tests := []struct {
+		maxAge time.Duration
+		want   string
+	}{
+		{maxAge: 0, want: "max-age=0"},
+		{maxAge: 90 * time.Second, want: "max-age=90"},
+		{maxAge: 1500 * time.Millisecond, want: "max-age=1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := cacheControl(tt.maxAge); got != tt.want {
+				t.Errorf("cacheControl() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,192 @@
+package s3
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+
+	"github.com/giantswarm/irsa-operator/pkg/key"
+)
+
+const replicationRuleID = "irsa-operator-replica"
+
+// replicationConfigurationNotFoundError is returned for buckets without replication, the SDK has no constant for it.
+const replicationConfigurationNotFoundError = "ReplicationConfigurationNotFoundError"
+
+// noReplicationCacheExpiry is how long it is remembered that a bucket doesn't replicate. EnsureReplication forgets
+// it right away, so it only delays noticing replication configured by another instance of the operator.
+const noReplicationCacheExpiry = time.Hour
+
+// EnableVersioning turns on versioning for the bucket, which S3 requires on both sides of a replication.
+func (s *Service) EnableVersioning(bucketName string) error {
+	i := &s3.PutBucketVersioningInput{
+		Bucket: aws.String(bucketName),
+		VersioningConfiguration: &s3.VersioningConfiguration{
+			Status: aws.String(s3.BucketVersioningStatusEnabled),
+		},
+	}
+	_, err := s.Client.PutBucketVersioning(i)
+	if err != nil {
+		return err
+	}
+
+	s.scope.Logger().Info("Enabled versioning for S3 bucket", "bucket", bucketName)
+	return nil
+}
+
+// IsVersioned returns whether versioning was ever enabled for the bucket. Suspending versioning keeps the existing
+// versions, so suspended buckets count as versioned.
+func (s *Service) IsVersioned(bucketName string) (bool, error) {
+	output, err := s.Client.GetBucketVersioning(&s3.GetBucketVersioningInput{Bucket: aws.String(bucketName)})
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == s3.ErrCodeNoSuchBucket {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	return aws.StringValue(output.Status) != "", nil
+}
+
+// EnsureReplication configures replication of all objects from bucketName to replicaBucketName using the
+// given IAM role.
+func (s *Service) EnsureReplication(bucketName, replicaBucketName, roleArn string) error {
+	i := &s3.PutBucketReplicationInput{
+		Bucket: aws.String(bucketName),
+		ReplicationConfiguration: &s3.ReplicationConfiguration{
+			Role: aws.String(roleArn),
+			Rules: []*s3.ReplicationRule{
+				{
+					ID:       aws.String(replicationRuleID),
+					Priority: aws.Int64(1),
+					Status:   aws.String(s3.ReplicationRuleStatusEnabled),
+					Filter: &s3.ReplicationRuleFilter{
+						Prefix: aws.String(""),
+					},
+					DeleteMarkerReplication: &s3.DeleteMarkerReplication{
+						Status: aws.String(s3.DeleteMarkerReplicationStatusDisabled),
+					},
+					Destination: &s3.Destination{
+						Bucket: aws.String(fmt.Sprintf("arn:%s:s3:::%s", key.ARNPrefix(s.scope.Region()), replicaBucketName)),
+					},
+				},
+			},
+		},
+	}
+	_, err := s.Client.PutBucketReplication(i)
+	if err != nil {
+		return err
+	}
+	s.scope.Cache().Delete(noReplicationCacheKey(bucketName))
+
+	s.scope.Logger().Info("Configured replication for S3 bucket", "bucket", bucketName, "replicaBucket", replicaBucketName)
+	return nil
+}
+
+// DeleteFileVersions removes all object versions and delete markers from a versioned bucket, so that the
+// bucket itself can be deleted afterwards.
+func (s *Service) DeleteFileVersions(bucketName string) error {
+	var deleteObjects []*s3.ObjectIdentifier
+	err := s.Client.ListObjectVersionsPages(&s3.ListObjectVersionsInput{Bucket: aws.String(bucketName)}, func(page *s3.ListObjectVersionsOutput, lastPage bool) bool {
+		for _, v := range page.Versions {
+			deleteObjects = append(deleteObjects, &s3.ObjectIdentifier{Key: v.Key, VersionId: v.VersionId})
+		}
+		for _, m := range page.DeleteMarkers {
+			deleteObjects = append(deleteObjects, &s3.ObjectIdentifier{Key: m.Key, VersionId: m.VersionId})
+		}
+		return true
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok {
+			switch aerr.Code() {
+			case s3.ErrCodeNoSuchBucket:
+				s.scope.Logger().Info("Bucket does not exist, skipping file versions deletion", "bucket", bucketName)
+				return nil
+			}
+		}
+		return err
+	}
+
+	// DeleteObjects accepts at most 1000 keys per request.
+	for len(deleteObjects) > 0 {
+		n := min(len(deleteObjects), 1000)
+
+		_, err = s.Client.DeleteObjects(&s3.DeleteObjectsInput{
+			Bucket: aws.String(bucketName),
+			Delete: &s3.Delete{
+				Objects: deleteObjects[:n],
+			},
+		})
+		if err != nil {
+			return err
+		}
+		deleteObjects = deleteObjects[n:]
+	}
+
+	s.scope.Logger().Info("Deleted all file versions from bucket", "bucket", bucketName)
+	return nil
+}
+
+// GetReplicaBucketName returns the destination bucket of the replication configured by EnsureReplication, or an
+// empty string if the bucket doesn't replicate or doesn't exist. Buckets without replication are remembered, as
+// almost all buckets have none and this is checked on every reconciliation.
+func (s *Service) GetReplicaBucketName(bucketName string) (string, error) {
+	cacheKey := noReplicationCacheKey(bucketName)
+	if _, ok := s.scope.Cache().Get(cacheKey); ok {
+		return "", nil
+	}
+
+	output, err := s.Client.GetBucketReplication(&s3.GetBucketReplicationInput{Bucket: aws.String(bucketName)})
+	if aerr, ok := err.(awserr.Error); ok && (aerr.Code() == replicationConfigurationNotFoundError || aerr.Code() == s3.ErrCodeNoSuchBucket) {
+		s.scope.Cache().Set(cacheKey, true, noReplicationCacheExpiry)
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+
+	for _, rule := range output.ReplicationConfiguration.Rules {
+		if aws.StringValue(rule.ID) != replicationRuleID || rule.Destination == nil {
+			continue
+		}
+
+		destination, err := arn.Parse(aws.StringValue(rule.Destination.Bucket))
+		if err != nil {
+			return "", err
+		}
+		return destination.Resource, nil
+	}
+
+	s.scope.Cache().Set(cacheKey, true, noReplicationCacheExpiry)
+	return "", nil
+}
+
+func noReplicationCacheKey(bucketName string) string {
+	return fmt.Sprintf("s3/bucket=%q/no-replication", bucketName)
+}
+
+// DeleteReplication removes the replication configuration of the bucket.
+func (s *Service) DeleteReplication(bucketName string) error {
+	_, err := s.Client.DeleteBucketReplication(&s3.DeleteBucketReplicationInput{Bucket: aws.String(bucketName)})
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == s3.ErrCodeNoSuchBucket {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	s.scope.Logger().Info("Deleted replication for S3 bucket", "bucket", bucketName)
+	return nil
+}
+
+// GetBucketRegion returns the region the bucket was created in.
+func (s *Service) GetBucketRegion(bucketName string) (string, error) {
+	output, err := s.Client.GetBucketLocation(&s3.GetBucketLocationInput{Bucket: aws.String(bucketName)})
+	if err != nil {
+		return "", err
+	}
+
+	// Buckets in us-east-1 have no location constraint.
+	return s3.NormalizeBucketLocation(aws.StringValue(output.LocationConstraint)), nil
+}
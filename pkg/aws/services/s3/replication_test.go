@@ -0,0 +1,112 @@
+package s3
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/go-logr/logr"
+	gocache "github.com/patrickmn/go-cache"
+
+	"github.com/giantswarm/irsa-operator/pkg/aws/scope"
+)
+
+type fakeScope struct {
+	scope.S3Scope
+	cache *gocache.Cache
+}
+
+func newFakeScope() *fakeScope {
+	return &fakeScope{cache: gocache.New(gocache.NoExpiration, 0)}
+}
+
+func (s *fakeScope) Cache() *gocache.Cache { return s.cache }
+func (s *fakeScope) Logger() logr.Logger   { return logr.Discard() }
+func (s *fakeScope) Region() string        { return "eu-west-1" }
+
+type fakeS3Client struct {
+	s3iface.S3API
+	replicaBucketName string
+
+	getReplicationCalls int
+}
+
+func (c *fakeS3Client) GetBucketReplication(*s3.GetBucketReplicationInput) (*s3.GetBucketReplicationOutput, error) {
+	c.getReplicationCalls++
+	if c.replicaBucketName == "" {
+		return nil, awserr.New(replicationConfigurationNotFoundError, "not found", nil)
+	}
+
+	return &s3.GetBucketReplicationOutput{
+		ReplicationConfiguration: &s3.ReplicationConfiguration{
+			Rules: []*s3.ReplicationRule{
+				{
+					ID:          aws.String(replicationRuleID),
+					Destination: &s3.Destination{Bucket: aws.String("arn:aws:s3:::" + c.replicaBucketName)},
+				},
+			},
+		},
+	}, nil
+}
+
+func (c *fakeS3Client) PutBucketReplication(input *s3.PutBucketReplicationInput) (*s3.PutBucketReplicationOutput, error) {
+	c.replicaBucketName = "test-replica"
+	return &s3.PutBucketReplicationOutput{}, nil
+}
+
+func Test_GetReplicaBucketName_cache(t *testing.T) {
+	client := &fakeS3Client{}
+	s := &Service{scope: newFakeScope(), Client: client}
+
+	// The steps run one after the other against the same cache, like consecutive reconciliations.
+	steps := []struct {
+		name      string
+		change    func()
+		want      string
+		wantCalls int
+	}{
+		{
+			name:      "no replication",
+			wantCalls: 1,
+		},
+		{
+			name:      "no replication is remembered",
+			wantCalls: 0,
+		},
+		{
+			name: "replication configured",
+			change: func() {
+				err := s.EnsureReplication("test", "test-replica", "arn:aws:iam::123456789012:role/test")
+				if err != nil {
+					t.Fatalf("EnsureReplication() error = %v", err)
+				}
+			},
+			want:      "test-replica",
+			wantCalls: 1,
+		},
+		{
+			name:      "replication is not remembered",
+			want:      "test-replica",
+			wantCalls: 1,
+		},
+	}
+	for _, step := range steps {
+		if step.change != nil {
+			step.change()
+		}
+
+		client.getReplicationCalls = 0
+		got, err := s.GetReplicaBucketName("test")
+		if err != nil {
+			t.Fatalf("%s: GetReplicaBucketName() error = %v", step.name, err)
+		}
+		if got != step.want {
+			t.Errorf("%s: GetReplicaBucketName() = %q, want %q", step.name, got, step.want)
+		}
+		if client.getReplicationCalls != step.wantCalls {
+			t.Errorf("%s: GetReplicaBucketName() called GetBucketReplication %d times, want %d", step.name, client.getReplicationCalls, step.wantCalls)
+		}
+	}
+}
@@ -19,3 +19,11 @@ func NewService(clusterScope scope.S3Scope) *Service {
 		Client: scope.NewS3Client(clusterScope, clusterScope.ARN(), clusterScope.Cluster()),
 	}
 }
+
+// NewReplicaService returns a new service given the S3 api client for the replica region.
+func NewReplicaService(clusterScope scope.S3Scope, region string) *Service {
+	return &Service{
+		scope:  clusterScope,
+		Client: scope.NewS3ClientForRegion(clusterScope, clusterScope.ARN(), region, clusterScope.Cluster()),
+	}
+}
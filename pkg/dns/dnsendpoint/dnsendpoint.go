@@ -0,0 +1,244 @@
+// Package dnsendpoint implements the DNS provider by creating external-dns `DNSEndpoint` resources, which
+// external-dns applies to whatever DNS provider it is configured for.
+package dnsendpoint
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"github.com/giantswarm/irsa-operator/pkg/aws/scope"
+	"github.com/giantswarm/irsa-operator/pkg/aws/services/route53"
+	"github.com/giantswarm/irsa-operator/pkg/key"
+)
+
+const (
+	clusterLabel   = "giantswarm.io/cluster"
+	managedByLabel = "app.kubernetes.io/managed-by"
+	managedBy      = "irsa-operator"
+
+	recordTTL = 600
+	// resolveTimeout limits how long a reconciliation waits for the DNS lookup of a record.
+	resolveTimeout = 5 * time.Second
+)
+
+var dnsEndpointGVK = schema.GroupVersionKind{
+	Group:   "externaldns.k8s.io",
+	Version: "v1alpha1",
+	Kind:    "DNSEndpoint",
+}
+
+// Service holds a collection of interfaces.
+type Service struct {
+	scope    scope.DNSEndpointScope
+	Client   client.Client
+	Resolver *net.Resolver
+}
+
+// NewService returns a new service creating DNSEndpoint resources with the given client.
+func NewService(clusterScope scope.DNSEndpointScope, client client.Client) *Service {
+	return &Service{
+		scope:    clusterScope,
+		Client:   client,
+		Resolver: net.DefaultResolver,
+	}
+}
+
+// FindPublicHostedZone returns the domain itself, since external-dns picks the zone on its own.
+func (s *Service) FindPublicHostedZone(domain string) (string, error) {
+	return domain, nil
+}
+
+// FindPrivateHostedZone returns an empty string, external-dns doesn't distinguish private zones.
+func (s *Service) FindPrivateHostedZone(domain string) (string, error) {
+	return "", nil
+}
+
+// EnsureDNSRecord makes sure a DNSEndpoint exists for the CNAME record.
+func (s *Service) EnsureDNSRecord(hostedZoneID string, cname route53.CNAME) error {
+	return s.ensureEndpoint(cname.Name, cname.Value)
+}
+
+// EnsureAliasRecords makes sure a DNSEndpoint exists for a CNAME record pointing name at the CloudFront
+// distribution. Alias records are specific to Route53, so a CNAME is the portable equivalent.
+func (s *Service) EnsureAliasRecords(hostedZoneID, name, cloudFrontDomain string) error {
+	return s.ensureEndpoint(name, cloudFrontDomain)
+}
+
+// RecordExists checks whether the DNSEndpoint for the CNAME record exists with the given value.
+func (s *Service) RecordExists(hostedZoneID string, cname route53.CNAME) (bool, error) {
+	endpoint, err := s.getEndpoint(cname.Name)
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	} else if err != nil {
+		return false, microerror.Mask(err)
+	}
+
+	return pointsTo(endpoint, cname.Value), nil
+}
+
+// IsChangeInSync checks whether external-dns processed the latest change of the DNSEndpoint for name and the
+// record resolves to its target.
+func (s *Service) IsChangeInSync(hostedZoneID, name string) (bool, error) {
+	endpoint, err := s.getEndpoint(name)
+	if apierrors.IsNotFound(err) {
+		return true, nil
+	} else if err != nil {
+		return false, microerror.Mask(err)
+	}
+
+	observedGeneration, _, err := unstructured.NestedInt64(endpoint.Object, "status", "observedGeneration")
+	if err != nil {
+		return false, microerror.Mask(err)
+	}
+	if observedGeneration < endpoint.GetGeneration() {
+		s.scope.Logger().Info("DNSEndpoint is not processed by external-dns yet", "name", name, "endpoint", endpoint.GetName())
+		return false, nil
+	}
+
+	target := endpointTarget(endpoint)
+
+	ctx, cancel := context.WithTimeout(context.Background(), resolveTimeout)
+	defer cancel()
+
+	// The canonical name is the end of the CNAME chain, which is the target for the records we manage.
+	resolved, err := s.Resolver.LookupCNAME(ctx, name)
+	if err != nil {
+		s.scope.Logger().Info("DNS record does not resolve yet", "name", name, "error", err.Error())
+		return false, nil
+	}
+	if !strings.EqualFold(key.EnsureTrailingDot(resolved), key.EnsureTrailingDot(target)) {
+		s.scope.Logger().Info("DNS record does not resolve to its target yet", "name", name, "resolved", resolved, "target", target)
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// DeleteDNSRecords deletes the DNSEndpoint for name if it still points at target.
+func (s *Service) DeleteDNSRecords(hostedZoneID, name, target string) error {
+	logger := s.scope.Logger().WithValues("name", name, "target", target)
+
+	endpoint, err := s.getEndpoint(name)
+	if apierrors.IsNotFound(err) {
+		logger.Info("No DNSEndpoint to delete")
+		return nil
+	} else if err != nil {
+		return microerror.Mask(err)
+	}
+
+	if !pointsTo(endpoint, target) {
+		logger.Info("DNSEndpoint does not point at the expected target anymore, skipping deletion")
+		return nil
+	}
+
+	err = s.Client.Delete(context.Background(), endpoint)
+	if client.IgnoreNotFound(err) != nil {
+		return microerror.Mask(err)
+	}
+
+	logger.Info("Deleted DNSEndpoint", "endpoint", endpoint.GetName())
+
+	return nil
+}
+
+func (s *Service) ensureEndpoint(name, target string) error {
+	logger := s.scope.Logger().WithValues("name", name, "target", target)
+
+	logger.Info("Ensuring DNSEndpoint")
+
+	endpoint := &unstructured.Unstructured{}
+	endpoint.SetGroupVersionKind(dnsEndpointGVK)
+	endpoint.SetName(objectName(s.scope.ClusterName(), name))
+	endpoint.SetNamespace(s.scope.ClusterNamespace())
+
+	result, err := controllerutil.CreateOrUpdate(context.Background(), s.Client, endpoint, func() error {
+		labels := endpoint.GetLabels()
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		labels[clusterLabel] = s.scope.ClusterName()
+		labels[managedByLabel] = managedBy
+		endpoint.SetLabels(labels)
+
+		if owner, ok := s.scope.Cluster().(client.Object); ok {
+			// Garbage collection cleans up in case the records were not deleted together with the cluster.
+			err := controllerutil.SetOwnerReference(owner, endpoint, s.Client.Scheme())
+			if err != nil {
+				return microerror.Mask(err)
+			}
+		}
+
+		return unstructured.SetNestedSlice(endpoint.Object, []interface{}{newEndpoint(name, target)}, "spec", "endpoints")
+	})
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	logger.Info("Ensured DNSEndpoint", "endpoint", endpoint.GetName(), "result", result)
+
+	return nil
+}
+
+func (s *Service) getEndpoint(name string) (*unstructured.Unstructured, error) {
+	endpoint := &unstructured.Unstructured{}
+	endpoint.SetGroupVersionKind(dnsEndpointGVK)
+
+	err := s.Client.Get(context.Background(), types.NamespacedName{Namespace: s.scope.ClusterNamespace(), Name: objectName(s.scope.ClusterName(), name)}, endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	return endpoint, nil
+}
+
+// objectName returns the name of the DNSEndpoint for a record. Record names can be longer than allowed for object
+// names and start with an underscore, so they are hashed.
+func objectName(clusterName, recordName string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(strings.TrimSuffix(recordName, "."))))
+	return fmt.Sprintf("%s-irsa-%s", clusterName, hex.EncodeToString(sum[:])[:10])
+}
+
+func newEndpoint(name, target string) map[string]interface{} {
+	return map[string]interface{}{
+		"dnsName":    strings.TrimSuffix(name, "."),
+		"recordTTL":  int64(recordTTL),
+		"recordType": "CNAME",
+		"targets":    []interface{}{strings.TrimSuffix(target, ".")},
+	}
+}
+
+// endpointTarget returns the target of the DNSEndpoint's record, or an empty string if it has none.
+func endpointTarget(endpoint *unstructured.Unstructured) string {
+	endpoints, _, _ := unstructured.NestedSlice(endpoint.Object, "spec", "endpoints")
+	if len(endpoints) != 1 {
+		return ""
+	}
+
+	e, ok := endpoints[0].(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	targets, _, _ := unstructured.NestedStringSlice(e, "targets")
+	if len(targets) != 1 {
+		return ""
+	}
+
+	return targets[0]
+}
+
+func pointsTo(endpoint *unstructured.Unstructured, target string) bool {
+	return strings.EqualFold(key.EnsureTrailingDot(endpointTarget(endpoint)), key.EnsureTrailingDot(target))
+}
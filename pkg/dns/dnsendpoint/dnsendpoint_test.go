@@ -0,0 +1,51 @@
+package dnsendpoint
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+func Test_objectName(t *testing.T) {
+	name := objectName("test", "_3639ac514e785e898d2646601fa951d5.irsa.test.gigantic.io.")
+
+	if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+		t.Errorf("objectName() = %q is not a valid object name: %v", name, errs)
+	}
+	if got := objectName("test", "_3639AC514E785E898D2646601FA951D5.irsa.test.gigantic.io"); got != name {
+		t.Errorf("objectName() = %q, want %q regardless of case and trailing dot", got, name)
+	}
+	if got := objectName("test", "irsa.test.gigantic.io"); got == name {
+		t.Errorf("objectName() = %q for different records", got)
+	}
+}
+
+func Test_pointsTo(t *testing.T) {
+	endpoint := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{
+			"endpoints": []interface{}{newEndpoint("irsa.test.gigantic.io.", "d111.cloudfront.net.")},
+		},
+	}}
+
+	tests := []struct {
+		name   string
+		target string
+		want   bool
+	}{
+		{name: "same target", target: "d111.cloudfront.net", want: true},
+		{name: "same target with trailing dot", target: "D111.cloudfront.net.", want: true},
+		{name: "other target", target: "d222.cloudfront.net", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := pointsTo(endpoint, tt.target); got != tt.want {
+				t.Errorf("pointsTo() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if got := endpointTarget(&unstructured.Unstructured{Object: map[string]interface{}{}}); got != "" {
+		t.Errorf("endpointTarget() = %q for endpoint without records, want empty string", got)
+	}
+}
@@ -0,0 +1,77 @@
+// Package dns defines how the reconcilers manage the DNS records of the CloudFront aliases and ACM certificate
+// validation, so that it doesn't matter whether they are written to Route53 directly or by external-dns.
+package dns
+
+import (
+	"github.com/giantswarm/microerror"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/giantswarm/irsa-operator/pkg/aws/scope"
+	"github.com/giantswarm/irsa-operator/pkg/aws/services/route53"
+	"github.com/giantswarm/irsa-operator/pkg/dns/dnsendpoint"
+	ctrlmetrics "github.com/giantswarm/irsa-operator/pkg/metrics"
+)
+
+const (
+	// ProviderRoute53 writes the records to Route53 directly.
+	ProviderRoute53 = "route53"
+	// ProviderDNSEndpoint creates external-dns `DNSEndpoint` resources for the records.
+	ProviderDNSEndpoint = "dnsendpoint"
+)
+
+// Provider manages DNS records. Hosted zone IDs are opaque to the reconcilers, they are only passed back to the
+// provider that returned them.
+type Provider interface {
+	// FindPublicHostedZone returns the ID of the closest public hosted zone enclosing domain.
+	FindPublicHostedZone(domain string) (string, error)
+	// FindPrivateHostedZone returns the ID of the closest private hosted zone enclosing domain, or an empty string if
+	// the provider doesn't distinguish private zones.
+	FindPrivateHostedZone(domain string) (string, error)
+
+	// EnsureDNSRecord makes sure the CNAME record exists.
+	EnsureDNSRecord(hostedZoneID string, cname route53.CNAME) error
+	// EnsureAliasRecords makes sure name points at the given CloudFront distribution domain.
+	EnsureAliasRecords(hostedZoneID, name, cloudFrontDomain string) error
+	// RecordExists checks whether the CNAME record exists with the given value.
+	RecordExists(hostedZoneID string, cname route53.CNAME) (bool, error)
+	// IsChangeInSync checks whether the last change of the records for name has been applied. Route53 changes are
+	// only tracked in memory, so changes made before a restart of the operator are considered applied.
+	IsChangeInSync(hostedZoneID, name string) (bool, error)
+	// DeleteDNSRecords deletes the records for name that still point at target.
+	DeleteDNSRecords(hostedZoneID, name, target string) error
+}
+
+var (
+	_ Provider = &route53.Service{}
+	_ Provider = &dnsendpoint.Service{}
+)
+
+// NewProvider returns the DNS provider configured in the cluster scope, falling back to Route53.
+func NewProvider(clusterScope *scope.ClusterScope, client client.Client) Provider {
+	if clusterScope.DNSProvider() == ProviderDNSEndpoint {
+		return dnsendpoint.NewService(clusterScope, client)
+	}
+
+	return route53.NewService(clusterScope)
+}
+
+// ChangeInSync checks whether the last changes of the records for name in the given hosted zones have been applied
+// and exposes the result as metric.
+func ChangeInSync(provider Provider, clusterScope *scope.ClusterScope, name string, hostedZoneIDs ...string) (bool, error) {
+	inSync := true
+	for _, hostedZoneID := range hostedZoneIDs {
+		zoneInSync, err := provider.IsChangeInSync(hostedZoneID, name)
+		if err != nil {
+			return false, microerror.Mask(err)
+		}
+		inSync = inSync && zoneInSync
+	}
+
+	pending := 0.0
+	if !inSync {
+		pending = 1
+	}
+	ctrlmetrics.DNSChangePending.WithLabelValues(clusterScope.Installation(), clusterScope.AccountID(), clusterScope.ClusterName(), clusterScope.ClusterNamespace(), name).Set(pending)
+
+	return inSync, nil
+}
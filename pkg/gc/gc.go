@@ -0,0 +1,333 @@
+// Package gc implements the garbage collection of AWS resources that are tagged with the installation but belong to
+// no existing cluster anymore, e.g. because the cluster was deleted while the operator was paused for it or its
+// deletion failed halfway.
+//
+// The accounts of AWSClusterRoleIdentity objects are checked, as well as the additional accounts the OIDC providers
+// of existing clusters are registered in (see key.IRSARegisteredAccountRolesAnnotation). Additional accounts only used
+// by deleted clusters are not checked, since the role to access them is only known from the annotation of the cluster,
+// which is gone with the cluster.
+package gc
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/arn"
+	infrastructurev1alpha3 "github.com/giantswarm/apiextensions/v6/pkg/apis/infrastructure/v1alpha3"
+	"github.com/giantswarm/microerror"
+	"github.com/go-logr/logr"
+	gocache "github.com/patrickmn/go-cache"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	capa "sigs.k8s.io/cluster-api-provider-aws/v2/api/v1beta2"
+	eks "sigs.k8s.io/cluster-api-provider-aws/v2/controlplane/eks/api/v1beta2"
+	capi "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/giantswarm/irsa-operator/pkg/aws/scope"
+	"github.com/giantswarm/irsa-operator/pkg/key"
+	ctrlmetrics "github.com/giantswarm/irsa-operator/pkg/metrics"
+)
+
+const (
+	ResourceTypeBucket       = "s3_bucket"
+	ResourceTypeCertificate  = "acm_certificate"
+	ResourceTypeDistribution = "cloudfront_distribution"
+	ResourceTypeOIDCProvider = "iam_oidc_provider"
+	ResourceTypeRole         = "iam_role"
+)
+
+// resourceTypes are ordered so that resources are deleted before the ones they use.
+var resourceTypes = []string{ResourceTypeDistribution, ResourceTypeCertificate, ResourceTypeBucket, ResourceTypeRole, ResourceTypeOIDCProvider}
+
+// accountRole is the role the resources of an account are accessed with.
+type accountRole struct {
+	ARN arn.ARN
+	// Name is the name of the AWSClusterRoleIdentity or the cluster the role is registered with, for logging.
+	Name string
+	// Target is the object AWS permission issues are reported on as events.
+	Target runtime.Object
+}
+
+// resource is an AWS resource tagged with the installation.
+type resource struct {
+	Type        string
+	ID          string
+	ClusterName string
+	// Region is only set for buckets, which have to be accessed in their region.
+	Region string
+}
+
+// +kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=awsclusterroleidentities,verbs=get;list;watch
+// +kubebuilder:rbac:groups=cluster.x-k8s.io,resources=clusters,verbs=get;list;watch
+// +kubebuilder:rbac:groups=controlplane.cluster.x-k8s.io,resources=awsmanagedcontrolplanes,verbs=get;list;watch
+
+// Collector periodically looks for orphaned resources in the accounts of all AWSClusterRoleIdentity objects and the
+// additional accounts of the clusters, reports them as metrics and deletes them once they were orphaned for the grace
+// period, if deletion is enabled.
+type Collector struct {
+	Client       client.Client
+	Log          logr.Logger
+	Installation string
+	// Cache is shared with the reconcilers, so that the OIDC providers of an account are only fetched once.
+	Cache *gocache.Cache
+
+	DeleteOrphans bool
+	GracePeriod   time.Duration
+	Interval      time.Duration
+
+	// orphanedSince keeps track of when resources were first seen orphaned. It is not persisted, so the grace
+	// period starts over when the operator restarts.
+	orphanedSince map[string]time.Time
+}
+
+// NeedLeaderElection makes sure only one replica collects garbage.
+func (c *Collector) NeedLeaderElection() bool {
+	return true
+}
+
+// Start runs the garbage collection every interval until the context is done.
+func (c *Collector) Start(ctx context.Context) error {
+	c.orphanedSince = map[string]time.Time{}
+
+	ticker := time.NewTicker(c.Interval)
+	defer ticker.Stop()
+
+	for {
+		err := c.collect(ctx)
+		if err != nil {
+			c.Log.Error(err, "failed to collect orphaned resources")
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *Collector) collect(ctx context.Context) error {
+	c.Log.Info("Collecting orphaned resources")
+
+	// Resources are only ever deleted based on a complete list of clusters.
+	clusterNames, registeredRoles, err := c.existingClusters(ctx)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	identities := &capa.AWSClusterRoleIdentityList{}
+	err = c.Client.List(ctx, identities)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	now := time.Now()
+	seen := map[string]bool{}
+	for _, role := range accountRoles(identities.Items, registeredRoles) {
+		roleARN := role.ARN
+		logger := c.Log.WithValues("accountID", roleARN.AccountID, "role", role.Name)
+
+		accountScope, err := scope.NewAccountScope(scope.AccountScopeParams{
+			AccountID:    roleARN.AccountID,
+			ARN:          roleARN.String(),
+			Cache:        c.Cache,
+			Installation: c.Installation,
+			Region:       sessionRegion(roleARN.Partition),
+			Target:       role.Target,
+
+			Logger: logger,
+		})
+		if err != nil {
+			logger.Error(err, "failed to create account scope")
+			keepAccount(c.orphanedSince, seen, roleARN.AccountID)
+			continue
+		}
+
+		account := newAccountResources(accountScope, roleARN.Partition)
+
+		resources, err := account.list()
+		if err != nil {
+			logger.Error(err, "failed to list resources of the installation")
+			keepAccount(c.orphanedSince, seen, roleARN.AccountID)
+			continue
+		}
+
+		orphans := findOrphans(resources, clusterNames, c.Installation)
+
+		counts := map[string]int{}
+		for _, orphan := range orphans {
+			counts[orphan.Type]++
+		}
+		for _, resourceType := range resourceTypes {
+			ctrlmetrics.GCOrphanedResources.WithLabelValues(c.Installation, roleARN.AccountID, resourceType).Set(float64(counts[resourceType]))
+		}
+
+		for _, orphan := range orphans {
+			orphanKey := roleARN.AccountID + "/" + orphan.ID
+			seen[orphanKey] = true
+			if _, ok := c.orphanedSince[orphanKey]; !ok {
+				c.orphanedSince[orphanKey] = now
+			}
+
+			orphanLogger := logger.WithValues("type", orphan.Type, "id", orphan.ID, "cluster", orphan.ClusterName, "orphanedSince", c.orphanedSince[orphanKey])
+			if !c.DeleteOrphans || now.Sub(c.orphanedSince[orphanKey]) < c.GracePeriod {
+				orphanLogger.Info("Found orphaned resource")
+				continue
+			}
+
+			deleted, err := account.delete(orphan)
+			if err != nil {
+				orphanLogger.Error(err, "failed to delete orphaned resource")
+				continue
+			}
+			if deleted {
+				orphanLogger.Info("Deleted orphaned resource")
+				ctrlmetrics.GCDeletedResources.WithLabelValues(c.Installation, roleARN.AccountID, orphan.Type).Inc()
+				delete(c.orphanedSince, orphanKey)
+			}
+		}
+	}
+
+	for orphanKey := range c.orphanedSince {
+		if !seen[orphanKey] {
+			delete(c.orphanedSince, orphanKey)
+		}
+	}
+
+	return nil
+}
+
+// existingClusters returns the names of all clusters of the installation, regardless of which controller manages
+// them, and the additional account roles their OIDC providers are registered with. Legacy clusters are only listed if
+// their CRD is installed.
+func (c *Collector) existingClusters(ctx context.Context) (map[string]bool, []accountRole, error) {
+	names := map[string]bool{}
+	registeredRoles := make([]accountRole, 0)
+
+	clusters := &capi.ClusterList{}
+	err := c.Client.List(ctx, clusters)
+	if err != nil {
+		return nil, nil, microerror.Mask(err)
+	}
+	for _, cluster := range clusters.Items {
+		names[cluster.Name] = true
+	}
+
+	awsClusters := &capa.AWSClusterList{}
+	err = c.Client.List(ctx, awsClusters)
+	if err != nil {
+		return nil, nil, microerror.Mask(err)
+	}
+	for i := range awsClusters.Items {
+		names[awsClusters.Items[i].Name] = true
+		registeredRoles = append(registeredRoles, registeredAccountRoles(&awsClusters.Items[i])...)
+	}
+
+	controlPlanes := &eks.AWSManagedControlPlaneList{}
+	err = c.Client.List(ctx, controlPlanes)
+	if err != nil {
+		return nil, nil, microerror.Mask(err)
+	}
+	for i := range controlPlanes.Items {
+		names[controlPlanes.Items[i].Name] = true
+		registeredRoles = append(registeredRoles, registeredAccountRoles(&controlPlanes.Items[i])...)
+	}
+
+	legacyClusters := &infrastructurev1alpha3.AWSClusterList{}
+	err = c.Client.List(ctx, legacyClusters)
+	if err != nil && !meta.IsNoMatchError(err) {
+		return nil, nil, microerror.Mask(err)
+	}
+	for i := range legacyClusters.Items {
+		names[legacyClusters.Items[i].Name] = true
+		registeredRoles = append(registeredRoles, registeredAccountRoles(&legacyClusters.Items[i])...)
+	}
+
+	return names, registeredRoles, nil
+}
+
+// registeredAccountRoles returns the additional account roles the OIDC providers of the cluster are registered with.
+// Invalid values are skipped.
+func registeredAccountRoles(cluster client.Object) []accountRole {
+	roles := make([]accountRole, 0)
+	for _, roleARN := range strings.Split(cluster.GetAnnotations()[key.IRSARegisteredAccountRolesAnnotation], ",") {
+		parsed, err := arn.Parse(strings.TrimSpace(roleARN))
+		if err != nil {
+			continue
+		}
+		roles = append(roles, accountRole{ARN: parsed, Name: cluster.GetNamespace() + "/" + cluster.GetName(), Target: cluster})
+	}
+
+	return roles
+}
+
+// accountRoles returns one role per account. The first identity by name is preferred, accounts without identity are
+// accessed with the first registered additional account role by ARN. Identities with invalid role ARNs are skipped.
+func accountRoles(identities []capa.AWSClusterRoleIdentity, registeredRoles []accountRole) []accountRole {
+	sort.Slice(identities, func(i, j int) bool { return identities[i].Name < identities[j].Name })
+	sort.SliceStable(registeredRoles, func(i, j int) bool { return registeredRoles[i].ARN.String() < registeredRoles[j].ARN.String() })
+
+	roles := make([]accountRole, 0)
+	for _, identity := range identities {
+		roleARN, err := arn.Parse(identity.Spec.RoleArn)
+		if err != nil {
+			continue
+		}
+		roles = append(roles, accountRole{ARN: roleARN, Name: identity.Name, Target: identity.DeepCopy()})
+	}
+	roles = append(roles, registeredRoles...)
+
+	accounts := map[string]bool{}
+	ret := make([]accountRole, 0)
+	for _, role := range roles {
+		if accounts[role.ARN.AccountID] {
+			continue
+		}
+		accounts[role.ARN.AccountID] = true
+		ret = append(ret, role)
+	}
+
+	return ret
+}
+
+// findOrphans returns the resources whose cluster doesn't exist, ordered by resourceTypes. Resources tagged with the
+// installation as cluster, like the OIDC provider of the management cluster in workload cluster accounts, are shared
+// by the clusters of the account and never orphaned.
+func findOrphans(resources []resource, clusterNames map[string]bool, installation string) []resource {
+	orphans := make([]resource, 0)
+	for _, resourceType := range resourceTypes {
+		for _, r := range resources {
+			if r.Type == resourceType && r.ClusterName != installation && !clusterNames[r.ClusterName] {
+				orphans = append(orphans, r)
+			}
+		}
+	}
+
+	return orphans
+}
+
+// keepAccount marks the orphans of an account that could not be checked as seen, so that their grace period
+// continues.
+func keepAccount(orphanedSince map[string]time.Time, seen map[string]bool, accountID string) {
+	for orphanKey := range orphanedSince {
+		if strings.HasPrefix(orphanKey, accountID+"/") {
+			seen[orphanKey] = true
+		}
+	}
+}
+
+// sessionRegion returns a region of the partition to create the session in. All resources are either global or
+// accessed in a specific region anyway.
+func sessionRegion(partition string) string {
+	switch partition {
+	case "aws-cn":
+		return "cn-north-1"
+	case "aws-us-gov":
+		return "us-gov-west-1"
+	default:
+		return "us-east-1"
+	}
+}
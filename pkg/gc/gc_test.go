@@ -0,0 +1,168 @@
+package gc
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudfront"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	capa "sigs.k8s.io/cluster-api-provider-aws/v2/api/v1beta2"
+
+	"github.com/giantswarm/irsa-operator/pkg/key"
+)
+
+func Test_findOrphans(t *testing.T) {
+	resources := []resource{
+		{Type: ResourceTypeOIDCProvider, ID: "provider-a", ClusterName: "a"},
+		{Type: ResourceTypeBucket, ID: "bucket-gone", ClusterName: "gone"},
+		{Type: ResourceTypeOIDCProvider, ID: "provider-gone", ClusterName: "gone"},
+		{Type: ResourceTypeRole, ID: "role-gone", ClusterName: "gone"},
+		{Type: ResourceTypeDistribution, ID: "distribution-gone", ClusterName: "gone"},
+		{Type: ResourceTypeOIDCProvider, ID: "provider-mc", ClusterName: "golem"},
+	}
+
+	want := []resource{
+		{Type: ResourceTypeDistribution, ID: "distribution-gone", ClusterName: "gone"},
+		{Type: ResourceTypeBucket, ID: "bucket-gone", ClusterName: "gone"},
+		{Type: ResourceTypeRole, ID: "role-gone", ClusterName: "gone"},
+		{Type: ResourceTypeOIDCProvider, ID: "provider-gone", ClusterName: "gone"},
+	}
+
+	tests := []struct {
+		name         string
+		clusterNames map[string]bool
+	}{
+		{
+			name:         "management cluster exists as cluster",
+			clusterNames: map[string]bool{"a": true, "golem": true},
+		},
+		{
+			// The management cluster has no cluster object named after the installation, its OIDC provider in the
+			// workload cluster accounts is still no orphan.
+			name:         "management cluster has no cluster object",
+			clusterNames: map[string]bool{"a": true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := findOrphans(resources, tt.clusterNames, "golem")
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("findOrphans() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func Test_accountRoles(t *testing.T) {
+	identity := func(name, roleARN string) capa.AWSClusterRoleIdentity {
+		return capa.AWSClusterRoleIdentity{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       capa.AWSClusterRoleIdentitySpec{AWSRoleSpec: capa.AWSRoleSpec{RoleArn: roleARN}},
+		}
+	}
+
+	cluster := &capa.AWSCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "org-test",
+			Annotations: map[string]string{
+				key.IRSARegisteredAccountRolesAnnotation: "arn:aws:iam::333333333333:role/z,arn:aws:iam::210987654321:role/registered, not-an-arn,arn:aws:iam::333333333333:role/y",
+			},
+		},
+	}
+
+	got := accountRoles([]capa.AWSClusterRoleIdentity{
+		identity("b", "arn:aws:iam::123456789012:role/b"),
+		identity("a", "arn:aws:iam::123456789012:role/a"),
+		identity("c", "arn:aws:iam::210987654321:role/c"),
+		identity("invalid", "not-an-arn"),
+	}, registeredAccountRoles(cluster))
+
+	var roleARNs []string
+	for _, role := range got {
+		roleARNs = append(roleARNs, role.ARN.String())
+	}
+	want := []string{
+		"arn:aws:iam::123456789012:role/a",
+		"arn:aws:iam::210987654321:role/c",
+		"arn:aws:iam::333333333333:role/y",
+	}
+	if !reflect.DeepEqual(roleARNs, want) {
+		t.Errorf("accountRoles() = %v, want %v", roleARNs, want)
+	}
+	if got[2].Name != "org-test/test" || got[2].Target != cluster {
+		t.Errorf("accountRoles() registered role = %q with target %v, want the cluster", got[2].Name, got[2].Target)
+	}
+}
+
+func Test_keepAccount(t *testing.T) {
+	orphanedSince := map[string]time.Time{
+		"123456789012/provider": {},
+		"1234567890123/bucket":  {},
+	}
+	seen := map[string]bool{}
+
+	keepAccount(orphanedSince, seen, "123456789012")
+
+	if want := map[string]bool{"123456789012/provider": true}; !reflect.DeepEqual(seen, want) {
+		t.Errorf("keepAccount() seen = %v, want %v", seen, want)
+	}
+}
+
+func Test_isOIDCBucket(t *testing.T) {
+	tests := []struct {
+		bucketName string
+		want       bool
+	}{
+		{bucketName: "123456789012-g8s-test-oidc-pod-identity", want: true},
+		{bucketName: "123456789012-g8s-test-oidc-pod-identity-v3", want: true},
+		{bucketName: "123456789012-g8s-test-oidc-pod-identity-v3-replica", want: true},
+		{bucketName: "210987654321-g8s-test-oidc-pod-identity-v3", want: false},
+		{bucketName: "123456789012-g8s-test-access-logs", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.bucketName, func(t *testing.T) {
+			if got := isOIDCBucket(tt.bucketName, "123456789012"); got != tt.want {
+				t.Errorf("isOIDCBucket() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_isReplicationRole(t *testing.T) {
+	tests := []struct {
+		roleName string
+		want     bool
+	}{
+		{roleName: key.ReplicationRoleName("golem", "test"), want: true},
+		{roleName: key.ReplicationRoleName("golem", "a-very-long-cluster-name-exceeding-the-length-of-role-names"), want: true},
+		{roleName: "golem-test-capa-controller", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.roleName, func(t *testing.T) {
+			if got := isReplicationRole(tt.roleName); got != tt.want {
+				t.Errorf("isReplicationRole() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_originAccessIdentityID(t *testing.T) {
+	config := &cloudfront.DistributionConfig{
+		Origins: &cloudfront.Origins{
+			Items: []*cloudfront.Origin{
+				{CustomOriginConfig: &cloudfront.CustomOriginConfig{}},
+				{S3OriginConfig: &cloudfront.S3OriginConfig{OriginAccessIdentity: aws.String("origin-access-identity/cloudfront/E2QWRUHAPOMQZL")}},
+			},
+		},
+	}
+
+	if got := originAccessIdentityID(config); got != "E2QWRUHAPOMQZL" {
+		t.Errorf("originAccessIdentityID() = %q, want %q", got, "E2QWRUHAPOMQZL")
+	}
+	if got := originAccessIdentityID(&cloudfront.DistributionConfig{}); got != "" {
+		t.Errorf("originAccessIdentityID() = %q for distribution without origins, want empty string", got)
+	}
+}
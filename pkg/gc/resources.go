@@ -0,0 +1,453 @@
+package gc
+
+import (
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/acm"
+	"github.com/aws/aws-sdk-go/service/cloudfront"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/irsa-operator/pkg/aws/scope"
+	iamservice "github.com/giantswarm/irsa-operator/pkg/aws/services/iam"
+	s3service "github.com/giantswarm/irsa-operator/pkg/aws/services/s3"
+	"github.com/giantswarm/irsa-operator/pkg/key"
+)
+
+// accountResources lists and deletes the resources of the installation in an account.
+type accountResources struct {
+	scope *scope.AccountScope
+	// CloudFront and ACM are only used in the commercial partition.
+	withCloudFront bool
+
+	acm        *acm.ACM
+	cloudfront *cloudfront.CloudFront
+	iam        *iam.IAM
+	// iamService shares the index of the account's OIDC providers with the reconcilers.
+	iamService *iamservice.Service
+	s3         *s3.S3
+	s3Regional map[string]*s3service.Service
+}
+
+func newAccountResources(accountScope *scope.AccountScope, partition string) *accountResources {
+	return &accountResources{
+		scope:          accountScope,
+		withCloudFront: partition == "aws",
+
+		acm:        scope.NewACMClient(accountScope, accountScope.ARN(), accountScope.Target()),
+		cloudfront: scope.NewCloudfrontClient(accountScope, accountScope.ARN(), accountScope.Target()),
+		iam:        scope.NewIAMClient(accountScope, accountScope.ARN(), accountScope.Target()),
+		iamService: iamservice.NewService(accountScope),
+		s3:         scope.NewS3Client(accountScope, accountScope.ARN(), accountScope.Target()),
+		s3Regional: map[string]*s3service.Service{},
+	}
+}
+
+// list returns all resources tagged with the installation and a cluster.
+func (a *accountResources) list() ([]resource, error) {
+	resources, err := a.listOIDCProviders()
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	roles, err := a.listReplicationRoles()
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	resources = append(resources, roles...)
+
+	buckets, err := a.listBuckets()
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	resources = append(resources, buckets...)
+
+	if !a.withCloudFront {
+		return resources, nil
+	}
+
+	distributions, err := a.listDistributions()
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	resources = append(resources, distributions...)
+
+	certificates, err := a.listCertificates()
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	resources = append(resources, certificates...)
+
+	return resources, nil
+}
+
+// delete deletes the resource and returns whether it is gone. Distributions take several runs, since they have to be
+// disabled first.
+func (a *accountResources) delete(r resource) (bool, error) {
+	switch r.Type {
+	case ResourceTypeOIDCProvider:
+		return a.deleteOIDCProvider(r)
+	case ResourceTypeRole:
+		return a.deleteReplicationRole(r)
+	case ResourceTypeBucket:
+		return a.deleteBucket(r)
+	case ResourceTypeDistribution:
+		return a.deleteDistribution(r)
+	case ResourceTypeCertificate:
+		return a.deleteCertificate(r)
+	}
+
+	return false, nil
+}
+
+// clusterOf returns the cluster the tags belong to, if they belong to the installation.
+func (a *accountResources) clusterOf(tags map[string]string) (string, bool) {
+	if tags[key.S3TagInstallation] != a.scope.Installation() || tags[key.S3TagCluster] == "" {
+		return "", false
+	}
+
+	return tags[key.S3TagCluster], true
+}
+
+func (a *accountResources) listOIDCProviders() ([]resource, error) {
+	providers, err := a.iamService.ListOIDCProviders()
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	resources := make([]resource, 0)
+	for providerArn, p := range providers {
+		tags := map[string]string{}
+		for _, tag := range p.Tags {
+			tags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+		}
+		if clusterName, ok := a.clusterOf(tags); ok {
+			resources = append(resources, resource{Type: ResourceTypeOIDCProvider, ID: providerArn, ClusterName: clusterName})
+		}
+	}
+
+	return resources, nil
+}
+
+func (a *accountResources) deleteOIDCProvider(r resource) (bool, error) {
+	err := a.iamService.DeleteOIDCProvider(r.ID, a.scope.Logger())
+	if err != nil {
+		return false, microerror.Mask(err)
+	}
+
+	return true, nil
+}
+
+func (a *accountResources) listReplicationRoles() ([]resource, error) {
+	var roleNames []string
+	err := a.iam.ListRolesPages(&iam.ListRolesInput{}, func(page *iam.ListRolesOutput, lastPage bool) bool {
+		for _, role := range page.Roles {
+			if isReplicationRole(aws.StringValue(role.RoleName)) {
+				roleNames = append(roleNames, aws.StringValue(role.RoleName))
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	resources := make([]resource, 0)
+	for _, roleName := range roleNames {
+		output, err := a.iam.ListRoleTags(&iam.ListRoleTagsInput{RoleName: aws.String(roleName)})
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == iam.ErrCodeNoSuchEntityException {
+			continue
+		} else if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		tags := map[string]string{}
+		for _, tag := range output.Tags {
+			tags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+		}
+		if clusterName, ok := a.clusterOf(tags); ok {
+			resources = append(resources, resource{Type: ResourceTypeRole, ID: roleName, ClusterName: clusterName})
+		}
+	}
+
+	return resources, nil
+}
+
+// deleteReplicationRole deletes the inline policies of the role, which IAM requires before the role can be deleted.
+func (a *accountResources) deleteReplicationRole(r resource) (bool, error) {
+	var policyNames []*string
+	err := a.iam.ListRolePoliciesPages(&iam.ListRolePoliciesInput{RoleName: aws.String(r.ID)}, func(page *iam.ListRolePoliciesOutput, lastPage bool) bool {
+		policyNames = append(policyNames, page.PolicyNames...)
+		return true
+	})
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == iam.ErrCodeNoSuchEntityException {
+		return true, nil
+	} else if err != nil {
+		return false, microerror.Mask(err)
+	}
+
+	for _, policyName := range policyNames {
+		_, err = a.iam.DeleteRolePolicy(&iam.DeleteRolePolicyInput{PolicyName: policyName, RoleName: aws.String(r.ID)})
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == iam.ErrCodeNoSuchEntityException {
+			continue
+		} else if err != nil {
+			return false, microerror.Mask(err)
+		}
+	}
+
+	_, err = a.iam.DeleteRole(&iam.DeleteRoleInput{RoleName: aws.String(r.ID)})
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == iam.ErrCodeNoSuchEntityException {
+		return true, nil
+	} else if err != nil {
+		return false, microerror.Mask(err)
+	}
+
+	return true, nil
+}
+
+func (a *accountResources) listBuckets() ([]resource, error) {
+	output, err := a.s3.ListBuckets(&s3.ListBucketsInput{})
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	resources := make([]resource, 0)
+	for _, bucket := range output.Buckets {
+		bucketName := aws.StringValue(bucket.Name)
+		if !isOIDCBucket(bucketName, a.scope.AccountID()) {
+			continue
+		}
+
+		location, err := a.s3.GetBucketLocation(&s3.GetBucketLocationInput{Bucket: bucket.Name})
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == s3.ErrCodeNoSuchBucket {
+			continue
+		} else if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		region := s3.NormalizeBucketLocation(aws.StringValue(location.LocationConstraint))
+
+		tagging, err := a.s3Service(region).Client.GetBucketTagging(&s3.GetBucketTaggingInput{Bucket: bucket.Name})
+		if aerr, ok := err.(awserr.Error); ok && (aerr.Code() == "NoSuchTagSet" || aerr.Code() == s3.ErrCodeNoSuchBucket) {
+			continue
+		} else if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		tags := map[string]string{}
+		for _, tag := range tagging.TagSet {
+			tags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+		}
+		if clusterName, ok := a.clusterOf(tags); ok {
+			resources = append(resources, resource{Type: ResourceTypeBucket, ID: bucketName, ClusterName: clusterName, Region: region})
+		}
+	}
+
+	return resources, nil
+}
+
+func (a *accountResources) deleteBucket(r resource) (bool, error) {
+	service := a.s3Service(r.Region)
+
+	// Listing versions also covers buckets without versioning, their objects have the `null` version.
+	err := service.DeleteFileVersions(r.ID)
+	if err != nil {
+		return false, microerror.Mask(err)
+	}
+
+	err = service.DeleteBucket(r.ID)
+	if err != nil {
+		return false, microerror.Mask(err)
+	}
+
+	return true, nil
+}
+
+func (a *accountResources) s3Service(region string) *s3service.Service {
+	if _, ok := a.s3Regional[region]; !ok {
+		a.s3Regional[region] = s3service.NewReplicaService(a.scope, region)
+	}
+
+	return a.s3Regional[region]
+}
+
+func (a *accountResources) listDistributions() ([]resource, error) {
+	var summaries []*cloudfront.DistributionSummary
+	err := a.cloudfront.ListDistributionsPages(&cloudfront.ListDistributionsInput{}, func(page *cloudfront.ListDistributionsOutput, lastPage bool) bool {
+		summaries = append(summaries, page.DistributionList.Items...)
+		return true
+	})
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	resources := make([]resource, 0)
+	for _, summary := range summaries {
+		output, err := a.cloudfront.ListTagsForResource(&cloudfront.ListTagsForResourceInput{Resource: summary.ARN})
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == cloudfront.ErrCodeNoSuchResource {
+			continue
+		} else if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		tags := map[string]string{}
+		for _, tag := range output.Tags.Items {
+			tags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+		}
+		if clusterName, ok := a.clusterOf(tags); ok {
+			resources = append(resources, resource{Type: ResourceTypeDistribution, ID: aws.StringValue(summary.Id), ClusterName: clusterName})
+		}
+	}
+
+	return resources, nil
+}
+
+// deleteDistribution disables the distribution, and deletes it together with its origin access identity once the
+// change is deployed.
+func (a *accountResources) deleteDistribution(r resource) (bool, error) {
+	output, err := a.cloudfront.GetDistribution(&cloudfront.GetDistributionInput{Id: aws.String(r.ID)})
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == cloudfront.ErrCodeNoSuchDistribution {
+		return true, nil
+	} else if err != nil {
+		return false, microerror.Mask(err)
+	}
+
+	config := output.Distribution.DistributionConfig
+	if aws.BoolValue(config.Enabled) {
+		config.SetEnabled(false)
+		_, err = a.cloudfront.UpdateDistribution(&cloudfront.UpdateDistributionInput{
+			DistributionConfig: config,
+			Id:                 aws.String(r.ID),
+			IfMatch:            output.ETag,
+		})
+		if err != nil {
+			return false, microerror.Mask(err)
+		}
+		a.scope.Logger().Info("Disabled orphaned cloudfront distribution", "id", r.ID)
+		return false, nil
+	}
+
+	if aws.StringValue(output.Distribution.Status) != "Deployed" {
+		a.scope.Logger().Info("Orphaned cloudfront distribution is not disabled yet", "id", r.ID)
+		return false, nil
+	}
+
+	_, err = a.cloudfront.DeleteDistribution(&cloudfront.DeleteDistributionInput{
+		Id:      aws.String(r.ID),
+		IfMatch: output.ETag,
+	})
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == cloudfront.ErrCodeNoSuchDistribution {
+		return true, nil
+	} else if err != nil {
+		return false, microerror.Mask(err)
+	}
+
+	// Origin access identities have no tags, the distribution is the only way to find them.
+	oaiID := originAccessIdentityID(config)
+	if oaiID == "" {
+		return true, nil
+	}
+
+	oai, err := a.cloudfront.GetCloudFrontOriginAccessIdentity(&cloudfront.GetCloudFrontOriginAccessIdentityInput{Id: aws.String(oaiID)})
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == cloudfront.ErrCodeNoSuchCloudFrontOriginAccessIdentity {
+		return true, nil
+	} else if err != nil {
+		return false, microerror.Mask(err)
+	}
+
+	_, err = a.cloudfront.DeleteCloudFrontOriginAccessIdentity(&cloudfront.DeleteCloudFrontOriginAccessIdentityInput{
+		Id:      aws.String(oaiID),
+		IfMatch: oai.ETag,
+	})
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == cloudfront.ErrCodeNoSuchCloudFrontOriginAccessIdentity {
+		return true, nil
+	} else if err != nil {
+		return false, microerror.Mask(err)
+	}
+
+	return true, nil
+}
+
+func (a *accountResources) listCertificates() ([]resource, error) {
+	var summaries []*acm.CertificateSummary
+	err := a.acm.ListCertificatesPages(&acm.ListCertificatesInput{
+		// Only RSA_2048 certificates are listed by default.
+		Includes: &acm.Filters{KeyTypes: aws.StringSlice(acm.KeyAlgorithm_Values())},
+	}, func(page *acm.ListCertificatesOutput, lastPage bool) bool {
+		summaries = append(summaries, page.CertificateSummaryList...)
+		return true
+	})
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	resources := make([]resource, 0)
+	for _, summary := range summaries {
+		output, err := a.acm.ListTagsForCertificate(&acm.ListTagsForCertificateInput{CertificateArn: summary.CertificateArn})
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == acm.ErrCodeResourceNotFoundException {
+			continue
+		} else if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		tags := map[string]string{}
+		for _, tag := range output.Tags {
+			tags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+		}
+		if clusterName, ok := a.clusterOf(tags); ok {
+			resources = append(resources, resource{Type: ResourceTypeCertificate, ID: aws.StringValue(summary.CertificateArn), ClusterName: clusterName})
+		}
+	}
+
+	return resources, nil
+}
+
+func (a *accountResources) deleteCertificate(r resource) (bool, error) {
+	_, err := a.acm.DeleteCertificate(&acm.DeleteCertificateInput{CertificateArn: aws.String(r.ID)})
+	if aerr, ok := err.(awserr.Error); ok {
+		switch aerr.Code() {
+		case acm.ErrCodeResourceNotFoundException:
+			return true, nil
+		case acm.ErrCodeResourceInUseException:
+			// Still used by a distribution that is being deleted.
+			a.scope.Logger().Info("Orphaned ACM certificate is still in use", "arn", r.ID)
+			return false, nil
+		}
+	}
+	if err != nil {
+		return false, microerror.Mask(err)
+	}
+
+	return true, nil
+}
+
+// isOIDCBucket checks whether the bucket name follows key.BucketName for the account, including the versioned and
+// replica suffixes.
+func isOIDCBucket(bucketName, accountID string) bool {
+	return strings.HasPrefix(bucketName, accountID+"-g8s-") && strings.Contains(bucketName, "-oidc-pod-identity")
+}
+
+// isReplicationRole checks whether the role name follows key.ReplicationRoleName.
+func isReplicationRole(roleName string) bool {
+	return strings.HasSuffix(roleName, "-irsa-s3-replication")
+}
+
+// originAccessIdentityID returns the ID of the origin access identity used by the distribution, or an empty string.
+func originAccessIdentityID(config *cloudfront.DistributionConfig) string {
+	if config.Origins == nil {
+		return ""
+	}
+
+	for _, origin := range config.Origins.Items {
+		if origin.S3OriginConfig == nil {
+			continue
+		}
+		if id := strings.TrimPrefix(aws.StringValue(origin.S3OriginConfig.OriginAccessIdentity), "origin-access-identity/cloudfront/"); id != "" {
+			return id
+		}
+	}
+
+	return ""
+}
@@ -11,8 +11,6 @@ import (
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/awserr"
-	awsiam "github.com/aws/aws-sdk-go/service/iam"
 	"github.com/giantswarm/backoff"
 	"github.com/giantswarm/microerror"
 	"github.com/pkg/errors"
@@ -29,7 +27,9 @@ import (
 	"github.com/giantswarm/irsa-operator/pkg/aws/services/iam"
 	"github.com/giantswarm/irsa-operator/pkg/aws/services/route53"
 	"github.com/giantswarm/irsa-operator/pkg/aws/services/s3"
+	"github.com/giantswarm/irsa-operator/pkg/dns"
 	irsaerrors "github.com/giantswarm/irsa-operator/pkg/errors"
+	"github.com/giantswarm/irsa-operator/pkg/irsa/pipeline"
 	"github.com/giantswarm/irsa-operator/pkg/key"
 	ctrlmetrics "github.com/giantswarm/irsa-operator/pkg/metrics"
 	"github.com/giantswarm/irsa-operator/pkg/util"
@@ -41,354 +41,480 @@ type Service struct {
 
 	ACM        *acm.Service
 	Cloudfront *cloudfront.Service
+	DNS        dns.Provider
 	IAM        *iam.Service
-	Route53    *route53.Service
 	S3         *s3.Service
+	// S3Replica is only set when the cluster has a replica region configured.
+	S3Replica *s3.Service
+
+	// AdditionalAccountIAM registers the cluster's OIDC providers in the accounts of the additional account roles.
+	AdditionalAccountIAM []*iam.Service
+	// RemovedAccountIAM deletes the cluster's OIDC providers from the accounts removed from the additional account
+	// roles.
+	RemovedAccountIAM []*iam.Service
 }
 
 func New(scope *scope.ClusterScope, client client.Client) *Service {
-	return &Service{
+	s := &Service{
 		Scope:  scope,
 		Client: client,
 
 		ACM:        acm.NewService(scope),
 		Cloudfront: cloudfront.NewService(scope),
+		DNS:        dns.NewProvider(scope, client),
 		IAM:        iam.NewService(scope),
-		Route53:    route53.NewService(scope),
 		S3:         s3.NewService(scope),
 	}
+
+	if scope.ReplicaRegion() != "" {
+		s.S3Replica = s3.NewReplicaService(scope, scope.ReplicaRegion())
+	}
+	for _, roleARN := range scope.AdditionalAccountRoleARNs() {
+		s.AdditionalAccountIAM = append(s.AdditionalAccountIAM, iam.NewServiceForRole(scope, roleARN))
+	}
+	for _, roleARN := range scope.RemovedAccountRoleARNs() {
+		s.RemovedAccountIAM = append(s.RemovedAccountIAM, iam.NewServiceForRole(scope, roleARN))
+	}
+
+	return s
 }
 
-func (s *Service) Reconcile(ctx context.Context, outRequeueAfter *time.Duration) error {
-	var cfDomain string
-	var cfOaiId string
+// reconcileState holds what the steps of a reconciliation hand on to the later ones.
+type reconcileState struct {
+	aliases           []*string
+	awsCluster        *capa.AWSCluster
+	certificateARN    string
+	dnsChangesPending bool
+	distribution      *cloudfront.Distribution
+	hostedZoneIDs     map[string]string
+	privateKey        *rsa.PrivateKey
+	// cfDomain and cfOaiId are only set outside of China, where the files are served by CloudFront.
+	cfDomain string
+	cfOaiId  string
+}
 
+func (s *Service) Reconcile(ctx context.Context, outRequeueAfter *time.Duration) error {
 	s.Scope.Logger().Info("Reconciling AWSCluster CR for IRSA")
 
+	s.preflight()
+
 	// Most operations that require polling are quick, however some can take up
 	// to a minute to complete. Currently 75 seconds covers most of the the
 	// errors that can occur.
 	b := backoff.NewMaxRetries(15, 5*time.Second)
-	err := s.S3.IsBucketReady(s.Scope.BucketName())
-	// Check if S3 bucket exists
-	if err != nil {
-		createBucket := func() error {
-			err := s.S3.CreateBucket(s.Scope.BucketName())
-			if err != nil {
-				s.Scope.Logger().Error(err, "Failed to create S3 bucket, retrying")
-			}
 
-			return err
-		}
-		err = backoff.Retry(createBucket, b)
-		if err != nil {
-			ctrlmetrics.Errors.WithLabelValues(s.Scope.Installation(), s.Scope.AccountID(), s.Scope.ClusterName(), s.Scope.ClusterNamespace()).Inc()
-			s.Scope.Logger().Error(err, "failed to create bucket")
-			return err
-		}
+	runner := &pipeline.Runner{
+		Name:         "capa",
+		Installation: s.Scope.Installation(),
+		Logger:       s.Scope.Logger(),
+		Backoff:      b,
+		Condition:    key.IRSAReconciledCondition,
+		Object:       s.Scope.Cluster(),
+		OnError:      errorCounter(s.Scope.Installation(), s.Scope.AccountID(), s.Scope.ClusterName(), s.Scope.ClusterNamespace()),
+		Cache:        s.Scope.Cache(),
 	}
-
-	err = s.S3.EncryptBucket(s.Scope.BucketName())
+	result, err := runner.Run(ctx, s.steps(&reconcileState{}, b))
 	if err != nil {
-		ctrlmetrics.Errors.WithLabelValues(s.Scope.Installation(), s.Scope.AccountID(), s.Scope.ClusterName(), s.Scope.ClusterNamespace()).Inc()
-		s.Scope.Logger().Error(err, "failed to encrypt bucket")
 		return err
 	}
-
-	// Fetch custom tags from AWSCluster CR
-	awsCluster := &capa.AWSCluster{}
-	err = s.Client.Get(ctx, types.NamespacedName{Namespace: s.Scope.ClusterNamespace(), Name: s.Scope.ClusterName()}, awsCluster)
-	if err != nil {
-		return err
+	if result.RequeueAfter > 0 {
+		*outRequeueAfter = result.RequeueAfter
 	}
-	err = s.S3.CreateTags(s.Scope.BucketName(), awsCluster.Spec.AdditionalTags)
-	if err != nil {
-		ctrlmetrics.Errors.WithLabelValues(s.Scope.Installation(), s.Scope.AccountID(), s.Scope.ClusterName(), s.Scope.ClusterNamespace()).Inc()
-		s.Scope.Logger().Error(err, "failed to create tags")
-		return err
+	if result.Stopped() {
+		return nil
 	}
 
-	aliases := make([]*string, 0)
-	var cloudfrontCertificateARN string
-	var hostedZoneID string
-
-	distribution := &cloudfront.Distribution{}
-	// Add Cloudfront only for non-China region
-	if !key.IsChina(s.Scope.Region()) {
-		cloudfrontAliasDomain := s.getCloudFrontAliasDomain()
-		if cloudfrontAliasDomain != "" {
-			// Ensure ACM certificate.
-			certificateArn, err := s.ACM.EnsureCertificate(cloudfrontAliasDomain, awsCluster.Spec.AdditionalTags)
-			if err != nil {
-				ctrlmetrics.Errors.WithLabelValues(s.Scope.Installation(), s.Scope.AccountID(), s.Scope.ClusterName(), s.Scope.ClusterNamespace()).Inc()
-				s.Scope.Logger().Error(err, "failed to create ACM certificate")
-				return err
-			}
-
-			// wait for certificate to be issued.
-			issued, err := s.ACM.IsCertificateIssued(*certificateArn)
-			if err != nil {
-				ctrlmetrics.Errors.WithLabelValues(s.Scope.Installation(), s.Scope.AccountID(), s.Scope.ClusterName(), s.Scope.ClusterNamespace()).Inc()
-				s.Scope.Logger().Error(err, "failed to create ACM certificate")
-				return err
-			}
-
-			hostedZoneID, err = s.Route53.FindPublicHostedZone(s.Scope.BaseDomain())
-			if err != nil {
-				ctrlmetrics.Errors.WithLabelValues(s.Scope.Installation(), s.Scope.AccountID(), s.Scope.ClusterName(), s.Scope.ClusterNamespace()).Inc()
-				s.Scope.Logger().Error(err, "failed to find route53 hosted zone ID")
-				return err
-			}
+	ctrlmetrics.Errors.WithLabelValues(s.Scope.Installation(), s.Scope.AccountID(), s.Scope.ClusterName(), s.Scope.ClusterNamespace()).Set(0)
+	s.Scope.Logger().Info("Finished reconciling on all resources.")
+	return nil
+}
 
-			// Check if domain ownership is validated
-			validated, err := s.ACM.IsValidated(*certificateArn)
-			if err != nil {
-				ctrlmetrics.Errors.WithLabelValues(s.Scope.Installation(), s.Scope.AccountID(), s.Scope.ClusterName(), s.Scope.ClusterNamespace()).Inc()
-				s.Scope.Logger().Error(err, "failed to check if ACM certificate's ownership is validated")
-				return err
-			}
+// errorCounter returns the OnError hook of the runner, which counts the failures of steps in the error metric of the
+// cluster.
+func errorCounter(installation, accountID, clusterName, clusterNamespace string) func(step string, err error) {
+	return func(step string, err error) {
+		// Waiting for the certificate to be issued is not an error of the reconciliation.
+		if IsCertificateNotIssued(err) {
+			return
+		}
+		ctrlmetrics.Errors.WithLabelValues(installation, accountID, clusterName, clusterNamespace).Inc()
+	}
+}
 
-			if !validated {
-				// Check if DNS record is present
-				var cname *route53.CNAME
-				getValidationCNAME := func() error {
-					var err error
-					cname, err = s.ACM.GetValidationCNAME(*certificateArn)
-					return err
+// steps returns the steps of a reconciliation in the order they run.
+func (s *Service) steps(st *reconcileState, b backoff.Interface) []pipeline.Step {
+	china := func() bool { return key.IsChina(s.Scope.Region()) }
+	notChina := func() bool { return !china() }
+
+	return []pipeline.Step{
+		{
+			Name:        "ensure-bucket",
+			Retry:       true,
+			CountErrors: true,
+			Action: pipeline.Do(func(ctx context.Context) error {
+				if s.S3.IsBucketReady(s.Scope.BucketName()) == nil {
+					return nil
+				}
+				return s.S3.CreateBucket(s.Scope.BucketName())
+			}),
+		},
+		{
+			Name:        "encrypt-bucket",
+			CountErrors: true,
+			Action: pipeline.Do(func(ctx context.Context) error {
+				return s.S3.EncryptBucket(s.Scope.BucketName())
+			}),
+		},
+		{
+			// Custom tags come from the AWSCluster CR.
+			Name: "get-aws-cluster",
+			Action: pipeline.Do(func(ctx context.Context) error {
+				st.awsCluster = &capa.AWSCluster{}
+				return s.Client.Get(ctx, types.NamespacedName{Namespace: s.Scope.ClusterNamespace(), Name: s.Scope.ClusterName()}, st.awsCluster)
+			}),
+		},
+		{
+			Name:        "tag-bucket",
+			CountErrors: true,
+			Action: pipeline.Do(func(ctx context.Context) error {
+				return s.S3.CreateTags(s.Scope.BucketName(), st.awsCluster.Spec.AdditionalTags)
+			}),
+		},
+		{
+			// Replica bucket is only useful as CloudFront failover origin, so only for non-China region
+			Name:         "ensure-replica-bucket",
+			Precondition: s.hasReplica,
+			CountErrors:  true,
+			Action: pipeline.Do(func(ctx context.Context) error {
+				return s.reconcileReplicaBucket(st.awsCluster.Spec.AdditionalTags, b)
+			}),
+		},
+		{
+			Name:         "ensure-certificate",
+			Precondition: func() bool { return notChina() && s.getCloudFrontAliasDomain() != "" },
+			CountErrors:  true,
+			Action: pipeline.Do(func(ctx context.Context) error {
+				domains := append([]string{s.getCloudFrontAliasDomain()}, s.Scope.ExtraAliases()...)
+
+				// The aliases are recorded before their records are created, so that the records of removed aliases
+				// are still known if they are removed from the annotation before the reconciliation succeeds.
+				s.Scope.SetManagedAliases(append(append([]string{}, s.Scope.ExtraAliases()...), s.Scope.RemovedAliases()...))
+
+				var certificateArn *string
+				var err error
+				if s.Scope.CertificateSecretName() != "" {
+					certificateArn, st.hostedZoneIDs, err = s.importCertificate(ctx, domains, st.awsCluster.Spec.AdditionalTags)
+				} else {
+					certificateArn, st.hostedZoneIDs, err = s.requestCertificate(domains, st.awsCluster.Spec.AdditionalTags, b)
 				}
-				err = backoff.Retry(getValidationCNAME, b)
 				if err != nil {
-					ctrlmetrics.Errors.WithLabelValues(s.Scope.Installation(), s.Scope.AccountID(), s.Scope.ClusterName(), s.Scope.ClusterNamespace()).Inc()
-					s.Scope.Logger().Error(err, "failed to get ACM certificate's validation DNS record details")
 					return err
 				}
 
-				err = s.Route53.EnsureDNSRecord(hostedZoneID, *cname)
+				st.aliases = aws.StringSlice(domains)
+				st.certificateARN = *certificateArn
+				return nil
+			}),
+		},
+		{
+			// Add Cloudfront only for non-China region
+			Name:         "ensure-distribution",
+			Precondition: notChina,
+			CountErrors:  true,
+			Action: pipeline.Do(func(ctx context.Context) error {
+				distributionConfig := cloudfront.DistributionConfig{CustomerTags: st.awsCluster.Spec.AdditionalTags, Aliases: st.aliases, CertificateArn: st.certificateARN, EnableCaching: s.Scope.CloudFrontCaching()}
+				if s.hasReplica() {
+					distributionConfig.ReplicaBucketName = s.Scope.ReplicaBucketName()
+					distributionConfig.ReplicaRegion = s.Scope.ReplicaRegion()
+				}
+
+				var err error
+				st.distribution, err = s.Cloudfront.EnsureDistribution(distributionConfig)
+				return err
+			}),
+		},
+		{
+			// The distribution no longer serves removed aliases, and the certificates that still cover them are only
+			// deleted in the next step, so that their validation records are still known.
+			Name:         "delete-removed-alias-records",
+			Precondition: func() bool { return notChina() && len(s.Scope.RemovedAliases()) > 0 },
+			CountErrors:  true,
+			Action: pipeline.Do(func(ctx context.Context) error {
+				records, err := s.ACM.GetValidationRecords(s.getCloudFrontAliasDomain())
 				if err != nil {
-					ctrlmetrics.Errors.WithLabelValues(s.Scope.Installation(), s.Scope.AccountID(), s.Scope.ClusterName(), s.Scope.ClusterNamespace()).Inc()
-					s.Scope.Logger().Error(err, "failed to create ACM certificate's validation DNS record")
 					return err
 				}
 
-			}
-
-			if issued {
-				notAfter, err := s.ACM.GetCertificateExpirationTS(*certificateArn)
+				err = s.deleteAliasRecords(s.Scope.RemovedAliases(), st.distribution.Domain, records)
 				if err != nil {
-					ctrlmetrics.Errors.WithLabelValues(s.Scope.Installation(), s.Scope.AccountID(), s.Scope.ClusterName(), s.Scope.ClusterNamespace()).Inc()
-					s.Scope.Logger().Error(err, "failed to check ACM certificate's expiration date")
 					return err
 				}
+				s.Scope.SetManagedAliases(s.Scope.ExtraAliases())
+
+				return nil
+			}),
+		},
+		{
+			// The distribution no longer fails over to a replica that was removed from the annotation.
+			Name:         "delete-removed-replica-bucket",
+			Precondition: func() bool { return notChina() && !s.hasReplica() },
+			CountErrors:  true,
+			Action: pipeline.Do(func(ctx context.Context) error {
+				return s.deleteReplicaBucket()
+			}),
+		},
+		{
+			// Certificates for an earlier set of aliases or key algorithm are no longer needed once the distribution uses
+			// the current one.
+			Name:         "delete-unused-certificates",
+			Precondition: func() bool { return st.certificateARN != "" },
+			CountErrors:  true,
+			Action: pipeline.Do(func(ctx context.Context) error {
+				return s.ACM.DeleteUnusedCertificates(s.getCloudFrontAliasDomain(), st.certificateARN)
+			}),
+		},
+		{
+			Name:         "ensure-alias-records",
+			Precondition: func() bool { return len(st.aliases) > 0 && len(st.hostedZoneIDs) > 0 },
+			CountErrors:  true,
+			Action: pipeline.Do(func(ctx context.Context) error {
+				for _, alias := range st.aliases {
+					hostedZoneID, ok := st.hostedZoneIDs[*alias]
+					if !ok {
+						continue
+					}
 
-				ctrlmetrics.Certs.WithLabelValues(s.Scope.Installation(), s.Scope.AccountID(), s.Scope.ClusterName(), s.Scope.ClusterNamespace(), cloudfrontAliasDomain).Set(float64(notAfter.Unix()))
-
-			} else {
-				s.Scope.Logger().Info("ACM certificate is not issued yet")
-
-				return microerror.Mask(certificateNotIssuedError)
-			}
-
-			aliases = append(aliases, &cloudfrontAliasDomain)
-			cloudfrontCertificateARN = *certificateArn
-		}
-
-		distribution, err = s.Cloudfront.EnsureDistribution(cloudfront.DistributionConfig{CustomerTags: awsCluster.Spec.AdditionalTags, Aliases: aliases, CertificateArn: cloudfrontCertificateARN})
-		if err != nil {
-			ctrlmetrics.Errors.WithLabelValues(s.Scope.Installation(), s.Scope.AccountID(), s.Scope.ClusterName(), s.Scope.ClusterNamespace()).Inc()
-			s.Scope.Logger().Error(err, "failed to create cloudfront distribution")
-			return err
-		}
+					// Create IRSA alias records
+					err := s.DNS.EnsureAliasRecords(hostedZoneID, *alias, st.distribution.Domain)
+					if err != nil {
+						return err
+					}
 
-		// kubeadmconfig only support secrets for now, therefore we need to store Cloudfront config as a secret, see
-		// https://github.com/giantswarm/cluster-api-app/blob/master/helm/cluster-api/files/bootstrap/patches/versions/v1beta1/kubeadmconfigs.bootstrap.cluster.x-k8s.io.yaml#L307-L325
+					inSync, err := dns.ChangeInSync(s.DNS, s.Scope, *alias, hostedZoneID)
+					if err != nil {
+						return err
+					}
+					st.dnsChangesPending = st.dnsChangesPending || !inSync
+				}
 
-		data := map[string]string{
-			"arn":                    distribution.ARN,
-			"domain":                 distribution.Domain,
-			"distributionId":         distribution.DistributionId,
-			"originAccessIdentityId": distribution.OriginAccessIdentityId,
-		}
+				return nil
+			}),
+		},
+		{
+			Name:         "ensure-cloudfront-config",
+			Precondition: notChina,
+			CountErrors:  true,
+			Action: pipeline.Do(func(ctx context.Context) error {
+				return s.ensureCloudfrontConfig(ctx, st)
+			}),
+		},
+		{
+			Name:         "update-bucket-policy",
+			Precondition: notChina,
+			Retry:        true,
+			Action: pipeline.Do(func(ctx context.Context) error {
+				return s.S3.UpdatePolicy(s.Scope.BucketName(), st.cfOaiId)
+			}),
+		},
+		{
+			// Block public S3 access only for non-China region
+			Name:         "block-public-access",
+			Precondition: notChina,
+			Action: pipeline.Do(func(ctx context.Context) error {
+				return s.S3.BlockPublicAccess(s.Scope.BucketName())
+			}),
+		},
+		{
+			Name:         "update-replica-bucket-policy",
+			Precondition: s.hasReplica,
+			Retry:        true,
+			Action: pipeline.Do(func(ctx context.Context) error {
+				return s.S3Replica.UpdatePolicy(s.Scope.ReplicaBucketName(), st.cfOaiId)
+			}),
+		},
+		{
+			Name:         "block-replica-public-access",
+			Precondition: s.hasReplica,
+			Action: pipeline.Do(func(ctx context.Context) error {
+				return s.S3Replica.BlockPublicAccess(s.Scope.ReplicaBucketName())
+			}),
+		},
+		{
+			// Allow public S3 access for China region
+			Name:         "allow-public-access",
+			Precondition: china,
+			Action: pipeline.Do(func(ctx context.Context) error {
+				return s.S3.AllowPublicAccess(s.Scope.BucketName())
+			}),
+		},
+		{
+			Name: "get-service-account-key",
+			Action: func(ctx context.Context) (pipeline.Result, error) {
+				var err error
+				st.privateKey, err = s.ServiceAccountSecret(ctx)
+				if apierrors.IsNotFound(err) {
+					// Secret is handled by CAPI/kubeadm, its creation triggers the next reconciliation.
+					s.Scope.Logger().Info("Service account is not ready yet, waiting ...")
+					return pipeline.Result{Stop: true}, nil
+				}
 
-		if len(aliases) > 0 && hostedZoneID != "" {
-			for _, alias := range aliases {
-				// Create IRSA Alias CNAME
-				err = s.Route53.EnsureDNSRecord(hostedZoneID, route53.CNAME{Name: *alias, Value: key.EnsureTrailingDot(distribution.Domain)})
+				return pipeline.Result{}, err
+			},
+		},
+		{
+			Name:        "upload-files",
+			Retry:       true,
+			CountErrors: true,
+			Action: pipeline.Do(func(ctx context.Context) error {
+				domain := st.cfDomain
+				if len(st.aliases) > 0 {
+					domain = *st.aliases[0] //nolint:gosec
+				}
+				err := s.S3.UploadFiles(s.Scope.Release(), domain, s.Scope.BucketName(), st.privateKey)
 				if err != nil {
-					ctrlmetrics.Errors.WithLabelValues(s.Scope.Installation(), s.Scope.AccountID(), s.Scope.ClusterName(), s.Scope.ClusterNamespace()).Inc()
-					s.Scope.Logger().Error(err, "failed to create cloudfront CNAME record")
 					return err
 				}
-			}
-
-			data["domainAlias"] = *aliases[0] //nolint:gosec
-		}
-
-		cfConfig := &v1.Secret{}
-		err = s.Client.Get(ctx, types.NamespacedName{Namespace: s.Scope.ClusterNamespace(), Name: s.Scope.ConfigName()}, cfConfig)
-		if apierrors.IsNotFound(err) {
-			if err := irsaerrors.IsEmptyCloudfrontDistribution(distribution); err != nil {
-				ctrlmetrics.Errors.WithLabelValues(s.Scope.Installation(), s.Scope.AccountID(), s.Scope.ClusterName(), s.Scope.ClusterNamespace()).Inc()
-				s.Scope.Logger().Error(err, "cloudfront distribution cannot be nil")
-				return err
-			}
-
-			// create new OIDC Cloudfront config
-			cfConfig.Name = s.Scope.ConfigName()
-			cfConfig.Namespace = s.Scope.ClusterNamespace()
-			cfConfig.StringData = data
-
-			if err := s.Client.Create(ctx, cfConfig); err != nil {
-				ctrlmetrics.Errors.WithLabelValues(s.Scope.Installation(), s.Scope.AccountID(), s.Scope.ClusterName(), s.Scope.ClusterNamespace()).Inc()
-				s.Scope.Logger().Error(err, "failed to create OIDC cloudfront secret for cluster")
-				return err
-			}
-			s.Scope.Logger().Info("Created OIDC cloudfront secret in k8s")
-
-		} else if err != nil {
-			return err
-		}
 
-		// Ensure CM is up to date
-		if reflect.DeepEqual(cfConfig.Data, data) {
-			s.Scope.Logger().Info("Secret is already up to date")
-		} else {
-			s.Scope.Logger().Info("Secret needs to be updated")
+				// Replication only copies objects written after it was configured, so also seed the replica directly.
+				// Unchanged files are skipped, which keeps this cheap once replication caught up.
+				if s.hasReplica() {
+					return s.S3Replica.UploadFiles(s.Scope.Release(), domain, s.Scope.ReplicaBucketName(), st.privateKey)
+				}
+				return nil
+			}),
+		},
+		{
+			// IAM fetches the issuer through the aliases, so wait until their records have propagated.
+			Name: "wait-for-alias-records",
+			Action: func(ctx context.Context) (pipeline.Result, error) {
+				if !st.dnsChangesPending {
+					return pipeline.Result{}, nil
+				}
+				s.Scope.Logger().Info("Alias records are not propagated yet, waiting ...")
+
+				return pipeline.Result{RequeueAfter: 15 * time.Second}, nil
+			},
+		},
+		{
+			Name:        "ensure-oidc-providers",
+			Retry:       true,
+			CountErrors: true,
+			Action: pipeline.Do(func(ctx context.Context) error {
+				var identityProviderURLs []string
+				s3Endpoint := fmt.Sprintf("s3.%s.%s", s.Scope.Region(), key.AWSEndpoint(s.Scope.Region()))
+				if key.IsChina(s.Scope.Region()) {
+					identityProviderURLs = append(identityProviderURLs, util.EnsureHTTPS(fmt.Sprintf("%s/%s", s3Endpoint, s.Scope.BucketName())))
+				}
 
-			cfConfig.StringData = data
+				for _, alias := range st.aliases {
+					identityProviderURLs = append(identityProviderURLs, util.EnsureHTTPS(*alias))
+				}
 
-			err = s.Client.Update(ctx, cfConfig)
-			if err != nil {
-				ctrlmetrics.Errors.WithLabelValues(s.Scope.Installation(), s.Scope.AccountID(), s.Scope.ClusterName(), s.Scope.ClusterNamespace()).Inc()
-				s.Scope.Logger().Error(err, "error updating secret")
-				return err
-			}
+				audiences := append([]string{key.STSUrl(s.Scope.Region())}, s.Scope.AdditionalAudiences()...)
+				err := s.IAM.EnsureOIDCProviders(identityProviderURLs, []string{}, audiences, st.awsCluster.Spec.AdditionalTags)
+				if err != nil {
+					return microerror.Mask(err)
+				}
 
-			s.Scope.Logger().Info("Secret updated successfully")
-		}
+				// The accounts are recorded before the providers are created, so that they are still known if they
+				// are removed from the annotation before the reconciliation succeeds.
+				s.Scope.SetRegisteredAccountRoleARNs(append(append([]string{}, s.Scope.AdditionalAccountRoleARNs()...), s.Scope.RemovedAccountRoleARNs()...))
 
-		cfDomain = data["domain"]
-		cfOaiId = data["originAccessIdentityId"]
+				for _, additionalAccountIAM := range s.AdditionalAccountIAM {
+					err := additionalAccountIAM.EnsureOIDCProviders(identityProviderURLs, []string{}, audiences, st.awsCluster.Spec.AdditionalTags)
+					if err != nil {
+						return microerror.Mask(err)
+					}
+				}
 
-		uploadPolicy := func() error { return s.S3.UpdatePolicy(s.Scope.BucketName(), cfOaiId) }
-		err = backoff.Retry(uploadPolicy, b)
-		if err != nil {
-			s.Scope.Logger().Error(err, "failed to upload policy")
-			return err
-		}
+				for _, removedAccountIAM := range s.RemovedAccountIAM {
+					err := removedAccountIAM.DeleteOIDCProviders()
+					if err != nil {
+						return microerror.Mask(err)
+					}
+				}
+				s.Scope.SetRegisteredAccountRoleARNs(s.Scope.AdditionalAccountRoleARNs())
+
+				return nil
+			}),
+		},
+		{
+			// We only need to manage the MC OIDC provider if the workload cluster uses a different account than the management cluster.
+			// If they would use the same account, the OIDC provider would already be there.
+			Name:         "ensure-management-cluster-oidc-provider",
+			Precondition: func() bool { return s.Scope.AccountID() != s.Scope.ManagementClusterAccountID() },
+			Retry:        true,
+			CountErrors:  true,
+			Action: pipeline.Do(func(ctx context.Context) error {
+				mcIdentityProviderURL := util.EnsureHTTPS(strings.Replace(key.CloudFrontAlias(s.Scope.BaseDomain()), s.Scope.ClusterName(), s.Scope.Installation(), 1))
+				if key.IsChina(s.Scope.Region()) {
+					s3Endpoint := fmt.Sprintf("s3.%s.%s", s.Scope.ManagementClusterRegion(), key.AWSEndpoint(s.Scope.ManagementClusterRegion()))
+					bucketName := key.BucketName(s.Scope.ManagementClusterAccountID(), s.Scope.Installation())
+					mcIdentityProviderURL = util.EnsureHTTPS(fmt.Sprintf("%s/%s", s3Endpoint, fmt.Sprintf("%s-v3", bucketName)))
+				}
+				s.Scope.Logger().Info("Ensuring MC OIDC provider in WC AWS account", "identityProviderURL", mcIdentityProviderURL)
 
-		// Block public S3 access only for non-China region
-		err = s.S3.BlockPublicAccess(s.Scope.BucketName())
-		if err != nil {
-			s.Scope.Logger().Error(err, "failed to block public access")
-			return err
-		}
-	} else {
-		// Allow public S3 access for China region
-		err = s.S3.AllowPublicAccess(s.Scope.BucketName())
-		if err != nil {
-			s.Scope.Logger().Error(err, "failed to allow public access")
-			return err
-		}
+				return s.IAM.EnsureManagementClusterOIDCProvider(mcIdentityProviderURL, []string{key.STSUrl(s.Scope.Region())})
+			}),
+		},
 	}
+}
 
-	privateKey, err := s.ServiceAccountSecret(ctx)
-	if apierrors.IsNotFound(err) {
-		s.Scope.Logger().Info("Service account is not ready yet, waiting ...")
+// ensureCloudfrontConfig stores the distribution's details in the secret the cluster's nodes are bootstrapped with.
+func (s *Service) ensureCloudfrontConfig(ctx context.Context, st *reconcileState) error {
+	// kubeadmconfig only support secrets for now, therefore we need to store Cloudfront config as a secret, see
+	// https://github.com/giantswarm/cluster-api-app/blob/master/helm/cluster-api/files/bootstrap/patches/versions/v1beta1/kubeadmconfigs.bootstrap.cluster.x-k8s.io.yaml#L307-L325
 
-		// Secret is handled by CAPI/kubeadm and may be available soon, so set a low requeue interval
-		*outRequeueAfter = 30 * time.Second
-		return nil
+	data := map[string]string{
+		"arn":                    st.distribution.ARN,
+		"domain":                 st.distribution.Domain,
+		"distributionId":         st.distribution.DistributionId,
+		"originAccessIdentityId": st.distribution.OriginAccessIdentityId,
 	}
-	if err != nil {
-		return err
+	if len(st.aliases) > 0 && len(st.hostedZoneIDs) > 0 {
+		data["domainAlias"] = *st.aliases[0] //nolint:gosec
 	}
 
-	uploadFiles := func() error {
-		domain := cfDomain
-		if len(aliases) > 0 {
-			domain = *aliases[0] //nolint:gosec
+	cfConfig := &v1.Secret{}
+	err := s.Client.Get(ctx, types.NamespacedName{Namespace: s.Scope.ClusterNamespace(), Name: s.Scope.ConfigName()}, cfConfig)
+	if apierrors.IsNotFound(err) {
+		if err := irsaerrors.IsEmptyCloudfrontDistribution(st.distribution); err != nil {
+			return err
 		}
-		return s.S3.UploadFiles(s.Scope.Release(), domain, s.Scope.BucketName(), privateKey)
-	}
-	err = backoff.Retry(uploadFiles, b)
-	if err != nil {
-		ctrlmetrics.Errors.WithLabelValues(s.Scope.Installation(), s.Scope.AccountID(), s.Scope.ClusterName(), s.Scope.ClusterNamespace()).Inc()
-		s.Scope.Logger().Error(err, "failed to upload files")
-		return err
-	}
 
-	createOIDCProvider := func() error {
-		var identityProviderURLs []string
-		s3Endpoint := fmt.Sprintf("s3.%s.%s", s.Scope.Region(), key.AWSEndpoint(s.Scope.Region()))
-		if key.IsChina(s.Scope.Region()) {
-			identityProviderURLs = append(identityProviderURLs, util.EnsureHTTPS(fmt.Sprintf("%s/%s", s3Endpoint, s.Scope.BucketName())))
-		}
+		// create new OIDC Cloudfront config
+		cfConfig.Name = s.Scope.ConfigName()
+		cfConfig.Namespace = s.Scope.ClusterNamespace()
+		cfConfig.StringData = data
 
-		for _, alias := range aliases {
-			identityProviderURLs = append(identityProviderURLs, util.EnsureHTTPS(*alias))
+		if err := s.Client.Create(ctx, cfConfig); err != nil {
+			return err
 		}
+		s.Scope.Logger().Info("Created OIDC cloudfront secret in k8s")
 
-		return s.IAM.EnsureOIDCProviders(identityProviderURLs, []string{}, key.STSUrl(s.Scope.Region()), awsCluster.Spec.AdditionalTags)
-	}
-	err = backoff.Retry(createOIDCProvider, b)
-	if err != nil {
-		ctrlmetrics.Errors.WithLabelValues(s.Scope.Installation(), s.Scope.AccountID(), s.Scope.ClusterName(), s.Scope.ClusterNamespace()).Inc()
-		s.Scope.Logger().Error(err, "failed to create OIDC provider")
+	} else if err != nil {
 		return err
 	}
 
-	// We only need to create the OIDC provider if the workload cluster uses a different account than the management cluster.
-	// If they would use the same account, the OIDC provider would already be there.
-	if s.Scope.AccountID() != s.Scope.ManagementClusterAccountID() {
-		createMCOIDCProvider := func() error {
-			mcIdentityProviderURL := util.EnsureHTTPS(strings.Replace(key.CloudFrontAlias(s.Scope.BaseDomain()), s.Scope.ClusterName(), s.Scope.Installation(), 1))
-			if key.IsChina(s.Scope.Region()) {
-				s3Endpoint := fmt.Sprintf("s3.%s.%s", s.Scope.ManagementClusterRegion(), key.AWSEndpoint(s.Scope.ManagementClusterRegion()))
-				bucketName := key.BucketName(s.Scope.ManagementClusterAccountID(), s.Scope.Installation())
-				mcIdentityProviderURL = util.EnsureHTTPS(fmt.Sprintf("%s/%s", s3Endpoint, fmt.Sprintf("%s-v3", bucketName)))
-			}
-			s.Scope.Logger().Info("creating MC OIDC provider in WC AWS account", "identityProviderURLs", mcIdentityProviderURL)
-
-			i := &awsiam.CreateOpenIDConnectProviderInput{
-				Url:          aws.String(mcIdentityProviderURL),
-				ClientIDList: []*string{aws.String(key.STSUrl(s.Scope.Region()))},
-				Tags: []*awsiam.Tag{
-					{
-						Key:   aws.String("giantswarm.io/installation"),
-						Value: aws.String(s.Scope.Installation()),
-					},
-					{
-						Key:   aws.String("giantswarm.io/cluster"),
-						Value: aws.String(s.Scope.Installation()),
-					},
-				},
-			}
+	// Ensure CM is up to date
+	if reflect.DeepEqual(cfConfig.Data, data) {
+		s.Scope.Logger().Info("Secret is already up to date")
+	} else {
+		s.Scope.Logger().Info("Secret needs to be updated")
 
-			_, err = s.IAM.Client.CreateOpenIDConnectProvider(i)
-			if err != nil {
-				if aerr, ok := err.(awserr.Error); ok {
-					switch aerr.Code() {
-					case awsiam.ErrCodeEntityAlreadyExistsException:
-						s.Scope.Logger().Info("MC OIDC provider already exists")
-						return nil
-					}
-				}
-				return microerror.Mask(err)
-			}
-			s.Scope.Logger().Info("Created MC OIDC provider in WC AWS account", "identityProviderURL", mcIdentityProviderURL)
+		cfConfig.StringData = data
 
-			return nil
-		}
-		err = backoff.Retry(createMCOIDCProvider, b)
+		err = s.Client.Update(ctx, cfConfig)
 		if err != nil {
-			ctrlmetrics.Errors.WithLabelValues(s.Scope.Installation(), s.Scope.AccountID(), s.Scope.ClusterName(), s.Scope.ClusterNamespace()).Inc()
-			s.Scope.Logger().Error(err, "failed to create MC OIDC provider")
 			return err
 		}
+
+		s.Scope.Logger().Info("Secret updated successfully")
 	}
 
-	ctrlmetrics.Errors.WithLabelValues(s.Scope.Installation(), s.Scope.AccountID(), s.Scope.ClusterName(), s.Scope.ClusterNamespace()).Set(0)
-	s.Scope.Logger().Info("Finished reconciling on all resources.")
+	st.cfDomain = data["domain"]
+	st.cfOaiId = data["originAccessIdentityId"]
+
 	return nil
 }
 
@@ -399,6 +525,27 @@ func (s *Service) Delete(ctx context.Context) error {
 		s.Scope.Logger().Error(err, "failed to delete S3 files")
 		return err
 	}
+	err = s.deleteReplicaBucket()
+	if err != nil {
+		ctrlmetrics.Errors.WithLabelValues(s.Scope.Installation(), s.Scope.AccountID(), s.Scope.ClusterName(), s.Scope.ClusterNamespace()).Inc()
+		s.Scope.Logger().Error(err, "failed to delete replica S3 bucket")
+		return err
+	}
+	// Versioning can't be disabled, so the bucket keeps old versions even after its replica was removed.
+	versioned, err := s.S3.IsVersioned(s.Scope.BucketName())
+	if err != nil {
+		ctrlmetrics.Errors.WithLabelValues(s.Scope.Installation(), s.Scope.AccountID(), s.Scope.ClusterName(), s.Scope.ClusterNamespace()).Inc()
+		s.Scope.Logger().Error(err, "failed to check versioning of S3 bucket")
+		return err
+	}
+	if versioned {
+		err = s.S3.DeleteFileVersions(s.Scope.BucketName())
+		if err != nil {
+			ctrlmetrics.Errors.WithLabelValues(s.Scope.Installation(), s.Scope.AccountID(), s.Scope.ClusterName(), s.Scope.ClusterNamespace()).Inc()
+			s.Scope.Logger().Error(err, "failed to delete S3 file versions")
+			return err
+		}
+	}
 	err = s.S3.DeleteBucket(s.Scope.BucketName())
 	if err != nil {
 		ctrlmetrics.Errors.WithLabelValues(s.Scope.Installation(), s.Scope.AccountID(), s.Scope.ClusterName(), s.Scope.ClusterNamespace()).Inc()
@@ -413,6 +560,34 @@ func (s *Service) Delete(ctx context.Context) error {
 		return err
 	}
 
+	for _, additionalAccountIAM := range s.AdditionalAccountIAM {
+		err = additionalAccountIAM.DeleteOIDCProviders()
+		if err != nil {
+			ctrlmetrics.Errors.WithLabelValues(s.Scope.Installation(), s.Scope.AccountID(), s.Scope.ClusterName(), s.Scope.ClusterNamespace()).Inc()
+			s.Scope.Logger().Error(err, "failed to delete OIDC provider in additional account")
+			return err
+		}
+	}
+
+	for _, removedAccountIAM := range s.RemovedAccountIAM {
+		err = removedAccountIAM.DeleteOIDCProviders()
+		if err != nil {
+			ctrlmetrics.Errors.WithLabelValues(s.Scope.Installation(), s.Scope.AccountID(), s.Scope.ClusterName(), s.Scope.ClusterNamespace()).Inc()
+			s.Scope.Logger().Error(err, "failed to delete OIDC provider in removed account")
+			return err
+		}
+	}
+
+	// The MC OIDC provider is shared by all clusters of the installation in the account, the last one removes it.
+	if s.Scope.AccountID() != s.Scope.ManagementClusterAccountID() {
+		err = s.IAM.DeleteManagementClusterOIDCProviderIfUnused()
+		if err != nil {
+			ctrlmetrics.Errors.WithLabelValues(s.Scope.Installation(), s.Scope.AccountID(), s.Scope.ClusterName(), s.Scope.ClusterNamespace()).Inc()
+			s.Scope.Logger().Error(err, "failed to delete MC OIDC provider")
+			return err
+		}
+	}
+
 	if !key.IsChina(s.Scope.Region()) {
 		cfConfig := &v1.Secret{}
 		err = s.Client.Get(ctx, types.NamespacedName{Namespace: s.Scope.ClusterNamespace(), Name: s.Scope.ConfigName()}, cfConfig)
@@ -428,6 +603,14 @@ func (s *Service) Delete(ctx context.Context) error {
 		cfDistributionId := string(data["distributionId"])
 		cfOriginAccessIdentityId := string(data["originAccessIdentityId"])
 
+		// Remove DNS records first, so nothing points at the distribution once it is gone.
+		err = s.deleteDNSRecords(string(data["domain"]))
+		if err != nil {
+			ctrlmetrics.Errors.WithLabelValues(s.Scope.Installation(), s.Scope.AccountID(), s.Scope.ClusterName(), s.Scope.ClusterNamespace()).Inc()
+			s.Scope.Logger().Error(err, "failed to delete DNS records")
+			return err
+		}
+
 		err = s.Cloudfront.DisableDistribution(cfDistributionId)
 		if err != nil {
 			s.Scope.Logger().Error(err, "failed to disable cloudfront distribution for cluster")
@@ -467,6 +650,8 @@ func (s *Service) Delete(ctx context.Context) error {
 	}
 
 	ctrlmetrics.Errors.DeleteLabelValues(s.Scope.Installation(), s.Scope.AccountID(), s.Scope.ClusterName(), s.Scope.ClusterNamespace())
+	ctrlmetrics.DeleteCertificateMetrics(s.Scope.Installation(), s.Scope.AccountID(), s.Scope.ClusterName(), s.Scope.ClusterNamespace())
+	ctrlmetrics.DeleteDNSMetrics(s.Scope.Installation(), s.Scope.AccountID(), s.Scope.ClusterName(), s.Scope.ClusterNamespace())
 	s.Scope.Logger().Info("Finished deleting all resources.")
 
 	return nil
@@ -474,7 +659,7 @@ func (s *Service) Delete(ctx context.Context) error {
 
 func (s *Service) ServiceAccountSecret(ctx context.Context) (*rsa.PrivateKey, error) {
 	oidcSecret := &v1.Secret{}
-	err := s.Client.Get(ctx, types.NamespacedName{Namespace: s.Scope.ClusterNamespace(), Name: s.Scope.ClusterName() + "-sa"}, oidcSecret)
+	err := s.Client.Get(ctx, types.NamespacedName{Namespace: s.Scope.ClusterNamespace(), Name: key.ServiceAccountKeySecretName(s.Scope.ClusterName())}, oidcSecret)
 	if err != nil {
 		return nil, err
 	}
@@ -487,6 +672,327 @@ func (s *Service) ServiceAccountSecret(ctx context.Context) (*rsa.PrivateKey, er
 	return privateKey, nil
 }
 
+// preflight reports missing permissions of the cluster role up front. Failing to check them doesn't stop the
+// reconciliation, the actual calls will tell.
+func (s *Service) preflight() {
+	hostingMode := key.HostingModeCloudFront
+	if key.IsChina(s.Scope.Region()) {
+		hostingMode = key.HostingModeS3
+	}
+	route53 := s.Scope.DNSProvider() != dns.ProviderDNSEndpoint && s.Scope.DNSRoleARN() == s.Scope.ARN()
+
+	_, err := s.IAM.Preflight(iam.RequiredActions(hostingMode, route53, s.hasReplica()), s.AdditionalAccountIAM...)
+	if err != nil {
+		s.Scope.Logger().Error(err, "failed to check permissions of the cluster role")
+	}
+}
+
+func (s *Service) hasReplica() bool {
+	return s.S3Replica != nil && !key.IsChina(s.Scope.Region())
+}
+
+// reconcileReplicaBucket ensures the replica bucket exists and that the primary bucket replicates into it.
+func (s *Service) reconcileReplicaBucket(customerTags map[string]string, b backoff.Interface) error {
+	replicaBucketName := s.Scope.ReplicaBucketName()
+
+	err := s.S3Replica.IsBucketReady(replicaBucketName)
+	if err != nil {
+		createBucket := func() error {
+			err := s.S3Replica.CreateBucket(replicaBucketName)
+			if err != nil {
+				s.Scope.Logger().Error(err, "Failed to create replica S3 bucket, retrying")
+			}
+
+			return err
+		}
+		err = backoff.Retry(createBucket, b)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = s.S3Replica.EncryptBucket(replicaBucketName)
+	if err != nil {
+		return err
+	}
+
+	err = s.S3Replica.CreateTags(replicaBucketName, customerTags)
+	if err != nil {
+		return err
+	}
+
+	// Versioning is required on both sides of a replication.
+	err = s.S3.EnableVersioning(s.Scope.BucketName())
+	if err != nil {
+		return err
+	}
+	err = s.S3Replica.EnableVersioning(replicaBucketName)
+	if err != nil {
+		return err
+	}
+
+	roleArn, err := s.IAM.EnsureS3ReplicationRole(key.ReplicationRoleName(s.Scope.Installation(), s.Scope.ClusterName()), s.Scope.BucketName(), replicaBucketName, customerTags)
+	if err != nil {
+		return err
+	}
+
+	// A freshly created role takes a few seconds until S3 accepts it.
+	ensureReplication := func() error { return s.S3.EnsureReplication(s.Scope.BucketName(), replicaBucketName, roleArn) }
+	return backoff.Retry(ensureReplication, b)
+}
+
+// deleteReplicaBucket removes the replication of the primary bucket and deletes the replica bucket and the
+// replication role. Replicas that were removed from the annotation are found through the replication configuration
+// of the primary bucket, so that they don't outlive it. The primary bucket is deleted by the caller as usual.
+func (s *Service) deleteReplicaBucket() error {
+	replica, replicaBucketName, err := s.existingReplica()
+	if err != nil {
+		return err
+	}
+	if replica == nil {
+		return nil
+	}
+
+	err = s.S3.DeleteReplication(s.Scope.BucketName())
+	if err != nil {
+		return err
+	}
+
+	err = replica.DeleteFileVersions(replicaBucketName)
+	if err != nil {
+		return err
+	}
+
+	err = replica.DeleteBucket(replicaBucketName)
+	if err != nil {
+		return err
+	}
+
+	return s.IAM.DeleteS3ReplicationRole(key.ReplicationRoleName(s.Scope.Installation(), s.Scope.ClusterName()))
+}
+
+// existingReplica returns the replica bucket along with a service for its region, or nil if the primary bucket has
+// no replica.
+func (s *Service) existingReplica() (*s3.Service, string, error) {
+	if s.hasReplica() {
+		return s.S3Replica, s.Scope.ReplicaBucketName(), nil
+	}
+	if key.IsChina(s.Scope.Region()) {
+		return nil, "", nil
+	}
+
+	replicaBucketName, err := s.S3.GetReplicaBucketName(s.Scope.BucketName())
+	if err != nil {
+		return nil, "", err
+	}
+	if replicaBucketName == "" {
+		return nil, "", nil
+	}
+
+	region, err := s.S3.GetBucketRegion(replicaBucketName)
+	if err != nil {
+		return nil, "", err
+	}
+	s.Scope.Logger().Info("Found replica S3 bucket that is no longer configured", "replicaBucket", replicaBucketName, "replicaRegion", region)
+
+	return s3.NewReplicaService(s.Scope, region), replicaBucketName, nil
+}
+
+// requestCertificate ensures an ACM certificate for the domains validated through Route53 and returns its ARN
+// along with the hosted zone ID of each domain. It returns certificateNotIssuedError until the certificate is issued.
+func (s *Service) requestCertificate(domains []string, customerTags map[string]string, b backoff.Interface) (*string, map[string]string, error) {
+	// Ensure ACM certificate.
+	certificateArn, err := s.ACM.EnsureCertificate(domains, customerTags)
+	if err != nil {
+		s.Scope.Logger().Error(err, "failed to create ACM certificate")
+		return nil, nil, err
+	}
+
+	// wait for certificate to be issued.
+	issued, err := s.ACM.IsCertificateIssued(*certificateArn)
+	if err != nil {
+		s.Scope.Logger().Error(err, "failed to create ACM certificate")
+		return nil, nil, err
+	}
+
+	hostedZoneIDs, err := s.findHostedZones(domains)
+	if err != nil {
+		s.Scope.Logger().Error(err, "failed to find route53 hosted zone ID")
+		return nil, nil, err
+	}
+
+	// Check if domain ownership is validated
+	validated, err := s.ACM.IsValidated(*certificateArn)
+	if err != nil {
+		s.Scope.Logger().Error(err, "failed to check if ACM certificate's ownership is validated")
+		return nil, nil, err
+	}
+
+	if !validated {
+		// Check if DNS records are present
+		var records []acm.DomainValidationRecord
+		getValidationCNAMEs := func() error {
+			var err error
+			records, err = s.ACM.GetValidationCNAMEs(*certificateArn)
+			return err
+		}
+		err = backoff.Retry(getValidationCNAMEs, b)
+		if err != nil {
+			s.Scope.Logger().Error(err, "failed to get ACM certificate's validation DNS record details")
+			return nil, nil, err
+		}
+
+		for _, record := range records {
+			err = s.DNS.EnsureDNSRecord(hostedZoneIDs[record.Domain], record.CNAME)
+			if err != nil {
+				s.Scope.Logger().Error(err, "failed to create ACM certificate's validation DNS record", "domain", record.Domain)
+				return nil, nil, err
+			}
+
+			// ACM only validates once the records have propagated, which is just reported here.
+			_, err = dns.ChangeInSync(s.DNS, s.Scope, record.CNAME.Name, hostedZoneIDs[record.Domain])
+			if err != nil {
+				s.Scope.Logger().Error(err, "failed to check ACM certificate's validation DNS record propagation", "domain", record.Domain)
+				return nil, nil, err
+			}
+		}
+	}
+
+	if issued {
+		notAfter, err := s.ACM.GetCertificateExpirationTS(*certificateArn)
+		if err != nil {
+			s.Scope.Logger().Error(err, "failed to check ACM certificate's expiration date")
+			return nil, nil, err
+		}
+
+		ctrlmetrics.Certs.WithLabelValues(s.Scope.Installation(), s.Scope.AccountID(), s.Scope.ClusterName(), s.Scope.ClusterNamespace(), domains[0]).Set(float64(notAfter.Unix()))
+		err = s.ACM.ReportCertificateHealth(*certificateArn, domains[0], func(r acm.DomainValidationRecord) (bool, error) {
+			return s.DNS.RecordExists(hostedZoneIDs[r.Domain], r.CNAME)
+		})
+		if err != nil {
+			s.Scope.Logger().Error(err, "failed to check ACM certificate's health")
+		}
+	} else {
+		s.Scope.Logger().Info("ACM certificate is not issued yet")
+
+		return nil, nil, microerror.Mask(certificateNotIssuedError)
+	}
+
+	return certificateArn, hostedZoneIDs, nil
+}
+
+// importCertificate imports the certificate from the configured TLS secret into ACM and returns its ARN along with
+// the hosted zone ID of each domain. Domains without a Route53 zone have no entry, their DNS is managed elsewhere.
+func (s *Service) importCertificate(ctx context.Context, domains []string, customerTags map[string]string) (*string, map[string]string, error) {
+	secret := &v1.Secret{}
+	err := s.Client.Get(ctx, types.NamespacedName{Namespace: s.Scope.ClusterNamespace(), Name: s.Scope.CertificateSecretName()}, secret)
+	if err != nil {
+		s.Scope.Logger().Error(err, "failed to get certificate secret", "secret", s.Scope.CertificateSecretName())
+		return nil, nil, err
+	}
+
+	certificateArn, err := s.ACM.ImportCertificate(domains, secret.Data[v1.TLSCertKey], secret.Data[v1.TLSPrivateKeyKey], customerTags)
+	if err != nil {
+		s.Scope.Logger().Error(err, "failed to import ACM certificate")
+		return nil, nil, err
+	}
+
+	notAfter, err := s.ACM.GetCertificateExpirationTS(*certificateArn)
+	if err != nil {
+		s.Scope.Logger().Error(err, "failed to check ACM certificate's expiration date")
+		return nil, nil, err
+	}
+	ctrlmetrics.Certs.WithLabelValues(s.Scope.Installation(), s.Scope.AccountID(), s.Scope.ClusterName(), s.Scope.ClusterNamespace(), domains[0]).Set(float64(notAfter.Unix()))
+
+	hostedZoneIDs := make(map[string]string, len(domains))
+	for _, domain := range domains {
+		zones, err := s.findHostedZones([]string{domain})
+		if route53.IsZoneNotFound(err) {
+			s.Scope.Logger().Info("No Route53 hosted zone found for alias, DNS record needs to be managed elsewhere", "domain", domain)
+			continue
+		} else if err != nil {
+			s.Scope.Logger().Error(err, "failed to find route53 hosted zone ID")
+			return nil, nil, err
+		}
+		hostedZoneIDs[domain] = zones[domain]
+	}
+
+	return certificateArn, hostedZoneIDs, nil
+}
+
+// deleteDNSRecords removes the alias records pointing at the distribution and the validation records of the ACM
+// certificates. Records changed by someone else in the meantime are kept.
+func (s *Service) deleteDNSRecords(distributionDomain string) error {
+	records, err := s.ACM.GetValidationRecords(s.getCloudFrontAliasDomain())
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	// Aliases removed from the annotation earlier are still covered by the certificate's validation records, or
+	// recorded as removed if their records were not deleted yet.
+	domains := append([]string{s.getCloudFrontAliasDomain()}, s.Scope.ExtraAliases()...)
+	for _, alias := range s.Scope.RemovedAliases() {
+		if !util.StringInSlice(alias, domains) {
+			domains = append(domains, alias)
+		}
+	}
+	for _, record := range records {
+		if !util.StringInSlice(record.Domain, domains) {
+			domains = append(domains, record.Domain)
+		}
+	}
+
+	return s.deleteAliasRecords(domains, distributionDomain, records)
+}
+
+// deleteAliasRecords removes the alias records of the domains pointing at the distribution, along with their
+// ownership records, and the validation records of the domains.
+func (s *Service) deleteAliasRecords(domains []string, distributionDomain string, records []acm.DomainValidationRecord) error {
+	for _, domain := range domains {
+		hostedZoneIDs, err := s.findHostedZones([]string{domain})
+		if route53.IsZoneNotFound(err) {
+			s.Scope.Logger().Info("No Route53 hosted zone found for alias, skipping DNS record deletion", "domain", domain)
+			continue
+		} else if err != nil {
+			return microerror.Mask(err)
+		}
+		hostedZoneID := hostedZoneIDs[domain]
+
+		if distributionDomain != "" {
+			err = s.DNS.DeleteDNSRecords(hostedZoneID, domain, distributionDomain)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+		}
+
+		for _, record := range records {
+			if record.Domain != domain {
+				continue
+			}
+			err = s.DNS.DeleteDNSRecords(hostedZoneID, record.CNAME.Name, record.CNAME.Value)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// findHostedZones returns the closest enclosing public hosted zone ID for each of the given aliases.
+func (s *Service) findHostedZones(aliases []string) (map[string]string, error) {
+	hostedZoneIDs := make(map[string]string, len(aliases))
+	for _, alias := range aliases {
+		hostedZoneID, err := s.DNS.FindPublicHostedZone(alias)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		hostedZoneIDs[alias] = hostedZoneID
+	}
+
+	return hostedZoneIDs, nil
+}
+
 func (s *Service) getCloudFrontAliasDomain() string {
 	return key.CloudFrontAlias(s.Scope.BaseDomain())
 }
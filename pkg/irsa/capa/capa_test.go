@@ -0,0 +1,65 @@
+package capa
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/giantswarm/microerror"
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	capa "sigs.k8s.io/cluster-api-provider-aws/v2/api/v1beta2"
+
+	"github.com/giantswarm/irsa-operator/pkg/irsa/pipeline"
+	ctrlmetrics "github.com/giantswarm/irsa-operator/pkg/metrics"
+)
+
+func Test_errorCounter_certificateStep(t *testing.T) {
+	runner := &pipeline.Runner{
+		Name:         "capa",
+		Installation: "wonderland",
+		Logger:       logr.Discard(),
+		Object:       &capa.AWSCluster{ObjectMeta: metav1.ObjectMeta{Name: "lbj23", Namespace: "org-wonderland"}},
+		OnError:      errorCounter("wonderland", "123456789012", "lbj23", "org-wonderland"),
+	}
+	errorCount := ctrlmetrics.Errors.WithLabelValues("wonderland", "123456789012", "lbj23", "org-wonderland")
+
+	tests := []struct {
+		name      string
+		err       error
+		wantCount float64
+	}{
+		{
+			name:      "certificate not issued yet",
+			err:       microerror.Mask(certificateNotIssuedError),
+			wantCount: 0,
+		},
+		{
+			name:      "certificate request failed",
+			err:       errors.New("test"),
+			wantCount: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errorCount.Set(0)
+
+			_, err := runner.Run(context.Background(), []pipeline.Step{
+				{
+					Name:        "ensure-certificate",
+					CountErrors: true,
+					Action: pipeline.Do(func(ctx context.Context) error {
+						return tt.err
+					}),
+				},
+			})
+			if err == nil {
+				t.Fatalf("Run() error = nil, want the error of the step")
+			}
+			if got := testutil.ToFloat64(errorCount); got != tt.wantCount {
+				t.Errorf("Run() counted %v errors, want %v", got, tt.wantCount)
+			}
+		})
+	}
+}
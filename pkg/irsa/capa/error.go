@@ -10,6 +10,11 @@ var certificateNotIssuedError = &microerror.Error{
 	Kind: "certificateNotIssuedError",
 }
 
+// IsCertificateNotIssued asserts certificateNotIssuedError.
+func IsCertificateNotIssued(err error) bool {
+	return microerror.Cause(err) == certificateNotIssuedError
+}
+
 type CloudfrontDistributionNotDisabledError struct {
 }
 
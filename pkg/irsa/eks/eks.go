@@ -2,10 +2,13 @@ package eks
 
 import (
 	"context"
+	"time"
 
 	"github.com/giantswarm/microerror"
 	"k8s.io/apimachinery/pkg/types"
 	controlplanecapa "sigs.k8s.io/cluster-api-provider-aws/v2/controlplane/eks/api/v1beta2"
+	capi "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/giantswarm/irsa-operator/pkg/aws/scope"
@@ -21,22 +24,58 @@ type Service struct {
 
 	IAM *iam.Service
 	EKS *eks.Service
+
+	// AdditionalAccountIAM registers the cluster's OIDC providers in the accounts of the additional account roles.
+	AdditionalAccountIAM []*iam.Service
+	// RemovedAccountIAM deletes the cluster's OIDC providers from the accounts removed from the additional account
+	// roles.
+	RemovedAccountIAM []*iam.Service
 }
 
 func New(scope *scope.ClusterScope, client client.Client) *Service {
-	return &Service{
+	s := &Service{
 		Scope:  scope,
 		Client: client,
 
 		EKS: eks.NewService(scope),
 		IAM: iam.NewService(scope),
 	}
+
+	for _, roleARN := range scope.AdditionalAccountRoleARNs() {
+		s.AdditionalAccountIAM = append(s.AdditionalAccountIAM, iam.NewServiceForRole(scope, roleARN))
+	}
+	for _, roleARN := range scope.RemovedAccountRoleARNs() {
+		s.RemovedAccountIAM = append(s.RemovedAccountIAM, iam.NewServiceForRole(scope, roleARN))
+	}
+
+	return s
 }
-func (s *Service) Reconcile(ctx context.Context) error {
+
+// Reconcile ensures the OIDC providers and pod identity associations of the cluster. While the EKS cluster has no
+// OIDC issuer yet, it returns without error and sets outRequeueAfter to check again.
+func (s *Service) Reconcile(ctx context.Context, outRequeueAfter *time.Duration) error {
 	s.Scope.Logger().Info("Reconciling AWSManagedCluster CR for IRSA")
-	oidcURL, err := s.EKS.GetEKSOpenIDConnectProviderURL(s.Scope.ClusterName())
+
+	// Failing to check the permissions doesn't stop the reconciliation, the actual calls will tell.
+	actions := iam.RequiredActions(key.HostingModeEKS, false, false)
+	if s.Scope.PodIdentityAssociations() != nil || s.Scope.PodIdentityManaged() {
+		actions = append(actions, iam.PodIdentityActions...)
+	}
+	_, err := s.IAM.Preflight(actions, s.AdditionalAccountIAM...)
 	if err != nil {
+		s.Scope.Logger().Error(err, "failed to check permissions of the cluster role")
+	}
+
+	oidcURL, err := s.EKS.GetEKSOpenIDConnectProviderURL(s.Scope.ClusterName())
+	if eks.IsOIDCIssuerNotAvailable(err) {
+		s.Scope.Logger().Info("EKS OIDC issuer is not available yet, waiting ...", "reason", err.Error())
+		s.markOIDCProviderNotReady("OIDCIssuerNotAvailable", "EKS OIDC issuer is not available yet: %s", err)
+
+		*outRequeueAfter = time.Minute
+		return nil
+	} else if err != nil {
 		s.Scope.Logger().Error(err, "failed to fetch EKS OIDC issuer URL")
+		s.markOIDCProviderNotReady("ReconciliationFailed", "Failed to fetch EKS OIDC issuer URL: %s", err)
 		return microerror.Mask(err)
 	}
 	identityProviderURLs := []string{oidcURL}
@@ -48,19 +87,95 @@ func (s *Service) Reconcile(ctx context.Context) error {
 		return microerror.Mask(err)
 	}
 
-	err = s.IAM.EnsureOIDCProviders(identityProviderURLs, []string{}, key.STSUrl(s.Scope.Region()), cluster.Spec.AdditionalTags)
+	audiences := append([]string{key.STSUrl(s.Scope.Region())}, s.Scope.AdditionalAudiences()...)
+	err = s.IAM.EnsureOIDCProviders(identityProviderURLs, []string{}, audiences, cluster.Spec.AdditionalTags)
 	if err != nil {
 		ctrlmetrics.Errors.WithLabelValues(s.Scope.Installation(), s.Scope.AccountID(), s.Scope.ClusterName(), s.Scope.ClusterNamespace()).Inc()
 		s.Scope.Logger().Error(err, "failed to create OIDC provider")
+		s.markOIDCProviderNotReady("ReconciliationFailed", "Failed to create OIDC provider: %s", err)
 		return err
 	}
 
+	// The accounts are recorded before the providers are created, so that they are still known if they are removed
+	// from the annotation before the reconciliation succeeds.
+	s.Scope.SetRegisteredAccountRoleARNs(append(append([]string{}, s.Scope.AdditionalAccountRoleARNs()...), s.Scope.RemovedAccountRoleARNs()...))
+
+	for _, additionalAccountIAM := range s.AdditionalAccountIAM {
+		err = additionalAccountIAM.EnsureOIDCProviders(identityProviderURLs, []string{}, audiences, cluster.Spec.AdditionalTags)
+		if err != nil {
+			ctrlmetrics.Errors.WithLabelValues(s.Scope.Installation(), s.Scope.AccountID(), s.Scope.ClusterName(), s.Scope.ClusterNamespace()).Inc()
+			s.Scope.Logger().Error(err, "failed to create OIDC provider in additional account")
+			s.markOIDCProviderNotReady("ReconciliationFailed", "Failed to create OIDC provider in additional account: %s", err)
+			return err
+		}
+	}
+
+	for _, removedAccountIAM := range s.RemovedAccountIAM {
+		err = removedAccountIAM.DeleteOIDCProviders()
+		if err != nil {
+			ctrlmetrics.Errors.WithLabelValues(s.Scope.Installation(), s.Scope.AccountID(), s.Scope.ClusterName(), s.Scope.ClusterNamespace()).Inc()
+			s.Scope.Logger().Error(err, "failed to delete OIDC provider in removed account")
+			s.markOIDCProviderNotReady("ReconciliationFailed", "Failed to delete OIDC provider in removed account: %s", err)
+			return err
+		}
+	}
+	s.Scope.SetRegisteredAccountRoleARNs(s.Scope.AdditionalAccountRoleARNs())
+
+	// Pod identity associations are only managed while the annotation is set, so that clusters can migrate from IRSA
+	// service account by service account. The cluster is marked before the associations are created, so that they
+	// are deleted once the annotation is removed.
+	if s.Scope.PodIdentityAssociations() != nil {
+		s.Scope.SetPodIdentityManaged(true)
+
+		if len(s.Scope.PodIdentityAssociations()) > 0 {
+			err = s.EKS.EnsurePodIdentityAgentAddon(s.Scope.ClusterName(), cluster.Spec.AdditionalTags)
+			if err != nil {
+				ctrlmetrics.Errors.WithLabelValues(s.Scope.Installation(), s.Scope.AccountID(), s.Scope.ClusterName(), s.Scope.ClusterNamespace()).Inc()
+				s.Scope.Logger().Error(err, "failed to ensure EKS Pod Identity agent add-on")
+				s.markOIDCProviderNotReady("ReconciliationFailed", "Failed to ensure EKS Pod Identity agent add-on: %s", err)
+				return err
+			}
+		}
+
+		err = s.EKS.EnsurePodIdentityAssociations(s.Scope.ClusterName(), s.Scope.PodIdentityAssociations(), cluster.Spec.AdditionalTags)
+		if err != nil {
+			ctrlmetrics.Errors.WithLabelValues(s.Scope.Installation(), s.Scope.AccountID(), s.Scope.ClusterName(), s.Scope.ClusterNamespace()).Inc()
+			s.Scope.Logger().Error(err, "failed to ensure pod identity associations")
+			s.markOIDCProviderNotReady("ReconciliationFailed", "Failed to ensure pod identity associations: %s", err)
+			return err
+		}
+	} else if s.Scope.PodIdentityManaged() {
+		err = s.EKS.DeletePodIdentityAssociations(s.Scope.ClusterName())
+		if err != nil {
+			ctrlmetrics.Errors.WithLabelValues(s.Scope.Installation(), s.Scope.AccountID(), s.Scope.ClusterName(), s.Scope.ClusterNamespace()).Inc()
+			s.Scope.Logger().Error(err, "failed to delete pod identity associations")
+			s.markOIDCProviderNotReady("ReconciliationFailed", "Failed to delete pod identity associations: %s", err)
+			return err
+		}
+		s.Scope.SetPodIdentityManaged(false)
+	}
+
+	// Only ready once all steps succeeded, including the pod identity associations.
+	if setter, ok := s.Scope.Cluster().(conditions.Setter); ok {
+		conditions.MarkTrue(setter, key.IRSAOIDCProviderCondition)
+	}
+
 	ctrlmetrics.Errors.WithLabelValues(s.Scope.Installation(), s.Scope.AccountID(), s.Scope.ClusterName(), s.Scope.ClusterNamespace()).Set(0)
 	s.Scope.Logger().Info("Finished reconciling on all resources.")
 	return nil
 }
 
 func (s *Service) Delete(ctx context.Context) error {
+	// Associations are deleted together with the EKS cluster anyway, this only covers clusters that are kept.
+	if s.Scope.PodIdentityAssociations() != nil || s.Scope.PodIdentityManaged() {
+		err := s.EKS.DeletePodIdentityAssociations(s.Scope.ClusterName())
+		if err != nil {
+			ctrlmetrics.Errors.WithLabelValues(s.Scope.Installation(), s.Scope.AccountID(), s.Scope.ClusterName(), s.Scope.ClusterNamespace()).Inc()
+			s.Scope.Logger().Error(err, "failed to delete pod identity associations")
+			return err
+		}
+	}
+
 	err := s.IAM.DeleteOIDCProviders()
 	if err != nil {
 		ctrlmetrics.Errors.WithLabelValues(s.Scope.Installation(), s.Scope.AccountID(), s.Scope.ClusterName(), s.Scope.ClusterNamespace()).Inc()
@@ -68,8 +183,32 @@ func (s *Service) Delete(ctx context.Context) error {
 		return err
 	}
 
+	for _, additionalAccountIAM := range s.AdditionalAccountIAM {
+		err = additionalAccountIAM.DeleteOIDCProviders()
+		if err != nil {
+			ctrlmetrics.Errors.WithLabelValues(s.Scope.Installation(), s.Scope.AccountID(), s.Scope.ClusterName(), s.Scope.ClusterNamespace()).Inc()
+			s.Scope.Logger().Error(err, "failed to delete OIDC provider in additional account")
+			return err
+		}
+	}
+
+	for _, removedAccountIAM := range s.RemovedAccountIAM {
+		err = removedAccountIAM.DeleteOIDCProviders()
+		if err != nil {
+			ctrlmetrics.Errors.WithLabelValues(s.Scope.Installation(), s.Scope.AccountID(), s.Scope.ClusterName(), s.Scope.ClusterNamespace()).Inc()
+			s.Scope.Logger().Error(err, "failed to delete OIDC provider in removed account")
+			return err
+		}
+	}
+
 	ctrlmetrics.Errors.DeleteLabelValues(s.Scope.Installation(), s.Scope.AccountID(), s.Scope.ClusterName(), s.Scope.ClusterNamespace())
 	s.Scope.Logger().Info("Finished deleting all resources.")
 
 	return nil
 }
+
+func (s *Service) markOIDCProviderNotReady(reason, messageFormat string, err error) {
+	if setter, ok := s.Scope.Cluster().(conditions.Setter); ok {
+		conditions.MarkFalse(setter, key.IRSAOIDCProviderCondition, reason, capi.ConditionSeverityWarning, messageFormat, err.Error())
+	}
+}
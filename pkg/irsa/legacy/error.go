@@ -5,3 +5,8 @@ import "github.com/giantswarm/microerror"
 var certificateNotIssuedError = &microerror.Error{
 	Kind: "certificateNotIssuedError",
 }
+
+// IsCertificateNotIssued asserts certificateNotIssuedError.
+func IsCertificateNotIssued(err error) bool {
+	return microerror.Cause(err) == certificateNotIssuedError
+}
@@ -26,7 +26,9 @@ import (
 	"github.com/giantswarm/irsa-operator/pkg/aws/services/iam"
 	"github.com/giantswarm/irsa-operator/pkg/aws/services/route53"
 	"github.com/giantswarm/irsa-operator/pkg/aws/services/s3"
+	"github.com/giantswarm/irsa-operator/pkg/dns"
 	irsaerrors "github.com/giantswarm/irsa-operator/pkg/errors"
+	"github.com/giantswarm/irsa-operator/pkg/irsa/pipeline"
 	"github.com/giantswarm/irsa-operator/pkg/key"
 	ctrlmetrics "github.com/giantswarm/irsa-operator/pkg/metrics"
 	"github.com/giantswarm/irsa-operator/pkg/pkcs"
@@ -39,333 +41,473 @@ type Service struct {
 
 	ACM        *acm.Service
 	Cloudfront *cloudfront.Service
+	DNS        dns.Provider
 	IAM        *iam.Service
-	Route53    *route53.Service
 	S3         *s3.Service
+
+	// AdditionalAccountIAM registers the cluster's OIDC providers in the accounts of the additional account roles.
+	AdditionalAccountIAM []*iam.Service
+	// RemovedAccountIAM deletes the cluster's OIDC providers from the accounts removed from the additional account
+	// roles.
+	RemovedAccountIAM []*iam.Service
 }
 
 func New(scope *scope.ClusterScope, client client.Client) *Service {
-	return &Service{
+	s := &Service{
 		Scope:  scope,
 		Client: client,
 
 		ACM:        acm.NewService(scope),
 		Cloudfront: cloudfront.NewService(scope),
+		DNS:        dns.NewProvider(scope, client),
 		IAM:        iam.NewService(scope),
-		Route53:    route53.NewService(scope),
 		S3:         s3.NewService(scope),
 	}
+
+	for _, roleARN := range scope.AdditionalAccountRoleARNs() {
+		s.AdditionalAccountIAM = append(s.AdditionalAccountIAM, iam.NewServiceForRole(scope, roleARN))
+	}
+	for _, roleARN := range scope.RemovedAccountRoleARNs() {
+		s.RemovedAccountIAM = append(s.RemovedAccountIAM, iam.NewServiceForRole(scope, roleARN))
+	}
+
+	return s
+}
+
+// reconcileState holds what the steps of a reconciliation hand on to the later ones.
+type reconcileState struct {
+	aliases             []*string
+	baseDomain          string
+	certificateARN      string
+	customerTags        map[string]string
+	dnsChangesPending   bool
+	distribution        *cloudfront.Distribution
+	privateHostedZoneID string
+	privateKey          *rsa.PrivateKey
+	publicHostedZoneID  string
+	// cfDomain and cfOaiId are only set when the files are served by CloudFront.
+	cfDomain string
+	cfOaiId  string
 }
-func (s *Service) Reconcile(ctx context.Context) error {
-	var cfDomain string
-	var cfOaiId string
 
+func (s *Service) Reconcile(ctx context.Context, outRequeueAfter *time.Duration) error {
 	s.Scope.Logger().Info("Reconciling AWSCluster CR for IRSA")
-	privateKey, err := s.ServiceAccountSecret(ctx)
-	if err != nil {
-		return err
-	}
+
+	s.preflight()
 
 	b := backoff.NewMaxRetries(3, 5*time.Second)
 
-	err = s.S3.IsBucketReady(s.Scope.BucketName())
-	// check if bucket exists
-	if err != nil {
-		createBucket := func() error {
-			err := s.S3.CreateBucket(s.Scope.BucketName())
-			if err != nil {
-				s.Scope.Logger().Error(err, "Failed to create S3 bucket, retrying")
+	runner := &pipeline.Runner{
+		Name:         "legacy",
+		Installation: s.Scope.Installation(),
+		Logger:       s.Scope.Logger(),
+		Backoff:      b,
+		Object:       s.Scope.Cluster(),
+		OnError: func(step string, err error) {
+			// Waiting for the certificate to be issued is not an error of the reconciliation.
+			if IsCertificateNotIssued(err) {
+				return
 			}
-
-			return err
-		}
-		err = backoff.Retry(createBucket, b)
-		if err != nil {
 			ctrlmetrics.Errors.WithLabelValues(s.Scope.Installation(), s.Scope.AccountID(), s.Scope.ClusterName(), s.Scope.ClusterNamespace()).Inc()
-			s.Scope.Logger().Error(err, "failed to create bucket")
-			return err
-		}
+		},
+		Cache: s.Scope.Cache(),
 	}
-
-	err = s.S3.EncryptBucket(s.Scope.BucketName())
+	result, err := runner.Run(ctx, s.steps(&reconcileState{}))
 	if err != nil {
-		ctrlmetrics.Errors.WithLabelValues(s.Scope.Installation(), s.Scope.AccountID(), s.Scope.ClusterName(), s.Scope.ClusterNamespace()).Inc()
-		s.Scope.Logger().Error(err, "failed to encrypt bucket")
-		return err
-	}
-
-	// Fetch custom tags from Cluster CR
-	cluster := &capi.Cluster{}
-	err = s.Client.Get(ctx, types.NamespacedName{Namespace: s.Scope.ClusterNamespace(), Name: s.Scope.ClusterName()}, cluster)
-	if apierrors.IsNotFound(err) {
-		// fallthrough
-	} else if err != nil {
 		return err
 	}
-
-	baseDomain, err := key.BaseDomain(*cluster)
-	if err != nil {
-		return err
+	if result.RequeueAfter > 0 {
+		*outRequeueAfter = result.RequeueAfter
 	}
-
-	customerTags := key.GetCustomerTags(cluster)
-	aliases := make([]*string, 0)
-	cloudfrontCertificateARN := ""
-
-	err = s.S3.CreateTags(s.Scope.BucketName(), customerTags)
-	if err != nil {
-		ctrlmetrics.Errors.WithLabelValues(s.Scope.Installation(), s.Scope.AccountID(), s.Scope.ClusterName(), s.Scope.ClusterNamespace()).Inc()
-		s.Scope.Logger().Error(err, "failed to create tags")
-		return err
+	if result.Stopped() {
+		return nil
 	}
 
-	// Cloudfront only for non-China region and v18.x.x release or higher
-	if !key.IsChina(s.Scope.Region()) && key.IsV18Release(s.Scope.Release()) || (s.Scope.MigrationNeeded() && !key.IsChina(s.Scope.Region())) {
-		var publicHostedZoneID string
-		var privateHostedZoneID string
-		cloudfrontAliasDomain := key.CloudFrontAlias(baseDomain)
-		if cloudfrontAliasDomain != "" {
-			// Ensure ACM certificate.
-			certificateArn, err := s.ACM.EnsureCertificate(cloudfrontAliasDomain, customerTags)
-			if err != nil {
-				ctrlmetrics.Errors.WithLabelValues(s.Scope.Installation(), s.Scope.AccountID(), s.Scope.ClusterName(), s.Scope.ClusterNamespace()).Inc()
-				s.Scope.Logger().Error(err, "failed to create ACM certificate")
-				return err
-			}
-
-			// wait for certificate to be issued.
-			issued, err := s.ACM.IsCertificateIssued(*certificateArn)
-			if err != nil {
-				ctrlmetrics.Errors.WithLabelValues(s.Scope.Installation(), s.Scope.AccountID(), s.Scope.ClusterName(), s.Scope.ClusterNamespace()).Inc()
-				s.Scope.Logger().Error(err, "failed to check if ACM certificate is issued")
-				return err
-			}
-
-			publicHostedZoneID, err = s.Route53.FindPublicHostedZone(baseDomain)
-			if err != nil {
-				ctrlmetrics.Errors.WithLabelValues(s.Scope.Installation(), s.Scope.AccountID(), s.Scope.ClusterName(), s.Scope.ClusterNamespace()).Inc()
-				s.Scope.Logger().Error(err, "failed to find route53 hosted zone ID")
-				return err
-			}
-
-			privateHostedZoneID, err = s.Route53.FindPrivateHostedZone(baseDomain)
-			if err != nil {
-				ctrlmetrics.Errors.WithLabelValues(s.Scope.Installation(), s.Scope.AccountID(), s.Scope.ClusterName(), s.Scope.ClusterNamespace()).Inc()
-				s.Scope.Logger().Error(err, "failed to find route53 hosted zone ID")
-				return err
-			}
+	ctrlmetrics.Errors.WithLabelValues(s.Scope.Installation(), s.Scope.AccountID(), s.Scope.ClusterName(), s.Scope.ClusterNamespace()).Set(0)
+	s.Scope.Logger().Info("Finished reconciling on all resources.")
+	return nil
+}
 
-			// Check if domain ownership is validated
-			validated, err := s.ACM.IsValidated(*certificateArn)
-			if err != nil {
-				ctrlmetrics.Errors.WithLabelValues(s.Scope.Installation(), s.Scope.AccountID(), s.Scope.ClusterName(), s.Scope.ClusterNamespace()).Inc()
-				s.Scope.Logger().Error(err, "failed to check if ACM certificate's ownership is validated")
+// steps returns the steps of a reconciliation in the order they run.
+func (s *Service) steps(st *reconcileState) []pipeline.Step {
+	return []pipeline.Step{
+		{
+			Name: "get-service-account-key",
+			Action: pipeline.Do(func(ctx context.Context) error {
+				var err error
+				st.privateKey, err = s.ServiceAccountSecret(ctx)
 				return err
-			}
-
-			if !validated {
-				// Check if DNS record is present
-				cname, err := s.ACM.GetValidationCNAME(*certificateArn)
-				if err != nil {
-					ctrlmetrics.Errors.WithLabelValues(s.Scope.Installation(), s.Scope.AccountID(), s.Scope.ClusterName(), s.Scope.ClusterNamespace()).Inc()
-					s.Scope.Logger().Error(err, "failed to get ACM certificate's validation DNS record details")
+			}),
+		},
+		{
+			Name:        "ensure-bucket",
+			Retry:       true,
+			CountErrors: true,
+			Action: pipeline.Do(func(ctx context.Context) error {
+				if s.S3.IsBucketReady(s.Scope.BucketName()) == nil {
+					return nil
+				}
+				return s.S3.CreateBucket(s.Scope.BucketName())
+			}),
+		},
+		{
+			Name:        "encrypt-bucket",
+			CountErrors: true,
+			Action: pipeline.Do(func(ctx context.Context) error {
+				return s.S3.EncryptBucket(s.Scope.BucketName())
+			}),
+		},
+		{
+			// Custom tags come from the Cluster CR.
+			Name: "get-cluster",
+			Action: pipeline.Do(func(ctx context.Context) error {
+				cluster := &capi.Cluster{}
+				err := s.Client.Get(ctx, types.NamespacedName{Namespace: s.Scope.ClusterNamespace(), Name: s.Scope.ClusterName()}, cluster)
+				if apierrors.IsNotFound(err) {
+					// fallthrough
+				} else if err != nil {
 					return err
 				}
 
-				err = s.Route53.EnsureDNSRecord(publicHostedZoneID, *cname)
+				st.baseDomain, err = key.BaseDomain(*cluster)
 				if err != nil {
-					ctrlmetrics.Errors.WithLabelValues(s.Scope.Installation(), s.Scope.AccountID(), s.Scope.ClusterName(), s.Scope.ClusterNamespace()).Inc()
-					s.Scope.Logger().Error(err, "failed to create ACM certificate's validation DNS record")
 					return err
 				}
-			}
+				st.customerTags = key.GetCustomerTags(cluster)
 
-			if issued {
-				notAfter, err := s.ACM.GetCertificateExpirationTS(*certificateArn)
-				if err != nil {
-					ctrlmetrics.Errors.WithLabelValues(s.Scope.Installation(), s.Scope.AccountID(), s.Scope.ClusterName(), s.Scope.ClusterNamespace()).Inc()
-					s.Scope.Logger().Error(err, "failed to check ACM certificate's expiration date")
-					return err
+				return nil
+			}),
+		},
+		{
+			Name:        "tag-bucket",
+			CountErrors: true,
+			Action: pipeline.Do(func(ctx context.Context) error {
+				return s.S3.CreateTags(s.Scope.BucketName(), st.customerTags)
+			}),
+		},
+		{
+			Name:         "ensure-certificate",
+			Precondition: func() bool { return s.cloudFrontEnabled() && key.CloudFrontAlias(st.baseDomain) != "" },
+			CountErrors:  true,
+			Action: pipeline.Do(func(ctx context.Context) error {
+				return s.ensureCertificate(st)
+			}),
+		},
+		{
+			Name:         "ensure-distribution",
+			Precondition: s.cloudFrontEnabled,
+			CountErrors:  true,
+			Action: pipeline.Do(func(ctx context.Context) error {
+				var err error
+				st.distribution, err = s.Cloudfront.EnsureDistribution(cloudfront.DistributionConfig{
+					Aliases:        st.aliases,
+					CertificateArn: st.certificateARN,
+					CustomerTags:   st.customerTags,
+					EnableCaching:  s.Scope.CloudFrontCaching(),
+				})
+				return err
+			}),
+		},
+		{
+			// Certificates with an earlier key algorithm are no longer needed once the distribution uses the current one.
+			Name:         "delete-unused-certificates",
+			Precondition: func() bool { return st.certificateARN != "" },
+			CountErrors:  true,
+			Action: pipeline.Do(func(ctx context.Context) error {
+				return s.ACM.DeleteUnusedCertificates(key.CloudFrontAlias(st.baseDomain), st.certificateARN)
+			}),
+		},
+		{
+			Name:         "ensure-alias-records",
+			Precondition: func() bool { return len(st.aliases) > 0 },
+			CountErrors:  true,
+			Action: pipeline.Do(func(ctx context.Context) error {
+				var hostedZoneIDs []string
+				for _, hostedZoneID := range []string{st.publicHostedZoneID, st.privateHostedZoneID} {
+					if hostedZoneID != "" {
+						hostedZoneIDs = append(hostedZoneIDs, hostedZoneID)
+					}
 				}
 
-				ctrlmetrics.Certs.WithLabelValues(s.Scope.Installation(), s.Scope.AccountID(), s.Scope.ClusterName(), s.Scope.ClusterNamespace(), cloudfrontAliasDomain).Set(float64(notAfter.Unix()))
-			} else {
-				s.Scope.Logger().Info("ACM certificate is not issued yet")
+				for _, hostedZoneID := range hostedZoneIDs {
+					for _, alias := range st.aliases {
+						// Create IRSA alias records
+						err := s.DNS.EnsureAliasRecords(hostedZoneID, *alias, st.distribution.Domain)
+						if err != nil {
+							return err
+						}
+					}
+				}
 
-				return microerror.Mask(certificateNotIssuedError)
-			}
+				for _, alias := range st.aliases {
+					inSync, err := dns.ChangeInSync(s.DNS, s.Scope, *alias, hostedZoneIDs...)
+					if err != nil {
+						return err
+					}
+					st.dnsChangesPending = st.dnsChangesPending || !inSync
+				}
 
-			aliases = append(aliases, &cloudfrontAliasDomain)
-			cloudfrontCertificateARN = *certificateArn
-		}
+				return nil
+			}),
+		},
+		{
+			Name:         "ensure-cloudfront-config",
+			Precondition: s.cloudFrontEnabled,
+			CountErrors:  true,
+			Action: pipeline.Do(func(ctx context.Context) error {
+				return s.ensureCloudfrontConfig(ctx, st)
+			}),
+		},
+		{
+			// restrict access only for non-China region and v18.x.x release or higher
+			Name:         "update-bucket-policy",
+			Precondition: s.cloudFrontEnabled,
+			Retry:        true,
+			Action: pipeline.Do(func(ctx context.Context) error {
+				return s.S3.UpdatePolicy(s.Scope.BucketName(), st.cfOaiId)
+			}),
+		},
+		{
+			Name:         "block-public-access",
+			Precondition: s.cloudFrontEnabled,
+			Action: pipeline.Do(func(ctx context.Context) error {
+				return s.S3.BlockPublicAccess(s.Scope.BucketName())
+			}),
+		},
+		{
+			Name:         "allow-public-access",
+			Precondition: func() bool { return !s.cloudFrontEnabled() },
+			Action: pipeline.Do(func(ctx context.Context) error {
+				return s.S3.AllowPublicAccess(s.Scope.BucketName())
+			}),
+		},
+		{
+			Name:        "upload-files",
+			Retry:       true,
+			CountErrors: true,
+			Action: pipeline.Do(func(ctx context.Context) error {
+				domain := st.cfDomain
+				if len(st.aliases) > 0 {
+					domain = *st.aliases[0] //nolint:gosec
+				}
+				return s.S3.UploadFiles(s.Scope.Release(), domain, s.Scope.BucketName(), st.privateKey)
+			}),
+		},
+		{
+			// IAM fetches the issuer through the aliases, so wait until their records have propagated.
+			Name: "wait-for-alias-records",
+			Action: func(ctx context.Context) (pipeline.Result, error) {
+				if !st.dnsChangesPending {
+					return pipeline.Result{}, nil
+				}
+				s.Scope.Logger().Info("Alias records are not propagated yet, waiting ...")
 
-		distribution, err := s.Cloudfront.EnsureDistribution(cloudfront.DistributionConfig{
-			Aliases:        aliases,
-			CertificateArn: cloudfrontCertificateARN,
-			CustomerTags:   customerTags,
-		})
-		if err != nil {
-			ctrlmetrics.Errors.WithLabelValues(s.Scope.Installation(), s.Scope.AccountID(), s.Scope.ClusterName(), s.Scope.ClusterNamespace()).Inc()
-			s.Scope.Logger().Error(err, "failed to create cloudfront distribution")
-			return err
-		}
+				return pipeline.Result{RequeueAfter: 15 * time.Second}, nil
+			},
+		},
+		{
+			Name:        "ensure-oidc-providers",
+			Retry:       true,
+			CountErrors: true,
+			Action: pipeline.Do(func(ctx context.Context) error {
+				var identityProviderURLs []string
+				var identityProviderURLsToDelete []string
+				s3Endpoint := fmt.Sprintf("s3.%s.%s", s.Scope.Region(), key.AWSEndpoint(s.Scope.Region()))
+				if s.cloudFrontEnabled() {
+					if s.Scope.KeepCloudFrontOIDCProvider() {
+						identityProviderURLs = append(identityProviderURLs, util.EnsureHTTPS(st.cfDomain))
+					} else {
+						identityProviderURLsToDelete = append(identityProviderURLs, util.EnsureHTTPS(st.cfDomain))
+					}
+				} else {
+					identityProviderURLs = append(identityProviderURLs, util.EnsureHTTPS(fmt.Sprintf("%s/%s", s3Endpoint, s.Scope.BucketName())))
+				}
 
-		data := map[string]string{
-			"arn":                    distribution.ARN,
-			"domain":                 distribution.Domain,
-			"distributionId":         distribution.DistributionId,
-			"originAccessIdentityId": distribution.OriginAccessIdentityId,
-		}
+				for _, alias := range st.aliases {
+					identityProviderURLs = append(identityProviderURLs, util.EnsureHTTPS(*alias))
+				}
+
+				audiences := append([]string{key.STSUrl(s.Scope.Region())}, s.Scope.AdditionalAudiences()...)
+				err := s.IAM.EnsureOIDCProviders(identityProviderURLs, identityProviderURLsToDelete, audiences, st.customerTags)
+				if err != nil {
+					return microerror.Mask(err)
+				}
 
-		if len(aliases) > 0 {
-			if publicHostedZoneID != "" {
-				for _, alias := range aliases {
-					// Create IRSA Alias CNAME
-					err = s.Route53.EnsureDNSRecord(publicHostedZoneID, route53.CNAME{Name: *alias, Value: key.EnsureTrailingDot(distribution.Domain)})
+				// The accounts are recorded before the providers are created, so that they are still known if they
+				// are removed from the annotation before the reconciliation succeeds.
+				s.Scope.SetRegisteredAccountRoleARNs(append(append([]string{}, s.Scope.AdditionalAccountRoleARNs()...), s.Scope.RemovedAccountRoleARNs()...))
+
+				for _, additionalAccountIAM := range s.AdditionalAccountIAM {
+					err := additionalAccountIAM.EnsureOIDCProviders(identityProviderURLs, identityProviderURLsToDelete, audiences, st.customerTags)
 					if err != nil {
-						ctrlmetrics.Errors.WithLabelValues(s.Scope.Installation(), s.Scope.AccountID(), s.Scope.ClusterName(), s.Scope.ClusterNamespace()).Inc()
-						s.Scope.Logger().Error(err, "failed to create cloudfront CNAME record in the public zone")
-						return err
+						return microerror.Mask(err)
 					}
 				}
-			}
-			if privateHostedZoneID != "" {
-				for _, alias := range aliases {
-					// Create IRSA Alias CNAME
-					err = s.Route53.EnsureDNSRecord(privateHostedZoneID, route53.CNAME{Name: *alias, Value: key.EnsureTrailingDot(distribution.Domain)})
+
+				for _, removedAccountIAM := range s.RemovedAccountIAM {
+					err := removedAccountIAM.DeleteOIDCProviders()
 					if err != nil {
-						ctrlmetrics.Errors.WithLabelValues(s.Scope.Installation(), s.Scope.AccountID(), s.Scope.ClusterName(), s.Scope.ClusterNamespace()).Inc()
-						s.Scope.Logger().Error(err, "failed to create cloudfront CNAME record in the private zone")
-						return err
+						return microerror.Mask(err)
 					}
 				}
-			}
+				s.Scope.SetRegisteredAccountRoleARNs(s.Scope.AdditionalAccountRoleARNs())
 
-			if key.IsV19Release(s.Scope.Release()) || s.Scope.PreCloudfrontAlias() {
-				data["domainAlias"] = *aliases[0] //nolint:gosec
-			}
-		}
+				return nil
+			}),
+		},
+	}
+}
 
-		cfConfig := &v1.ConfigMap{}
-		err = s.Client.Get(ctx, types.NamespacedName{Namespace: s.Scope.ClusterNamespace(), Name: s.Scope.ConfigName()}, cfConfig)
-		if apierrors.IsNotFound(err) {
-			if err := irsaerrors.IsEmptyCloudfrontDistribution(distribution); err != nil {
-				ctrlmetrics.Errors.WithLabelValues(s.Scope.Installation(), s.Scope.AccountID(), s.Scope.ClusterName(), s.Scope.ClusterNamespace()).Inc()
-				s.Scope.Logger().Error(err, "cloudfront distribution cannot be nil")
-				return err
-			}
+// cloudFrontEnabled returns whether the files are served by CloudFront, which is only the case for non-China
+// regions and v18.x.x releases or higher.
+func (s *Service) cloudFrontEnabled() bool {
+	return !key.IsChina(s.Scope.Region()) && key.IsV18Release(s.Scope.Release()) || (s.Scope.MigrationNeeded() && !key.IsChina(s.Scope.Region()))
+}
 
-			// create new OIDC Cloudfront config
-			cfConfig = &v1.ConfigMap{
-				ObjectMeta: metav1.ObjectMeta{
-					Name:      s.Scope.ConfigName(),
-					Namespace: s.Scope.ClusterNamespace(),
-				},
-				Data: data,
-			}
+// ensureCertificate ensures the ACM certificate of the CloudFront alias validated through Route53. It returns
+// certificateNotIssuedError until the certificate is issued.
+func (s *Service) ensureCertificate(st *reconcileState) error {
+	cloudfrontAliasDomain := key.CloudFrontAlias(st.baseDomain)
 
-			if err := s.Client.Create(ctx, cfConfig); err != nil {
-				ctrlmetrics.Errors.WithLabelValues(s.Scope.Installation(), s.Scope.AccountID(), s.Scope.ClusterName(), s.Scope.ClusterNamespace()).Inc()
-				s.Scope.Logger().Error(err, "failed to create OIDC cloudfront config map for cluster")
-				return err
-			}
-			s.Scope.Logger().Info("Created OIDC cloudfront config map in k8s")
-		} else if err != nil {
-			return err
-		}
+	// Ensure ACM certificate.
+	certificateArn, err := s.ACM.EnsureCertificate([]string{cloudfrontAliasDomain}, st.customerTags)
+	if err != nil {
+		s.Scope.Logger().Error(err, "failed to create ACM certificate")
+		return err
+	}
+
+	// wait for certificate to be issued.
+	issued, err := s.ACM.IsCertificateIssued(*certificateArn)
+	if err != nil {
+		s.Scope.Logger().Error(err, "failed to check if ACM certificate is issued")
+		return err
+	}
+
+	st.publicHostedZoneID, err = s.DNS.FindPublicHostedZone(st.baseDomain)
+	if err != nil {
+		s.Scope.Logger().Error(err, "failed to find route53 hosted zone ID")
+		return err
+	}
+
+	st.privateHostedZoneID, err = s.DNS.FindPrivateHostedZone(st.baseDomain)
+	if err != nil {
+		s.Scope.Logger().Error(err, "failed to find route53 hosted zone ID")
+		return err
+	}
 
-		// Ensure CM is up-to-date.
-		if reflect.DeepEqual(cfConfig.Data, data) {
-			s.Scope.Logger().Info("Configmap is already up to date")
-		} else {
-			s.Scope.Logger().Info("Configmap needs to be updated")
+	// Check if domain ownership is validated
+	validated, err := s.ACM.IsValidated(*certificateArn)
+	if err != nil {
+		s.Scope.Logger().Error(err, "failed to check if ACM certificate's ownership is validated")
+		return err
+	}
 
-			cfConfig.Data = data
+	if !validated {
+		// Check if DNS records are present
+		records, err := s.ACM.GetValidationCNAMEs(*certificateArn)
+		if err != nil {
+			s.Scope.Logger().Error(err, "failed to get ACM certificate's validation DNS record details")
+			return err
+		}
 
-			err = s.Client.Update(ctx, cfConfig)
+		for _, record := range records {
+			err = s.DNS.EnsureDNSRecord(st.publicHostedZoneID, record.CNAME)
 			if err != nil {
-				ctrlmetrics.Errors.WithLabelValues(s.Scope.Installation(), s.Scope.AccountID(), s.Scope.ClusterName(), s.Scope.ClusterNamespace()).Inc()
-				s.Scope.Logger().Error(err, "error updating configmap")
+				s.Scope.Logger().Error(err, "failed to create ACM certificate's validation DNS record")
 				return err
 			}
 
-			s.Scope.Logger().Info("Configmap updated successfully")
+			// ACM only validates once the records have propagated, which is just reported here.
+			_, err = dns.ChangeInSync(s.DNS, s.Scope, record.CNAME.Name, st.publicHostedZoneID)
+			if err != nil {
+				s.Scope.Logger().Error(err, "failed to check ACM certificate's validation DNS record propagation")
+				return err
+			}
 		}
+	}
 
-		cfDomain = distribution.Domain
-		cfOaiId = data["originAccessIdentityId"]
+	if !issued {
+		s.Scope.Logger().Info("ACM certificate is not issued yet")
+
+		return microerror.Mask(certificateNotIssuedError)
 	}
 
-	// restrict access only for non-China region and v18.x.x release or higher
-	if (!key.IsChina(s.Scope.Region()) && key.IsV18Release(s.Scope.Release())) || (s.Scope.MigrationNeeded() && !key.IsChina(s.Scope.Region())) {
-		uploadPolicy := func() error { return s.S3.UpdatePolicy(s.Scope.BucketName(), cfOaiId) }
-		err = backoff.Retry(uploadPolicy, b)
-		if err != nil {
-			s.Scope.Logger().Error(err, "failed to upload policy")
-			return err
-		}
+	notAfter, err := s.ACM.GetCertificateExpirationTS(*certificateArn)
+	if err != nil {
+		s.Scope.Logger().Error(err, "failed to check ACM certificate's expiration date")
+		return err
 	}
-	// restrict access only for non-China region and v18.x.x release or higher
-	if (!key.IsChina(s.Scope.Region()) && key.IsV18Release(s.Scope.Release())) || (s.Scope.MigrationNeeded() && !key.IsChina(s.Scope.Region())) {
-		err = s.S3.BlockPublicAccess(s.Scope.BucketName())
-		if err != nil {
-			s.Scope.Logger().Error(err, "failed to block public access")
+
+	ctrlmetrics.Certs.WithLabelValues(s.Scope.Installation(), s.Scope.AccountID(), s.Scope.ClusterName(), s.Scope.ClusterNamespace(), cloudfrontAliasDomain).Set(float64(notAfter.Unix()))
+	err = s.ACM.ReportCertificateHealth(*certificateArn, cloudfrontAliasDomain, func(r acm.DomainValidationRecord) (bool, error) {
+		return s.DNS.RecordExists(st.publicHostedZoneID, r.CNAME)
+	})
+	if err != nil {
+		s.Scope.Logger().Error(err, "failed to check ACM certificate's health")
+	}
+
+	st.aliases = []*string{&cloudfrontAliasDomain}
+	st.certificateARN = *certificateArn
+
+	return nil
+}
+
+// ensureCloudfrontConfig stores the distribution's details in the config map the cluster's nodes are bootstrapped
+// with.
+func (s *Service) ensureCloudfrontConfig(ctx context.Context, st *reconcileState) error {
+	data := map[string]string{
+		"arn":                    st.distribution.ARN,
+		"domain":                 st.distribution.Domain,
+		"distributionId":         st.distribution.DistributionId,
+		"originAccessIdentityId": st.distribution.OriginAccessIdentityId,
+	}
+	if len(st.aliases) > 0 && (key.IsV19Release(s.Scope.Release()) || s.Scope.PreCloudfrontAlias()) {
+		data["domainAlias"] = *st.aliases[0] //nolint:gosec
+	}
+
+	cfConfig := &v1.ConfigMap{}
+	err := s.Client.Get(ctx, types.NamespacedName{Namespace: s.Scope.ClusterNamespace(), Name: s.Scope.ConfigName()}, cfConfig)
+	if apierrors.IsNotFound(err) {
+		if err := irsaerrors.IsEmptyCloudfrontDistribution(st.distribution); err != nil {
 			return err
 		}
-	} else {
-		err = s.S3.AllowPublicAccess(s.Scope.BucketName())
-		if err != nil {
-			s.Scope.Logger().Error(err, "failed to allow public access")
-			return err
+
+		// create new OIDC Cloudfront config
+		cfConfig = &v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      s.Scope.ConfigName(),
+				Namespace: s.Scope.ClusterNamespace(),
+			},
+			Data: data,
 		}
-	}
 
-	uploadFiles := func() error {
-		domain := cfDomain
-		if len(aliases) > 0 {
-			domain = *aliases[0] //nolint:gosec
+		if err := s.Client.Create(ctx, cfConfig); err != nil {
+			return err
 		}
-		return s.S3.UploadFiles(s.Scope.Release(), domain, s.Scope.BucketName(), privateKey)
-	}
-	err = backoff.Retry(uploadFiles, b)
-	if err != nil {
-		ctrlmetrics.Errors.WithLabelValues(s.Scope.Installation(), s.Scope.AccountID(), s.Scope.ClusterName(), s.Scope.ClusterNamespace()).Inc()
-		s.Scope.Logger().Error(err, "failed to upload files")
+		s.Scope.Logger().Info("Created OIDC cloudfront config map in k8s")
+	} else if err != nil {
 		return err
 	}
 
-	createOIDCProvider := func() error {
-		var identityProviderURLs []string
-		var identityProviderURLsToDelete []string
-		s3Endpoint := fmt.Sprintf("s3.%s.%s", s.Scope.Region(), key.AWSEndpoint(s.Scope.Region()))
-		if (key.IsV18Release(s.Scope.Release()) && !key.IsChina(s.Scope.Region())) || (s.Scope.MigrationNeeded() && !key.IsChina(s.Scope.Region())) {
-			if s.Scope.KeepCloudFrontOIDCProvider() {
-				identityProviderURLs = append(identityProviderURLs, util.EnsureHTTPS(cfDomain))
-			} else {
-				identityProviderURLsToDelete = append(identityProviderURLs, util.EnsureHTTPS(cfDomain))
-			}
-		} else {
-			identityProviderURLs = append(identityProviderURLs, util.EnsureHTTPS(fmt.Sprintf("%s/%s", s3Endpoint, s.Scope.BucketName())))
-		}
+	// Ensure CM is up-to-date.
+	if reflect.DeepEqual(cfConfig.Data, data) {
+		s.Scope.Logger().Info("Configmap is already up to date")
+	} else {
+		s.Scope.Logger().Info("Configmap needs to be updated")
 
-		for _, alias := range aliases {
-			identityProviderURLs = append(identityProviderURLs, util.EnsureHTTPS(*alias))
+		cfConfig.Data = data
+
+		err = s.Client.Update(ctx, cfConfig)
+		if err != nil {
+			return err
 		}
 
-		return s.IAM.EnsureOIDCProviders(identityProviderURLs, identityProviderURLsToDelete, key.STSUrl(s.Scope.Region()), customerTags)
-	}
-	n := func(err error, d time.Duration) {
-		s.Scope.Logger().Info("level", "warning", "message", fmt.Sprintf("retrying backoff in '%s' due to error", d.String()), "stack", fmt.Sprintf("%#v", err))
-	}
-	err = backoff.RetryNotify(createOIDCProvider, b, n)
-	if err != nil {
-		ctrlmetrics.Errors.WithLabelValues(s.Scope.Installation(), s.Scope.AccountID(), s.Scope.ClusterName(), s.Scope.ClusterNamespace()).Inc()
-		s.Scope.Logger().Error(err, "failed to create OIDC provider")
-		return err
+		s.Scope.Logger().Info("Configmap updated successfully")
 	}
 
-	ctrlmetrics.Errors.WithLabelValues(s.Scope.Installation(), s.Scope.AccountID(), s.Scope.ClusterName(), s.Scope.ClusterNamespace()).Set(0)
-	s.Scope.Logger().Info("Finished reconciling on all resources.")
+	st.cfDomain = st.distribution.Domain
+	st.cfOaiId = data["originAccessIdentityId"]
+
 	return nil
 }
 
@@ -426,6 +568,24 @@ func (s *Service) Delete(ctx context.Context, awsCluster *infrastructurev1alpha3
 		return err
 	}
 
+	for _, additionalAccountIAM := range s.AdditionalAccountIAM {
+		err = additionalAccountIAM.DeleteOIDCProviders()
+		if err != nil {
+			ctrlmetrics.Errors.WithLabelValues(s.Scope.Installation(), s.Scope.AccountID(), s.Scope.ClusterName(), s.Scope.ClusterNamespace()).Inc()
+			s.Scope.Logger().Error(err, "failed to delete OIDC provider in additional account")
+			return err
+		}
+	}
+
+	for _, removedAccountIAM := range s.RemovedAccountIAM {
+		err = removedAccountIAM.DeleteOIDCProviders()
+		if err != nil {
+			ctrlmetrics.Errors.WithLabelValues(s.Scope.Installation(), s.Scope.AccountID(), s.Scope.ClusterName(), s.Scope.ClusterNamespace()).Inc()
+			s.Scope.Logger().Error(err, "failed to delete OIDC provider in removed account")
+			return err
+		}
+	}
+
 	oidcSecret := &v1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      s.Scope.SecretName(),
@@ -444,6 +604,19 @@ func (s *Service) Delete(ctx context.Context, awsCluster *infrastructurev1alpha3
 	}
 
 	if (!key.IsChina(s.Scope.Region()) && key.IsV18Release(s.Scope.Release())) || (s.Scope.MigrationNeeded() && !key.IsChina(s.Scope.Region())) {
+		baseDomain, err := key.BaseDomain(*cluster)
+		if err != nil {
+			return err
+		}
+
+		// Remove DNS records first, so nothing points at the distribution once it is gone.
+		err = s.deleteDNSRecords(baseDomain, cfConfig.Data["domain"])
+		if err != nil {
+			ctrlmetrics.Errors.WithLabelValues(s.Scope.Installation(), s.Scope.AccountID(), s.Scope.ClusterName(), s.Scope.ClusterNamespace()).Inc()
+			s.Scope.Logger().Error(err, "failed to delete DNS records")
+			return err
+		}
+
 		err = s.Cloudfront.DisableDistribution(cfDistributionId)
 		if err != nil {
 			s.Scope.Logger().Error(err, "failed to disable cloudfront distribution for cluster")
@@ -470,11 +643,6 @@ func (s *Service) Delete(ctx context.Context, awsCluster *infrastructurev1alpha3
 			return err
 		}
 
-		baseDomain, err := key.BaseDomain(*cluster)
-		if err != nil {
-			return err
-		}
-
 		cloudFrontAliasDomain := key.CloudFrontAlias(baseDomain)
 		if cloudFrontAliasDomain != "" {
 			err = s.ACM.DeleteCertificate(cloudFrontAliasDomain)
@@ -497,6 +665,8 @@ func (s *Service) Delete(ctx context.Context, awsCluster *infrastructurev1alpha3
 	}
 
 	ctrlmetrics.Errors.DeleteLabelValues(s.Scope.Installation(), s.Scope.AccountID(), s.Scope.ClusterName(), s.Scope.ClusterNamespace())
+	ctrlmetrics.DeleteCertificateMetrics(s.Scope.Installation(), s.Scope.AccountID(), s.Scope.ClusterName(), s.Scope.ClusterNamespace())
+	ctrlmetrics.DeleteDNSMetrics(s.Scope.Installation(), s.Scope.AccountID(), s.Scope.ClusterName(), s.Scope.ClusterNamespace())
 	s.Scope.Logger().Info("Finished deleting all resources.")
 
 	return nil
@@ -545,3 +715,54 @@ func (s *Service) ServiceAccountSecret(ctx context.Context) (*rsa.PrivateKey, er
 		return nil, err
 	}
 }
+
+// deleteDNSRecords removes the alias records pointing at the distribution and the validation records of the ACM
+// certificate from the public and private zones. Records changed by someone else in the meantime are kept.
+func (s *Service) deleteDNSRecords(baseDomain, distributionDomain string) error {
+	cloudFrontAliasDomain := key.CloudFrontAlias(baseDomain)
+
+	records, err := s.ACM.GetValidationRecords(cloudFrontAliasDomain)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	for _, findHostedZone := range []func(string) (string, error){s.DNS.FindPublicHostedZone, s.DNS.FindPrivateHostedZone} {
+		hostedZoneID, err := findHostedZone(baseDomain)
+		if route53.IsZoneNotFound(err) || (err == nil && hostedZoneID == "") {
+			continue
+		} else if err != nil {
+			return microerror.Mask(err)
+		}
+
+		if distributionDomain != "" {
+			err = s.DNS.DeleteDNSRecords(hostedZoneID, cloudFrontAliasDomain, distributionDomain)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+		}
+
+		for _, record := range records {
+			err = s.DNS.DeleteDNSRecords(hostedZoneID, record.CNAME.Name, record.CNAME.Value)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// preflight reports missing permissions of the cluster role up front. Failing to check them doesn't stop the
+// reconciliation, the actual calls will tell.
+func (s *Service) preflight() {
+	hostingMode := key.HostingModeS3
+	if (!key.IsChina(s.Scope.Region()) && key.IsV18Release(s.Scope.Release())) || (s.Scope.MigrationNeeded() && !key.IsChina(s.Scope.Region())) {
+		hostingMode = key.HostingModeCloudFront
+	}
+	route53 := s.Scope.DNSProvider() != dns.ProviderDNSEndpoint && s.Scope.DNSRoleARN() == s.Scope.ARN()
+
+	_, err := s.IAM.Preflight(iam.RequiredActions(hostingMode, route53, false), s.AdditionalAccountIAM...)
+	if err != nil {
+		s.Scope.Logger().Error(err, "failed to check permissions of the cluster role")
+	}
+}
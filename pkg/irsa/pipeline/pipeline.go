@@ -0,0 +1,205 @@
+// Package pipeline runs a reconciliation as a sequence of named steps. The runner takes care of what is the same
+// for every step: skipping it if its precondition doesn't hold, retrying it, timing it, and reporting failures as
+// metric, log line, event and condition.
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/giantswarm/backoff"
+	"github.com/go-logr/logr"
+	gocache "github.com/patrickmn/go-cache"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	capi "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+
+	ctrlmetrics "github.com/giantswarm/irsa-operator/pkg/metrics"
+	"github.com/giantswarm/irsa-operator/pkg/util/record"
+)
+
+const (
+	resultError   = "error"
+	resultSuccess = "success"
+	resultWait    = "wait"
+)
+
+// reportedFailureExpiry is how long a reported failure is remembered. An unchanged failure is reported again after
+// it, and the failures of deleted objects don't stay in the cache.
+const reportedFailureExpiry = time.Hour
+
+// Action performs a step. Steps share state through the variables their actions close over.
+type Action func(ctx context.Context) (Result, error)
+
+// Step is a named part of a reconciliation.
+type Step struct {
+	// Name identifies the step in logs, metrics, events and conditions, e.g. `ensure-bucket`.
+	Name string
+	// Precondition decides right before the step whether it runs, so it can depend on the outcome of earlier
+	// steps. Nil means the step always runs.
+	Precondition func() bool
+	// Retry retries a failing action with the backoff of the runner before the step fails.
+	Retry bool
+	// CountErrors passes failures of the step to the OnError hook of the runner.
+	CountErrors bool
+	Action      Action
+}
+
+// Result tells the runner how to continue after a successful step.
+type Result struct {
+	// Stop ends the reconciliation without running the remaining steps, e.g. while waiting for an object whose
+	// creation triggers the next reconciliation anyway.
+	Stop bool
+	// RequeueAfter ends the reconciliation like Stop and asks for the next one after the duration.
+	RequeueAfter time.Duration
+}
+
+// Stopped returns whether the reconciliation ended before the last step.
+func (r Result) Stopped() bool {
+	return r.Stop || r.RequeueAfter > 0
+}
+
+// Do turns a function that only fails or succeeds into an action.
+func Do(f func(ctx context.Context) error) Action {
+	return func(ctx context.Context) (Result, error) {
+		return Result{}, f(ctx)
+	}
+}
+
+// Runner runs steps and reports their outcome.
+type Runner struct {
+	// Name identifies the pipeline in metrics, e.g. `capa`.
+	Name         string
+	Installation string
+	Logger       logr.Logger
+
+	// Backoff is used for steps with Retry set.
+	Backoff backoff.Interface
+	// Condition is set on Object after every run, if Object has conditions. Empty means no condition is set.
+	Condition capi.ConditionType
+	// Object is the cluster object events are emitted on.
+	Object runtime.Object
+	// OnError is called once for the failed step of a run if the step has CountErrors set, e.g. to count errors.
+	// It may be nil.
+	OnError func(step string, err error)
+	// Cache remembers the failure reported for Object for reportedFailureExpiry, so that the event and condition are
+	// only updated when the failure changes. Nil reports every failure.
+	Cache *gocache.Cache
+}
+
+// Run runs the steps in order until one fails or stops the reconciliation. The returned result is the one of the
+// stopping step, or empty if all steps ran.
+func (r *Runner) Run(ctx context.Context, steps []Step) (Result, error) {
+	for _, step := range steps {
+		if step.Precondition != nil && !step.Precondition() {
+			continue
+		}
+
+		start := time.Now()
+		result, err := r.runStep(ctx, step)
+		duration := time.Since(start)
+
+		if err != nil {
+			ctrlmetrics.ReconcileStepDuration.WithLabelValues(r.Installation, r.Name, step.Name, resultError).Observe(duration.Seconds())
+			r.Logger.Error(err, "Reconciliation step failed", "step", step.Name, "duration", duration)
+			if r.reportFailure(step.Name, err) {
+				record.Warnf(r.Object, "ReconciliationFailed", "Step %s failed: %s", step.Name, err)
+				r.markFalse("StepFailed", capi.ConditionSeverityWarning, "Step %s failed: %s", step.Name, err)
+			}
+			if step.CountErrors && r.OnError != nil {
+				r.OnError(step.Name, err)
+			}
+
+			return Result{}, err
+		}
+
+		if result.Stopped() {
+			ctrlmetrics.ReconcileStepDuration.WithLabelValues(r.Installation, r.Name, step.Name, resultWait).Observe(duration.Seconds())
+			r.Logger.Info("Reconciliation step is waiting, stopping", "step", step.Name, "requeueAfter", result.RequeueAfter)
+			r.forgetFailure()
+			r.markFalse("Waiting", capi.ConditionSeverityInfo, "Step %s is waiting", step.Name)
+
+			return result, nil
+		}
+
+		ctrlmetrics.ReconcileStepDuration.WithLabelValues(r.Installation, r.Name, step.Name, resultSuccess).Observe(duration.Seconds())
+	}
+
+	r.forgetFailure()
+	if setter, ok := r.Object.(conditions.Setter); ok && r.Condition != "" {
+		conditions.MarkTrue(setter, r.Condition)
+	}
+
+	return Result{}, nil
+}
+
+func (r *Runner) runStep(ctx context.Context, step Step) (Result, error) {
+	if !step.Retry {
+		return step.Action(ctx)
+	}
+
+	var result Result
+	action := func() error {
+		var err error
+		result, err = step.Action(ctx)
+		return err
+	}
+	notify := func(err error, d time.Duration) {
+		r.Logger.Info("Reconciliation step failed, retrying", "step", step.Name, "retryIn", d.String(), "error", err.Error())
+	}
+	err := backoff.RetryNotify(action, r.Backoff, notify)
+
+	return result, err
+}
+
+func (r *Runner) markFalse(reason string, severity capi.ConditionSeverity, messageFormat string, args ...interface{}) {
+	if setter, ok := r.Object.(conditions.Setter); ok && r.Condition != "" {
+		conditions.MarkFalse(setter, r.Condition, reason, severity, messageFormat, args...)
+	}
+}
+
+// reportFailure returns whether the failure of the step differs from the one reported in the previous run, and
+// remembers it for the next run.
+func (r *Runner) reportFailure(step string, err error) bool {
+	cacheKey, ok := r.failureCacheKey()
+	if !ok {
+		return true
+	}
+
+	// The message of AWS errors contains the ID of the failed request, so they are compared by their code.
+	failure := fmt.Sprintf("%s: %s", step, err)
+	var aerr awserr.Error
+	if errors.As(err, &aerr) {
+		failure = fmt.Sprintf("%s: %s", step, aerr.Code())
+	}
+
+	if cachedValue, ok := r.Cache.Get(cacheKey); ok && cachedValue.(string) == failure {
+		return false
+	}
+	r.Cache.Set(cacheKey, failure, reportedFailureExpiry)
+
+	return true
+}
+
+// forgetFailure drops the reported failure, so that it is reported again if it recurs after the run got further.
+func (r *Runner) forgetFailure() {
+	if cacheKey, ok := r.failureCacheKey(); ok {
+		r.Cache.Delete(cacheKey)
+	}
+}
+
+func (r *Runner) failureCacheKey() (string, bool) {
+	if r.Cache == nil {
+		return "", false
+	}
+	accessor, err := meta.Accessor(r.Object)
+	if err != nil {
+		return "", false
+	}
+
+	return fmt.Sprintf("pipeline/name=%q/object=%s/%s/reported-failure", r.Name, accessor.GetNamespace(), accessor.GetName()), true
+}
@@ -0,0 +1,248 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/giantswarm/backoff"
+	"github.com/go-logr/logr"
+	gocache "github.com/patrickmn/go-cache"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8srecord "k8s.io/client-go/tools/record"
+	capa "sigs.k8s.io/cluster-api-provider-aws/v2/api/v1beta2"
+	capi "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+
+	"github.com/giantswarm/irsa-operator/pkg/util/record"
+)
+
+const testCondition capi.ConditionType = "Test"
+
+func Test_Runner_Run(t *testing.T) {
+	errTest := errors.New("test")
+
+	tests := []struct {
+		name           string
+		steps          func(ran *[]string) []Step
+		wantRan        []string
+		wantResult     Result
+		wantErr        bool
+		wantFailedStep string
+		wantReconciled bool
+	}{
+		{
+			name: "all steps run",
+			steps: func(ran *[]string) []Step {
+				return []Step{recordingStep(ran, "a", nil), recordingStep(ran, "b", nil)}
+			},
+			wantRan:        []string{"a", "b"},
+			wantReconciled: true,
+		},
+		{
+			name: "precondition skips step",
+			steps: func(ran *[]string) []Step {
+				skipped := recordingStep(ran, "b", nil)
+				skipped.Precondition = func() bool { return false }
+				return []Step{recordingStep(ran, "a", nil), skipped, recordingStep(ran, "c", nil)}
+			},
+			wantRan:        []string{"a", "c"},
+			wantReconciled: true,
+		},
+		{
+			name: "requeue stops",
+			steps: func(ran *[]string) []Step {
+				waiting := recordingStep(ran, "b", nil)
+				waiting.Action = func(ctx context.Context) (Result, error) {
+					*ran = append(*ran, "b")
+					return Result{RequeueAfter: time.Minute}, nil
+				}
+				return []Step{recordingStep(ran, "a", nil), waiting, recordingStep(ran, "c", nil)}
+			},
+			wantRan:    []string{"a", "b"},
+			wantResult: Result{RequeueAfter: time.Minute},
+		},
+		{
+			name: "error stops",
+			steps: func(ran *[]string) []Step {
+				failing := recordingStep(ran, "b", errTest)
+				failing.CountErrors = true
+				return []Step{recordingStep(ran, "a", nil), failing, recordingStep(ran, "c", nil)}
+			},
+			wantRan:        []string{"a", "b"},
+			wantErr:        true,
+			wantFailedStep: "b",
+		},
+		{
+			name: "uncounted error",
+			steps: func(ran *[]string) []Step {
+				return []Step{recordingStep(ran, "a", errTest), recordingStep(ran, "b", nil)}
+			},
+			wantRan: []string{"a"},
+			wantErr: true,
+		},
+		{
+			name: "retry",
+			steps: func(ran *[]string) []Step {
+				failures := 2
+				flaky := Step{
+					Name:  "a",
+					Retry: true,
+					Action: Do(func(ctx context.Context) error {
+						*ran = append(*ran, "a")
+						if failures > 0 {
+							failures--
+							return errTest
+						}
+						return nil
+					}),
+				}
+				return []Step{flaky}
+			},
+			wantRan:        []string{"a", "a", "a"},
+			wantReconciled: true,
+		},
+		{
+			name: "retry gives up",
+			steps: func(ran *[]string) []Step {
+				failing := recordingStep(ran, "a", errTest)
+				failing.Retry = true
+				failing.CountErrors = true
+				return []Step{failing}
+			},
+			wantRan:        []string{"a", "a", "a"},
+			wantErr:        true,
+			wantFailedStep: "a",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cluster := &capa.AWSCluster{}
+			var failedStep string
+			runner := &Runner{
+				Name:      "test",
+				Logger:    logr.Discard(),
+				Backoff:   backoff.NewMaxRetries(3, time.Millisecond),
+				Condition: testCondition,
+				Object:    cluster,
+				OnError:   func(step string, err error) { failedStep = step },
+			}
+
+			var ran []string
+			result, err := runner.Run(context.Background(), tc.steps(&ran))
+
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Run() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if result != tc.wantResult {
+				t.Errorf("Run() = %+v, want %+v", result, tc.wantResult)
+			}
+			if !reflect.DeepEqual(ran, tc.wantRan) {
+				t.Errorf("Run() ran %v, want %v", ran, tc.wantRan)
+			}
+			if failedStep != tc.wantFailedStep {
+				t.Errorf("Run() reported failed step %q, want %q", failedStep, tc.wantFailedStep)
+			}
+			if got := conditions.IsTrue(cluster, testCondition); got != tc.wantReconciled {
+				t.Errorf("Run() set condition true = %v, want %v", got, tc.wantReconciled)
+			}
+		})
+	}
+}
+
+func Test_Runner_Run_reportsChangedFailures(t *testing.T) {
+	recorder := k8srecord.NewFakeRecorder(100)
+	record.InitFromRecorder(recorder)
+
+	accessDenied := func(requestID string) error {
+		return awserr.NewRequestFailure(awserr.New("AccessDenied", "not allowed", nil), 403, requestID)
+	}
+
+	// The steps run one after the other against the same cache and cluster, like consecutive reconciliations.
+	steps := []struct {
+		name        string
+		err         error
+		wantEvent   bool
+		wantMessage string
+	}{
+		{
+			name:        "failure",
+			err:         errors.New("test"),
+			wantEvent:   true,
+			wantMessage: "Step a failed: test",
+		},
+		{
+			name:        "same failure",
+			err:         errors.New("test"),
+			wantMessage: "Step a failed: test",
+		},
+		{
+			name:        "AWS failure",
+			err:         accessDenied("request-1"),
+			wantEvent:   true,
+			wantMessage: "Step a failed: " + accessDenied("request-1").Error(),
+		},
+		{
+			name:        "same AWS failure of another request",
+			err:         accessDenied("request-2"),
+			wantMessage: "Step a failed: " + accessDenied("request-1").Error(),
+		},
+		{
+			name: "success",
+		},
+		{
+			name:        "failure after success",
+			err:         errors.New("test"),
+			wantEvent:   true,
+			wantMessage: "Step a failed: test",
+		},
+	}
+
+	cluster := &capa.AWSCluster{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "org-test"}}
+	runner := &Runner{
+		Name:      "test",
+		Logger:    logr.Discard(),
+		Condition: testCondition,
+		Object:    cluster,
+		Cache:     gocache.New(gocache.NoExpiration, gocache.NoExpiration),
+	}
+
+	for _, step := range steps {
+		_, err := runner.Run(context.Background(), []Step{{Name: "a", Action: Do(func(ctx context.Context) error { return step.err })}})
+		if !errors.Is(err, step.err) {
+			t.Fatalf("%s: Run() error = %v, want %v", step.name, err, step.err)
+		}
+
+		var gotEvent bool
+		for len(recorder.Events) > 0 {
+			<-recorder.Events
+			gotEvent = true
+		}
+		if gotEvent != step.wantEvent {
+			t.Errorf("%s: Run() emitted event = %v, want %v", step.name, gotEvent, step.wantEvent)
+		}
+		if got := conditions.GetMessage(cluster, testCondition); got != step.wantMessage {
+			t.Errorf("%s: Run() condition message = %q, want %q", step.name, got, step.wantMessage)
+		}
+	}
+
+	cacheKey, _ := runner.failureCacheKey()
+	if _, expiration, ok := runner.Cache.GetWithExpiration(cacheKey); !ok || expiration.IsZero() {
+		t.Errorf("Run() remembered the failure = %v with expiration %v, want it to expire", ok, expiration)
+	}
+}
+
+// recordingStep returns a step that appends its name to ran and fails with err.
+func recordingStep(ran *[]string, name string, err error) Step {
+	return Step{
+		Name: name,
+		Action: Do(func(ctx context.Context) error {
+			*ran = append(*ran, name)
+			return err
+		}),
+	}
+}
@@ -6,6 +6,30 @@ var unexpectedApiEndpointError = &microerror.Error{
 	Kind: "unexpectedApiEndpoint",
 }
 
+var invalidAdditionalAccountRoleARNError = &microerror.Error{
+	Kind: "invalidAdditionalAccountRoleARN",
+}
+
+var invalidCertificateKeyAlgorithmError = &microerror.Error{
+	Kind: "invalidCertificateKeyAlgorithm",
+}
+
+var invalidDNSRoleARNError = &microerror.Error{
+	Kind: "invalidDNSRoleARN",
+}
+
+var invalidPodIdentityAssociationsError = &microerror.Error{
+	Kind: "invalidPodIdentityAssociations",
+}
+
+var invalidThumbprintModeError = &microerror.Error{
+	Kind: "invalidThumbprintMode",
+}
+
+var invalidUnknownAudiencesPolicyError = &microerror.Error{
+	Kind: "invalidUnknownAudiencesPolicy",
+}
+
 var missingApiEndpointError = &microerror.Error{
 	Kind: "missingApiEndpoint",
 }
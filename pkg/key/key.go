@@ -1,9 +1,14 @@
 package key
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"strings"
 
+	"github.com/aws/aws-sdk-go/aws/arn"
 	"github.com/blang/semver"
 	"github.com/giantswarm/microerror"
 	capi "sigs.k8s.io/cluster-api/api/v1beta1"
@@ -29,24 +34,156 @@ const (
 	// The CloudFront distribution is of course not deleted, since it also hosts the OIDC configuration for the
 	// predictable `irsa.<basedomain>` OIDC provider (which customers should use).
 	KeepCloudFrontOIDCProviderAnnotation = "alpha.aws.giantswarm.io/irsa-keep-cloudfront-oidc-provider"
+	// AWS region in which a replica of the OIDC S3 bucket is kept. When set, the bucket content is replicated
+	// to that region and CloudFront fails over to the replica if the primary bucket is unavailable.
+	IRSAReplicaRegionAnnotation = "alpha.aws.giantswarm.io/irsa-replica-region"
+	// Comma-separated list of domains served by the CloudFront distribution in addition to `irsa.<basedomain>`,
+	// e.g. during a base domain migration. All of them are added to the ACM certificate as subject alternative names.
+	IRSAExtraAliasesAnnotation = "alpha.aws.giantswarm.io/irsa-extra-aliases"
+	// Comma-separated list of the extra aliases the operator created DNS records for. Set by the operator, to delete
+	// the records of aliases that are removed from IRSAExtraAliasesAnnotation.
+	IRSAManagedAliasesAnnotation = "alpha.aws.giantswarm.io/irsa-managed-aliases"
+	// Key algorithm of the ACM certificate, one of `RSA_2048` (default), `EC_prime256v1` or `EC_secp384r1`. Changing
+	// it requests a new certificate, the old one is deleted once the distribution switched over.
+	IRSACertificateKeyAlgorithmAnnotation = "alpha.aws.giantswarm.io/irsa-certificate-key-algorithm"
+	// Comma-separated list of the customer tag keys the operator set on the ACM certificate. Set by the operator, to
+	// remove the tags that are dropped from the additional tags of the cluster.
+	IRSACertificateCustomerTagsAnnotation = "alpha.aws.giantswarm.io/irsa-certificate-customer-tags"
+	// Name of a `kubernetes.io/tls` Secret in the cluster namespace (e.g. issued by cert-manager) holding the
+	// certificate for the CloudFront aliases. When set, the certificate is imported into ACM instead of requesting
+	// one validated through Route53. Only supported for CAPA clusters.
+	IRSACertificateSecretAnnotation = "alpha.aws.giantswarm.io/irsa-certificate-secret"
+	// ARN of the IAM role used for Route53, e.g. when the base domain lives in a central DNS account. Overrides the
+	// `--dns-role-arn` flag of the operator.
+	IRSADNSRoleARNAnnotation = "alpha.aws.giantswarm.io/irsa-dns-role-arn"
+	// Comma-separated list of audiences (client IDs) added to the OIDC providers next to the STS one, for other
+	// relying parties using the same issuer (e.g. Vault).
+	IRSAAdditionalAudiencesAnnotation = "alpha.aws.giantswarm.io/irsa-additional-audiences"
+	// What to do with audiences found on the OIDC providers that are neither the STS one nor additional audiences,
+	// one of `remove` (default), `keep` or `warn` (keep and emit a warning event).
+	IRSAUnknownAudiencesAnnotation = "alpha.aws.giantswarm.io/irsa-unknown-audiences"
+	// Which certificates of the issuer's verified TLS chain are set as thumbprints on the OIDC providers, one of
+	// `root` (default), `chain` (root and intermediates) or `unmanaged` (none, AWS uses its own trust store).
+	IRSAThumbprintModeAnnotation = "alpha.aws.giantswarm.io/irsa-thumbprint-mode"
+	// Comma-separated list of IAM role ARNs in other accounts in which the cluster's OIDC providers are registered
+	// as well, so that roles in these accounts can trust the cluster's service accounts.
+	IRSAAdditionalAccountRolesAnnotation = "alpha.aws.giantswarm.io/irsa-additional-account-roles"
+	// Comma-separated list of the additional account roles the cluster's OIDC providers are registered with. Set by
+	// the operator, to delete the providers from accounts that are removed from IRSAAdditionalAccountRolesAnnotation.
+	IRSARegisteredAccountRolesAnnotation = "alpha.aws.giantswarm.io/irsa-registered-account-roles"
+	// JSON list of EKS Pod Identity associations of the cluster, e.g.
+	// `[{"namespace": "kube-system", "serviceAccount": "ebs-csi-controller-sa", "roleArn": "arn:aws:iam::123456789012:role/ebs-csi"}]`.
+	// Only supported for EKS clusters. Associations are only managed while the annotation is set, so `[]` deletes
+	// the ones created by the operator.
+	IRSAPodIdentityAssociationsAnnotation = "alpha.aws.giantswarm.io/irsa-pod-identity-associations"
+	// Set by the operator while it manages the pod identity associations of the cluster, to delete the associations it
+	// created once IRSAPodIdentityAssociationsAnnotation is removed.
+	IRSAManagedPodIdentityAssociationsAnnotation = "alpha.aws.giantswarm.io/irsa-managed-pod-identity-associations"
+
+	// EKS add-on running the agent that hands out the credentials of pod identity associations.
+	PodIdentityAgentAddonName = "eks-pod-identity-agent"
+
+	DefaultCertificateKeyAlgorithm = "RSA_2048"
+
+	ThumbprintModeRoot      = "root"
+	ThumbprintModeChain     = "chain"
+	ThumbprintModeUnmanaged = "unmanaged"
+
+	UnknownAudiencesRemove = "remove"
+	UnknownAudiencesKeep   = "keep"
+	UnknownAudiencesWarn   = "warn"
+
+	// How the OIDC documents are served, which determines the AWS actions needed by the cluster role.
+	HostingModeCloudFront = "cloudfront"
+	HostingModeS3         = "s3"
+	HostingModeEKS        = "eks"
+
+	// ACM starts renewing 60 days before expiry, so less than 30 days left means the renewal is stuck.
+	CertificateExpiryWarningDays = 30
 
 	S3TagCloudProvider = "kubernetes.io/cluster/%s"
 	S3TagCluster       = "giantswarm.io/cluster"
 	S3TagInstallation  = "giantswarm.io/installation"
 	S3TagOrganization  = "giantswarm.io/organization"
 
+	// Marks the resources created by the operator, where the installation and cluster tags are set by others as well.
+	TagManagedBy         = "giantswarm.io/managed-by"
+	TagManagedByOperator = "irsa-operator"
+
+	bucketNameMaxLength = 63
+	roleNameMaxLength   = 64
+
+	ClusterValuesConfigMapSuffix  = "-cluster-values"
+	ServiceAccountKeySecretSuffix = "-sa"
+
 	CustomerTagLabel = "tag.provider.giantswarm.io/"
 	ReleaseLabel     = "release.giantswarm.io/version"
 )
 
+// PodIdentityAssociation grants the pods of a service account the credentials of an IAM role through EKS Pod
+// Identity.
+type PodIdentityAssociation struct {
+	Namespace      string `json:"namespace"`
+	ServiceAccount string `json:"serviceAccount"`
+	RoleARN        string `json:"roleArn"`
+}
+
+// IRSAPermissionsCondition reports whether the cluster role is allowed to perform all AWS actions the operator needs.
+const IRSAPermissionsCondition capi.ConditionType = "IRSAPermissionsReady"
+
+// IRSAReconciledCondition reports whether the last reconciliation of a CAPA cluster ran through all steps.
+const IRSAReconciledCondition capi.ConditionType = "IRSAReconciled"
+
+// IRSAOIDCProviderCondition reports whether the OIDC providers and pod identity associations of an EKS cluster are
+// reconciled.
+const IRSAOIDCProviderCondition capi.ConditionType = "IRSAOIDCProviderReady"
+
 func BucketName(accountID, clusterName string) string {
 	return fmt.Sprintf("%s-g8s-%s-oidc-pod-identity", accountID, clusterName)
 }
 
+// ReplicaBucketName returns the name of the replica of the bucket. Names exceeding the length S3 allows are
+// shortened and kept unique with a hash of the bucket name.
+func ReplicaBucketName(bucketName string) string {
+	return shortenName(fmt.Sprintf("%s-replica", bucketName), bucketName, "-replica", bucketNameMaxLength)
+}
+
+// ReplicationRoleName returns the name of the role S3 replicates the bucket with. Names exceeding the length IAM
+// allows are shortened and kept unique with a hash of installation and cluster name.
+func ReplicationRoleName(installation, clusterName string) string {
+	prefix := fmt.Sprintf("%s-%s", installation, clusterName)
+	return shortenName(fmt.Sprintf("%s-irsa-s3-replication", prefix), prefix, "-irsa-s3-replication", roleNameMaxLength)
+}
+
+// shortenName returns name if it fits into maxLength, otherwise prefix cut to length followed by a hash of prefix
+// and the suffix.
+func shortenName(name, prefix, suffix string, maxLength int) string {
+	if len(name) <= maxLength {
+		return name
+	}
+
+	sum := sha256.Sum256([]byte(prefix))
+	suffix = "-" + hex.EncodeToString(sum[:])[:8] + suffix
+
+	return strings.TrimRight(prefix[:maxLength-len(suffix)], "-.") + suffix
+}
+
 func ConfigName(clusterName string) string {
 	return fmt.Sprintf("%s-irsa-cloudfront", clusterName)
 }
 
+// ClusterValuesConfigMapName returns the name of the ConfigMap created by cluster-apps-operator, which holds the
+// base domain of the cluster.
+func ClusterValuesConfigMapName(clusterName string) string {
+	return clusterName + ClusterValuesConfigMapSuffix
+}
+
+// ServiceAccountKeySecretName returns the name of the Secret holding the service account signing key, which is
+// created by CAPI/kubeadm.
+func ServiceAccountKeySecretName(clusterName string) string {
+	return clusterName + ServiceAccountKeySecretSuffix
+}
+
 func SecretName(clusterName string) string {
 	return fmt.Sprintf("%s-service-account-v2", clusterName)
 }
@@ -125,6 +262,223 @@ func CloudFrontAlias(baseDomain string) string {
 	return fmt.Sprintf("irsa.%s", baseDomain)
 }
 
+// ExtraAliases parses the value of the extra aliases annotation. Duplicates and the default alias are dropped.
+func ExtraAliases(annotation, baseDomain string) []string {
+	aliases := make([]string, 0)
+	seen := map[string]bool{CloudFrontAlias(baseDomain): true}
+	for _, alias := range strings.Split(annotation, ",") {
+		alias = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(alias)), ".")
+		if alias == "" || seen[alias] {
+			continue
+		}
+		seen[alias] = true
+		aliases = append(aliases, alias)
+	}
+
+	return aliases
+}
+
+// RemovedAliases returns the aliases of the managed aliases annotation that are no extra aliases anymore.
+func RemovedAliases(annotation, baseDomain string, extraAliases []string) []string {
+	current := map[string]bool{}
+	for _, alias := range extraAliases {
+		current[alias] = true
+	}
+
+	removed := make([]string, 0)
+	for _, alias := range ExtraAliases(annotation, baseDomain) {
+		if !current[alias] {
+			removed = append(removed, alias)
+		}
+	}
+
+	return removed
+}
+
+// AdditionalAudiences parses the value of the additional audiences annotation. Audiences are case-sensitive, only
+// whitespace and duplicates are dropped.
+func AdditionalAudiences(annotation string) []string {
+	audiences := make([]string, 0)
+	seen := map[string]bool{}
+	for _, audience := range strings.Split(annotation, ",") {
+		audience = strings.TrimSpace(audience)
+		if audience == "" || seen[audience] {
+			continue
+		}
+		seen[audience] = true
+		audiences = append(audiences, audience)
+	}
+
+	return audiences
+}
+
+// CertificateCustomerTagKeys parses the value of the certificate customer tags annotation.
+func CertificateCustomerTagKeys(annotation string) []string {
+	keys := make([]string, 0)
+	for _, k := range strings.Split(annotation, ",") {
+		k = strings.TrimSpace(k)
+		if k == "" {
+			continue
+		}
+		keys = append(keys, k)
+	}
+
+	return keys
+}
+
+// UnknownAudiencesPolicy validates the value of the unknown audiences annotation and falls back to removing them if
+// it is not set.
+func UnknownAudiencesPolicy(annotation string) (string, error) {
+	switch annotation {
+	case "":
+		return UnknownAudiencesRemove, nil
+	case UnknownAudiencesRemove, UnknownAudiencesKeep, UnknownAudiencesWarn:
+		return annotation, nil
+	}
+
+	return "", microerror.Maskf(invalidUnknownAudiencesPolicyError, "invalid value %q in annotation %q, only `remove`, `keep` and `warn` are allowed", annotation, IRSAUnknownAudiencesAnnotation)
+}
+
+// ThumbprintMode validates the value of the thumbprint mode annotation and falls back to the root certificate if it
+// is not set.
+func ThumbprintMode(annotation string) (string, error) {
+	switch annotation {
+	case "":
+		return ThumbprintModeRoot, nil
+	case ThumbprintModeRoot, ThumbprintModeChain, ThumbprintModeUnmanaged:
+		return annotation, nil
+	}
+
+	return "", microerror.Maskf(invalidThumbprintModeError, "invalid value %q in annotation %q, only `root`, `chain` and `unmanaged` are allowed", annotation, IRSAThumbprintModeAnnotation)
+}
+
+// CertificateKeyAlgorithm validates the value of the certificate key algorithm annotation and falls back to the
+// default if it is not set.
+func CertificateKeyAlgorithm(annotation string) (string, error) {
+	switch annotation {
+	case "":
+		return DefaultCertificateKeyAlgorithm, nil
+	case "RSA_2048", "EC_prime256v1", "EC_secp384r1":
+		return annotation, nil
+	}
+
+	return "", microerror.Maskf(invalidCertificateKeyAlgorithmError, "invalid value %q in annotation %q, only `RSA_2048`, `EC_prime256v1` and `EC_secp384r1` are allowed", annotation, IRSACertificateKeyAlgorithmAnnotation)
+}
+
+// DNSRoleARN returns the role ARN to use for Route53 from the DNS role ARN annotation, falling back to the given
+// default. An empty result means that the cluster's role is used.
+func DNSRoleARN(annotation, defaultARN string) (string, error) {
+	roleARN := strings.TrimSpace(annotation)
+	if roleARN == "" {
+		return defaultARN, nil
+	}
+
+	parsed, err := arn.Parse(roleARN)
+	if err != nil || parsed.Service != "iam" || !strings.HasPrefix(parsed.Resource, "role/") {
+		return "", microerror.Maskf(invalidDNSRoleARNError, "invalid value %q in annotation %q, expected an IAM role ARN", annotation, IRSADNSRoleARNAnnotation)
+	}
+
+	return roleARN, nil
+}
+
+// AdditionalAccountRoleARNs parses the value of the additional account roles annotation. Each role has to be in a
+// different account than the cluster and the other roles, since an account has only one provider per issuer.
+func AdditionalAccountRoleARNs(annotation, accountID string) ([]string, error) {
+	roleARNs := make([]string, 0)
+	accounts := map[string]bool{accountID: true}
+	for _, roleARN := range strings.Split(annotation, ",") {
+		roleARN = strings.TrimSpace(roleARN)
+		if roleARN == "" {
+			continue
+		}
+
+		parsed, err := arn.Parse(roleARN)
+		if err != nil || parsed.Service != "iam" || !strings.HasPrefix(parsed.Resource, "role/") {
+			return nil, microerror.Maskf(invalidAdditionalAccountRoleARNError, "invalid value %q in annotation %q, expected an IAM role ARN", roleARN, IRSAAdditionalAccountRolesAnnotation)
+		}
+		if accounts[parsed.AccountID] {
+			return nil, microerror.Maskf(invalidAdditionalAccountRoleARNError, "role %q in annotation %q is in the cluster's account or in the account of another role", roleARN, IRSAAdditionalAccountRolesAnnotation)
+		}
+		accounts[parsed.AccountID] = true
+
+		roleARNs = append(roleARNs, roleARN)
+	}
+
+	return roleARNs, nil
+}
+
+// RemovedAccountRoleARNs returns the roles of the registered account roles annotation whose accounts are neither the
+// cluster's account nor the account of one of the additional account roles anymore. Invalid values are skipped.
+func RemovedAccountRoleARNs(annotation, accountID string, additionalAccountRoleARNs []string) []string {
+	accounts := map[string]bool{accountID: true}
+	for _, roleARN := range additionalAccountRoleARNs {
+		if parsed, err := arn.Parse(roleARN); err == nil {
+			accounts[parsed.AccountID] = true
+		}
+	}
+
+	roleARNs := make([]string, 0)
+	for _, roleARN := range strings.Split(annotation, ",") {
+		roleARN = strings.TrimSpace(roleARN)
+		parsed, err := arn.Parse(roleARN)
+		if err != nil || accounts[parsed.AccountID] {
+			continue
+		}
+		accounts[parsed.AccountID] = true
+
+		roleARNs = append(roleARNs, roleARN)
+	}
+
+	return roleARNs
+}
+
+// PodIdentityAssociations parses the value of the pod identity associations annotation. It returns nil if the
+// annotation is not set, so that callers can tell it apart from an empty list.
+func PodIdentityAssociations(annotation string) ([]PodIdentityAssociation, error) {
+	if strings.TrimSpace(annotation) == "" {
+		return nil, nil
+	}
+
+	associations := make([]PodIdentityAssociation, 0)
+	decoder := json.NewDecoder(bytes.NewBufferString(annotation))
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&associations)
+	if err != nil {
+		return nil, microerror.Maskf(invalidPodIdentityAssociationsError, "invalid value in annotation %q: %s", IRSAPodIdentityAssociationsAnnotation, err)
+	}
+
+	serviceAccounts := map[string]bool{}
+	for _, association := range associations {
+		if association.Namespace == "" || association.ServiceAccount == "" {
+			return nil, microerror.Maskf(invalidPodIdentityAssociationsError, "association in annotation %q is missing the namespace or service account", IRSAPodIdentityAssociationsAnnotation)
+		}
+
+		parsed, err := arn.Parse(association.RoleARN)
+		if err != nil || parsed.Service != "iam" || !strings.HasPrefix(parsed.Resource, "role/") {
+			return nil, microerror.Maskf(invalidPodIdentityAssociationsError, "invalid role %q in annotation %q, expected an IAM role ARN", association.RoleARN, IRSAPodIdentityAssociationsAnnotation)
+		}
+
+		// EKS allows only one association per service account.
+		serviceAccount := association.Namespace + "/" + association.ServiceAccount
+		if serviceAccounts[serviceAccount] {
+			return nil, microerror.Maskf(invalidPodIdentityAssociationsError, "service account %q is associated more than once in annotation %q", serviceAccount, IRSAPodIdentityAssociationsAnnotation)
+		}
+		serviceAccounts[serviceAccount] = true
+	}
+
+	return associations, nil
+}
+
+// ParentDomain returns the domain without its first label, or an empty string for a single label.
+func ParentDomain(domain string) string {
+	_, parent, found := strings.Cut(strings.TrimSuffix(domain, "."), ".")
+	if !found {
+		return ""
+	}
+
+	return parent
+}
+
 func BaseDomain(cluster capi.Cluster) (string, error) {
 	apiEndpoint := cluster.Spec.ControlPlaneEndpoint.Host
 	if apiEndpoint == "" {
@@ -1,6 +1,7 @@
 package key
 
 import (
+	"reflect"
 	"testing"
 
 	infrastructurev1alpha3 "github.com/giantswarm/apiextensions/v6/pkg/apis/infrastructure/v1alpha3"
@@ -116,3 +117,341 @@ func TestKeepOnDeletion(t *testing.T) {
 		})
 	}
 }
+
+func TestExtraAliases(t *testing.T) {
+	tests := []struct {
+		name       string
+		annotation string
+		want       []string
+	}{
+		{
+			name:       "Empty annotation",
+			annotation: "",
+			want:       []string{},
+		},
+		{
+			name:       "Single alias",
+			annotation: "irsa.new.example.com",
+			want:       []string{"irsa.new.example.com"},
+		},
+		{
+			name:       "Whitespace, case, trailing dots and duplicates",
+			annotation: " irsa.new.example.com., IRSA.vanity.io ,,irsa.new.example.com",
+			want:       []string{"irsa.new.example.com", "irsa.vanity.io"},
+		},
+		{
+			name:       "Default alias is dropped",
+			annotation: "irsa.example.com,irsa.new.example.com",
+			want:       []string{"irsa.new.example.com"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ExtraAliases(tt.annotation, "example.com")
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ExtraAliases() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParentDomain(t *testing.T) {
+	tests := []struct {
+		domain string
+		want   string
+	}{
+		{domain: "irsa.example.com", want: "example.com"},
+		{domain: "irsa.example.com.", want: "example.com"},
+		{domain: "com", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.domain, func(t *testing.T) {
+			if got := ParentDomain(tt.domain); got != tt.want {
+				t.Errorf("ParentDomain() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReplicaBucketName(t *testing.T) {
+	tests := []struct {
+		name       string
+		bucketName string
+		want       string
+	}{
+		{
+			name:       "short",
+			bucketName: BucketName("123456789012", "test"),
+			want:       "123456789012-g8s-test-oidc-pod-identity-replica",
+		},
+		{
+			name:       "longest unchanged",
+			bucketName: BucketName("123456789012", "abcdefghijklmnopqrst"),
+			want:       "123456789012-g8s-abcdefghijklmnopqrst-oidc-pod-identity-replica",
+		},
+		{
+			name:       "shortened",
+			bucketName: BucketName("123456789012", "abcdefghijklmnopqrstu"),
+			want:       "123456789012-g8s-abcdefghijklmnopqrstu-oidc-po-9316b8cf-replica",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ReplicaBucketName(tt.bucketName)
+			if got != tt.want {
+				t.Errorf("ReplicaBucketName() got = %v, want %v", got, tt.want)
+			}
+			if len(got) > 63 {
+				t.Errorf("ReplicaBucketName() got %d characters, S3 allows at most 63", len(got))
+			}
+		})
+	}
+
+	if ReplicaBucketName(BucketName("123456789012", "abcdefghijklmnopqrstu")) == ReplicaBucketName(BucketName("123456789012", "abcdefghijklmnopqrstv")) {
+		t.Errorf("ReplicaBucketName() is the same for different buckets")
+	}
+}
+
+func TestCertificateKeyAlgorithm(t *testing.T) {
+	tests := []struct {
+		annotation string
+		want       string
+		wantErr    bool
+	}{
+		{annotation: "", want: "RSA_2048"},
+		{annotation: "EC_prime256v1", want: "EC_prime256v1"},
+		{annotation: "EC_secp384r1", want: "EC_secp384r1"},
+		{annotation: "RSA_4096", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.annotation, func(t *testing.T) {
+			got, err := CertificateKeyAlgorithm(tt.annotation)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CertificateKeyAlgorithm() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("CertificateKeyAlgorithm() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDNSRoleARN(t *testing.T) {
+	tests := []struct {
+		name       string
+		annotation string
+		defaultARN string
+		want       string
+		wantErr    bool
+	}{
+		{name: "not set", annotation: "", defaultARN: "", want: ""},
+		{name: "default", annotation: "", defaultARN: "arn:aws:iam::123456789012:role/dns", want: "arn:aws:iam::123456789012:role/dns"},
+		{name: "annotation overrides default", annotation: "arn:aws:iam::210987654321:role/dns", defaultARN: "arn:aws:iam::123456789012:role/dns", want: "arn:aws:iam::210987654321:role/dns"},
+		{name: "no ARN", annotation: "dns", wantErr: true},
+		{name: "no role", annotation: "arn:aws:iam::210987654321:user/dns", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DNSRoleARN(tt.annotation, tt.defaultARN)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("DNSRoleARN() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("DNSRoleARN() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAdditionalAccountRoleARNs(t *testing.T) {
+	tests := []struct {
+		name       string
+		annotation string
+		want       []string
+		wantErr    bool
+	}{
+		{name: "not set", annotation: "", want: []string{}},
+		{
+			name:       "roles in other accounts",
+			annotation: " arn:aws:iam::210987654321:role/irsa, ,arn:aws:iam::111111111111:role/irsa",
+			want:       []string{"arn:aws:iam::210987654321:role/irsa", "arn:aws:iam::111111111111:role/irsa"},
+		},
+		{name: "no ARN", annotation: "irsa", wantErr: true},
+		{name: "no role", annotation: "arn:aws:iam::210987654321:user/irsa", wantErr: true},
+		{name: "cluster account", annotation: "arn:aws:iam::123456789012:role/irsa", wantErr: true},
+		{name: "same account twice", annotation: "arn:aws:iam::210987654321:role/a,arn:aws:iam::210987654321:role/b", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := AdditionalAccountRoleARNs(tt.annotation, "123456789012")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("AdditionalAccountRoleARNs() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("AdditionalAccountRoleARNs() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRemovedAliases(t *testing.T) {
+	tests := []struct {
+		name       string
+		annotation string
+		want       []string
+	}{
+		{name: "not set", annotation: "", want: []string{}},
+		{name: "still extra alias", annotation: "irsa.old.example.com", want: []string{}},
+		{name: "removed", annotation: "irsa.old.example.com, irsa.older.example.com", want: []string{"irsa.older.example.com"}},
+		{name: "default alias", annotation: "irsa.example.com", want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := RemovedAliases(tt.annotation, "example.com", []string{"irsa.old.example.com"})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("RemovedAliases() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRemovedAccountRoleARNs(t *testing.T) {
+	additionalAccountRoleARNs := []string{"arn:aws:iam::210987654321:role/irsa"}
+
+	tests := []struct {
+		name       string
+		annotation string
+		want       []string
+	}{
+		{name: "not set", annotation: "", want: []string{}},
+		{name: "still registered", annotation: "arn:aws:iam::210987654321:role/irsa", want: []string{}},
+		{name: "other role in registered account", annotation: "arn:aws:iam::210987654321:role/other", want: []string{}},
+		{
+			name:       "removed account",
+			annotation: "arn:aws:iam::210987654321:role/irsa, arn:aws:iam::111111111111:role/irsa",
+			want:       []string{"arn:aws:iam::111111111111:role/irsa"},
+		},
+		{name: "cluster account", annotation: "arn:aws:iam::123456789012:role/irsa", want: []string{}},
+		{name: "invalid", annotation: "irsa,arn:aws:iam::111111111111:role/irsa", want: []string{"arn:aws:iam::111111111111:role/irsa"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := RemovedAccountRoleARNs(tt.annotation, "123456789012", additionalAccountRoleARNs)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("RemovedAccountRoleARNs() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPodIdentityAssociations(t *testing.T) {
+	tests := []struct {
+		name       string
+		annotation string
+		want       []PodIdentityAssociation
+		wantErr    bool
+	}{
+		{name: "not set", annotation: "", want: nil},
+		{name: "empty list", annotation: "[]", want: []PodIdentityAssociation{}},
+		{
+			name:       "associations",
+			annotation: `[{"namespace": "kube-system", "serviceAccount": "ebs-csi-controller-sa", "roleArn": "arn:aws:iam::123456789012:role/ebs-csi"}, {"namespace": "kube-system", "serviceAccount": "external-dns", "roleArn": "arn:aws:iam::123456789012:role/external-dns"}]`,
+			want: []PodIdentityAssociation{
+				{Namespace: "kube-system", ServiceAccount: "ebs-csi-controller-sa", RoleARN: "arn:aws:iam::123456789012:role/ebs-csi"},
+				{Namespace: "kube-system", ServiceAccount: "external-dns", RoleARN: "arn:aws:iam::123456789012:role/external-dns"},
+			},
+		},
+		{name: "no JSON", annotation: "kube-system/external-dns", wantErr: true},
+		{name: "unknown field", annotation: `[{"namespace": "kube-system", "serviceAccount": "external-dns", "role": "arn:aws:iam::123456789012:role/external-dns"}]`, wantErr: true},
+		{name: "missing service account", annotation: `[{"namespace": "kube-system", "roleArn": "arn:aws:iam::123456789012:role/external-dns"}]`, wantErr: true},
+		{name: "no role", annotation: `[{"namespace": "kube-system", "serviceAccount": "external-dns", "roleArn": "arn:aws:iam::123456789012:user/external-dns"}]`, wantErr: true},
+		{
+			name:       "service account twice",
+			annotation: `[{"namespace": "kube-system", "serviceAccount": "external-dns", "roleArn": "arn:aws:iam::123456789012:role/a"}, {"namespace": "kube-system", "serviceAccount": "external-dns", "roleArn": "arn:aws:iam::123456789012:role/b"}]`,
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := PodIdentityAssociations(tt.annotation)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("PodIdentityAssociations() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("PodIdentityAssociations() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAdditionalAudiences(t *testing.T) {
+	tests := []struct {
+		annotation string
+		want       []string
+	}{
+		{annotation: "", want: []string{}},
+		{annotation: "vault", want: []string{"vault"}},
+		{annotation: " vault, Custom-Audience ,vault,", want: []string{"vault", "Custom-Audience"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.annotation, func(t *testing.T) {
+			if got := AdditionalAudiences(tt.annotation); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("AdditionalAudiences() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnknownAudiencesPolicy(t *testing.T) {
+	tests := []struct {
+		annotation string
+		want       string
+		wantErr    bool
+	}{
+		{annotation: "", want: "remove"},
+		{annotation: "keep", want: "keep"},
+		{annotation: "warn", want: "warn"},
+		{annotation: "delete", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.annotation, func(t *testing.T) {
+			got, err := UnknownAudiencesPolicy(tt.annotation)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("UnknownAudiencesPolicy() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("UnknownAudiencesPolicy() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestThumbprintMode(t *testing.T) {
+	tests := []struct {
+		annotation string
+		want       string
+		wantErr    bool
+	}{
+		{annotation: "", want: "root"},
+		{annotation: "chain", want: "chain"},
+		{annotation: "unmanaged", want: "unmanaged"},
+		{annotation: "leaf", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.annotation, func(t *testing.T) {
+			got, err := ThumbprintMode(tt.annotation)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ThumbprintMode() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("ThumbprintMode() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -9,12 +9,22 @@ const (
 	metricNamespace               = "irsa_operator"
 	errorMetricSubsystem          = "cluster"
 	acmCertificateMetricSubsystem = "acm_certificate"
+	route53MetricSubsystem        = "route53"
+	gcMetricSubsystem             = "gc"
+	reconcileStepMetricSubsystem  = "reconcile_step"
 
 	labelAccountID       = "account_id"
 	labelCertificateName = "certificate_name"
 	labelCluster         = "cluster_id"
 	labelNamespace       = "cluster_namespace"
 	labelInstallation    = "installation"
+	labelPipeline        = "pipeline"
+	labelRecordName      = "record_name"
+	labelRenewalStatus   = "renewal_status"
+	labelRenewalReason   = "renewal_status_reason"
+	labelResourceType    = "resource_type"
+	labelResult          = "result"
+	labelStep            = "step"
 )
 
 var (
@@ -39,10 +49,135 @@ var (
 		},
 		append(commonLabels, labelCertificateName),
 	)
+
+	CertDaysUntilExpiry = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricNamespace,
+			Subsystem: acmCertificateMetricSubsystem,
+			Name:      "days_until_expiry",
+			Help:      "Number of days until ACM certificates used for IRSA expire",
+		},
+		append(commonLabels, labelCertificateName),
+	)
+
+	CertRenewalStatus = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricNamespace,
+			Subsystem: acmCertificateMetricSubsystem,
+			Name:      "renewal_status",
+			Help:      "Managed renewal status of ACM certificates used for IRSA, set to 1 for the current status and reason",
+		},
+		append(commonLabels, labelCertificateName, labelRenewalStatus, labelRenewalReason),
+	)
+
+	CertInUseBy = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricNamespace,
+			Subsystem: acmCertificateMetricSubsystem,
+			Name:      "in_use_by",
+			Help:      "Number of AWS resources using ACM certificates used for IRSA",
+		},
+		append(commonLabels, labelCertificateName),
+	)
+
+	CertValidationRecordPresent = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricNamespace,
+			Subsystem: acmCertificateMetricSubsystem,
+			Name:      "validation_record_present",
+			Help:      "Whether all DNS validation records of ACM certificates used for IRSA exist, which is required for managed renewal",
+		},
+		append(commonLabels, labelCertificateName),
+	)
+
+	DNSChangePending = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricNamespace,
+			Subsystem: route53MetricSubsystem,
+			Name:      "change_pending",
+			Help:      "Whether the last Route53 change of DNS records used for IRSA is still propagating",
+		},
+		append(commonLabels, labelRecordName),
+	)
+
+	GCOrphanedResources = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricNamespace,
+			Subsystem: gcMetricSubsystem,
+			Name:      "orphaned_resources",
+			Help:      "Number of AWS resources tagged with the installation that belong to no existing cluster",
+		},
+		[]string{labelInstallation, labelAccountID, labelResourceType},
+	)
+
+	GCDeletedResources = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricNamespace,
+			Subsystem: gcMetricSubsystem,
+			Name:      "deleted_resources_total",
+			Help:      "Number of orphaned AWS resources deleted by the garbage collection",
+		},
+		[]string{labelInstallation, labelAccountID, labelResourceType},
+	)
+
+	ReconcileStepDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: metricNamespace,
+			Subsystem: reconcileStepMetricSubsystem,
+			Name:      "duration_seconds",
+			Help:      "Duration of the reconciliation steps including retries, by result (success, wait or error)",
+			Buckets:   prometheus.ExponentialBuckets(0.05, 2, 12),
+		},
+		[]string{labelInstallation, labelPipeline, labelStep, labelResult},
+	)
 )
 
+// SetCertificateRenewalStatus sets the renewal status of a certificate, replacing the previously reported status.
+func SetCertificateRenewalStatus(installation, accountID, clusterName, clusterNamespace, certificateName, status, reason string) {
+	CertRenewalStatus.DeletePartialMatch(prometheus.Labels{
+		labelInstallation:    installation,
+		labelAccountID:       accountID,
+		labelCluster:         clusterName,
+		labelNamespace:       clusterNamespace,
+		labelCertificateName: certificateName,
+	})
+	CertRenewalStatus.WithLabelValues(installation, accountID, clusterName, clusterNamespace, certificateName, status, reason).Set(1)
+}
+
+// DeleteCertificateMetrics removes all ACM certificate metrics of a cluster.
+func DeleteCertificateMetrics(installation, accountID, clusterName, clusterNamespace string) {
+	labels := prometheus.Labels{
+		labelInstallation: installation,
+		labelAccountID:    accountID,
+		labelCluster:      clusterName,
+		labelNamespace:    clusterNamespace,
+	}
+
+	for _, gauge := range []*prometheus.GaugeVec{Certs, CertDaysUntilExpiry, CertRenewalStatus, CertInUseBy, CertValidationRecordPresent} {
+		gauge.DeletePartialMatch(labels)
+	}
+}
+
+// DeleteDNSMetrics removes all Route53 metrics of a cluster.
+func DeleteDNSMetrics(installation, accountID, clusterName, clusterNamespace string) {
+	DNSChangePending.DeletePartialMatch(prometheus.Labels{
+		labelInstallation: installation,
+		labelAccountID:    accountID,
+		labelCluster:      clusterName,
+		labelNamespace:    clusterNamespace,
+	})
+}
+
 func init() {
 	// Register custom metrics with the global prometheus registry
 	metrics.Registry.MustRegister(Errors)
 	metrics.Registry.MustRegister(Certs)
+	metrics.Registry.MustRegister(CertDaysUntilExpiry)
+	metrics.Registry.MustRegister(CertRenewalStatus)
+	metrics.Registry.MustRegister(CertInUseBy)
+	metrics.Registry.MustRegister(CertValidationRecordPresent)
+	metrics.Registry.MustRegister(DNSChangePending)
+	metrics.Registry.MustRegister(GCOrphanedResources)
+	metrics.Registry.MustRegister(GCDeletedResources)
+	metrics.Registry.MustRegister(ReconcileStepDuration)
 }